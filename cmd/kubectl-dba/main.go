@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"os"
 
 	"kubedb.dev/cli/pkg/cmds"
@@ -33,5 +34,14 @@ func main() {
 
 	if err := cmd.Execute(); err != nil {
 		klog.Warningln(err)
+		// Commands whose outcome scripts branch on, e.g. dc-dr wait, return an
+		// error carrying the exit code.
+		var coder interface{ ExitCode() int }
+		if errors.As(err, &coder) {
+			logs.FlushLogs()
+			os.Exit(coder.ExitCode())
+		}
+		logs.FlushLogs()
+		os.Exit(1)
 	}
 }
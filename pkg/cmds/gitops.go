@@ -0,0 +1,100 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"kubedb.dev/cli/pkg/debug"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	gitOpsStatusLong = templates.LongDesc(`
+		Compare the desired spec of a GitOps managed database with the live
+		database, field by field. Every drift is linked to the OpsRequest that
+		is meant to fix it. Drifts that no OpsRequest can fix are reported as
+		Unsupported; they will never be reconciled.
+    `)
+
+	gitOpsStatusExample = templates.Examples(`
+		# Show the drift of a GitOps managed postgres
+		kubectl dba gitops status postgres/sample-postgres -n demo
+
+		# Machine readable output
+		kubectl dba gitops status mongodb/sample-mongodb -n demo -o json
+`)
+)
+
+func NewCmdGitOps(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "gitops",
+		Short:                 i18n.T("Inspect GitOps managed databases"),
+		Run:                   func(cmd *cobra.Command, args []string) {},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	cmd.AddCommand(newCmdGitOpsStatus(f, streams))
+	return cmd
+}
+
+func newCmdGitOpsStatus(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:                   "status <kind>/<name>",
+		Short:                 i18n.T("Show the drift between the GitOps object and the live database"),
+		Long:                  gitOpsStatusLong,
+		Example:               gitOpsStatusExample,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := debug.GitOpsDrift(f, args[0])
+			if err != nil {
+				return err
+			}
+			switch output {
+			case "json":
+				b, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintln(streams.Out, string(b))
+				return nil
+			case "yaml":
+				b, err := yaml.Marshal(report)
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprint(streams.Out, string(b))
+				return nil
+			case "":
+				return debug.PrintDriftReport(streams.Out, report)
+			}
+			return fmt.Errorf("unsupported output format %q, must be one of: json|yaml", output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", output, "Output format. One of: json|yaml")
+	return cmd
+}
@@ -0,0 +1,209 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kubedb.dev/cli/pkg/lister"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	listLong = templates.LongDesc(`
+		List every KubeDB database in the cluster with its health at a glance.
+		The database kinds are discovered from the kubedb.com API group, so
+		every kind the installed operator serves is included.
+    `)
+
+	listExample = templates.Examples(`
+		# List all databases in the current namespace
+		kubectl dba list
+
+		# List all databases in all namespaces
+		kubectl dba list -A
+
+		# List only postgres and mysql databases, with more columns
+		kubectl dba list -A --kind postgres,mysql -o wide
+
+		# Machine readable output
+		kubectl dba list -A -l team=payments -o json
+`)
+)
+
+type ListOptions struct {
+	Namespace     string
+	AllNamespaces bool
+	Selector      string
+	Kinds         []string
+	Output        string
+
+	Factory cmdutil.Factory
+
+	genericclioptions.IOStreams
+}
+
+func NewCmdList(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := &ListOptions{
+		Factory:   f,
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "list [--kind KIND] [-l label] [-A]",
+		Aliases: []string{"ls"},
+		Short:   i18n.T("List all KubeDB databases with their health overview"),
+		Long:    listLong,
+		Example: listExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate(args))
+			cmdutil.CheckErr(o.Run())
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", o.AllNamespaces, "If present, list the databases across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringSliceVar(&o.Kinds, "kind", o.Kinds, "Only list these database kinds (e.g. --kind postgres,mysql). Kind, resource, singular and short names are accepted.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: wide|json|yaml")
+
+	return cmd
+}
+
+func (o *ListOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	var err error
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	return err
+}
+
+func (o *ListOptions) Validate(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("list takes no arguments; use --kind to select database kinds")
+	}
+	switch o.Output {
+	case "", "wide", "json", "yaml":
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of: wide|json|yaml", o.Output)
+}
+
+func (o *ListOptions) Run() error {
+	config, err := o.Factory.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	l, err := lister.NewLister(config)
+	if err != nil {
+		return err
+	}
+	rows, err := l.List(context.TODO(), lister.Options{
+		Namespace:     o.Namespace,
+		AllNamespaces: o.AllNamespaces,
+		Kinds:         o.Kinds,
+		Selector:      o.Selector,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch o.Output {
+	case "json":
+		b, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(o.Out, string(b))
+		return nil
+	case "yaml":
+		b, err := yaml.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(o.Out, string(b))
+		return nil
+	}
+
+	if len(rows) == 0 {
+		if o.AllNamespaces {
+			_, _ = fmt.Fprintln(o.ErrOut, "No databases found.")
+		} else {
+			_, _ = fmt.Fprintf(o.ErrOut, "No databases found in %s namespace.\n", o.Namespace)
+		}
+		return nil
+	}
+	return printListTable(o.Out, rows, o.AllNamespaces, o.Output == "wide")
+}
+
+func printListTable(out io.Writer, rows []lister.Row, withNamespace, wide bool) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	headers := []string{"KIND", "NAME", "VERSION", "MODE", "READY", "PHASE", "STATE", "AGE"}
+	if withNamespace {
+		headers = append([]string{"NAMESPACE"}, headers...)
+	}
+	if wide {
+		headers = append(headers, "TLS", "MONITORING", "BACKUP", "LAST-OPSREQUEST")
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	now := time.Now()
+	for _, r := range rows {
+		cells := []string{r.Kind, r.Name, orDash(r.Version), r.Mode, r.Ready(), orDash(r.Phase), r.State(), age(r.CreationTime.Time, now)}
+		if withNamespace {
+			cells = append([]string{r.Namespace}, cells...)
+		}
+		if wide {
+			cells = append(cells, onOff(r.TLS), onOff(r.Monitoring), orDash(r.Backup), r.LastOpsRequest.String())
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+func age(t, now time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(t))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -17,12 +17,20 @@ limitations under the License.
 package cmds
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	"kubedb.dev/cli/pkg/monitor"
 	"kubedb.dev/cli/pkg/monitor/alerts"
 	"kubedb.dev/cli/pkg/monitor/connection"
 	"kubedb.dev/cli/pkg/monitor/dashboard"
+	"kubedb.dev/cli/pkg/monitor/generate"
+	"kubedb.dev/cli/pkg/monitor/scrape"
+	"kubedb.dev/cli/pkg/monitor/silence"
 
 	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/resource"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/templates"
@@ -45,10 +53,31 @@ var monitorExample = templates.Examples(`
 		# Check connection status of target with prometheus server for a specific database
 		kubectl dba monitor check-connection [DATABASE] [DATABASE_NAME] -n [NAMESPACE] 
 
+		# Summarize the exporter metrics of a database, without prometheus
+		kubectl dba monitor scrape [DATABASE] [DATABASE_NAME] -n [NAMESPACE]
+
+		# Generate a PrometheusRule and a grafana dashboard for a database
+		kubectl dba monitor generate alerts [DATABASE] [DATABASE_NAME] -n [NAMESPACE]
+		kubectl dba monitor generate dashboard [DATABASE] [DATABASE_NAME] -n [NAMESPACE]
+
+		# Silence the alerts of a database during maintenance
+		kubectl dba monitor silence [DATABASE] [DATABASE_NAME] -n [NAMESPACE] --for 2h
+
+		# Query a Prometheus compatible API directly, e.g. Thanos Query behind TLS
+		kubectl dba monitor get-alerts [DATABASE] [DATABASE_NAME] -n [NAMESPACE] \
+			--prom-url=https://thanos-query.example.com --prom-bearer-token-file=token --prom-ca-file=ca.crt
+
+		# Use the prometheus settings stored in a context file
+		kubectl dba monitor check-connection [DATABASE] [DATABASE_NAME] --prom-config=~/.kube/prometheus.yaml --prom-context=prod
+
 		# Common Flags
 		--prom-svc-name : name of the prometheus service
 		--prom-svc-namespace : namespace of the prometheus service
 		--prom-svc-port : port of the prometheus service
+		--prom-url : address of prometheus, used instead of port-forwarding to the service
+		--prom-bearer-token[-file], --prom-username/--prom-password : prometheus credentials
+		--prom-ca-file, --prom-client-cert-file/--prom-client-key-file : prometheus TLS settings
+		--prom-config, --prom-context : kubeconfig-style file to load the above from ($KUBEDB_PROM_CONFIG)
 
 `)
 
@@ -60,17 +89,23 @@ func NewCmdMonitor(f cmdutil.Factory) *cobra.Command {
 		Example: monitorExample,
 		Run: func(cmd *cobra.Command, args []string) {
 		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(prom.Complete(cmd.Flags()))
+		},
 		DisableFlagsInUseLine: true,
 		DisableAutoGenTag:     true,
 	}
 
-	cmd.PersistentFlags().StringVarP(&prom.Name, "prom-svc-name", "", "", "name of the prometheus service")
-	cmd.PersistentFlags().StringVarP(&prom.Namespace, "prom-svc-namespace", "", "", "namespace of the prometheus service")
-	cmd.PersistentFlags().IntVarP(&prom.Port, "prom-svc-port", "", 9090, "port of the prometheus service")
+	monitor.AddPromFlags(cmd.PersistentFlags(), &prom)
 
 	cmd.AddCommand(DashboardCMD(f))
 	cmd.AddCommand(AlertCMD(f))
 	cmd.AddCommand(ConnectionCMD(f))
+	cmd.AddCommand(ScrapeCMD(f))
+	cmd.AddCommand(GenerateCMD(f))
+	cmd.AddCommand(SilenceCMD(f))
+	cmd.AddCommand(SilencesCMD(f))
+	cmd.AddCommand(UnsilenceCMD(f))
 
 	return cmd
 }
@@ -87,6 +122,10 @@ var alertExample = templates.Examples(`
 		# Get triggered alert for a specific mongodb
 	    kubectl dba monitor get-alerts mongodb sample-mongodb -n demo \
  		--prom-svc-name=prometheus-kube-prometheus-prometheus --prom-svc-namespace=monitoring --prom-svc-port=9090
+
+		# Firing and pending alerts of the last day as json. The command exits
+		# non-zero while a critical alert is firing, so it can gate maintenance.
+		kubectl dba monitor get-alerts postgres sample-postgres -n demo --since=24h --state=all -o json
 		
  		Valid resource types include:
 			* connectcluster
@@ -109,17 +148,21 @@ var alertExample = templates.Examples(`
 `)
 
 func AlertCMD(f cmdutil.Factory) *cobra.Command {
+	var opts alerts.Options
 	cmd := &cobra.Command{
 		Use:     "get-alerts",
 		Short:   i18n.T("Alerts associated with a database"),
 		Long:    alertLong,
 		Example: alertExample,
 		Run: func(cmd *cobra.Command, args []string) {
-			alerts.Run(f, args, prom)
+			alerts.Run(f, args, prom, opts)
 		},
 		DisableFlagsInUseLine: true,
 		DisableAutoGenTag:     true,
 	}
+	cmd.Flags().DurationVar(&opts.Since, "since", time.Hour, "show alerts that were active within this duration")
+	cmd.Flags().StringVar(&opts.State, "state", alerts.StateFiring, "current alert state to show; resolved alerts are always shown. One of: firing|pending|all")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "table", "output format. One of: table|json")
 	return cmd
 }
 
@@ -193,6 +236,12 @@ func DashboardCMD(f cmdutil.Factory) *cobra.Command {
 // check-connection
 var connectionLong = templates.LongDesc(`
 		Check connection status for different targets with prometheus server for specific DB.
+		Before querying the metrics, every link from the database to Prometheus is reported as a
+		pass/fail step: spec.monitor, the stats service and its endpoints, the ServiceMonitor or
+		PodMonitor selecting it, the Prometheus serviceMonitorSelector and the scrape target health.
+
+		Without a prometheus service or --prom-url, or with --scrape, the exporter is scraped
+		directly through the <db>-stats service to confirm that it is healthy.
 `)
 
 var connectionExample = templates.Examples(`
@@ -203,6 +252,9 @@ var connectionExample = templates.Examples(`
 		kubectl dba monitor check-connection mongodb sample_mg -n demo \
 		--prom-svc-name=prometheus-kube-prometheus-prometheus --prom-svc-namespace=monitoring --prom-svc-port=9090
 
+		# Check only the exporter of a database, in a cluster without prometheus
+		kubectl dba monitor check-connection postgres pg-demo -n demo --scrape
+
  		Valid resource types include:
 			* connectcluster
 			* druid
@@ -224,16 +276,224 @@ var connectionExample = templates.Examples(`
 `)
 
 func ConnectionCMD(f cmdutil.Factory) *cobra.Command {
+	var scrapeOnly bool
 	cmd := &cobra.Command{
 		Use:     "check-connection",
 		Short:   i18n.T("Check connection status of prometheus targets with server"),
 		Long:    connectionLong,
 		Example: connectionExample,
 		Run: func(cmd *cobra.Command, args []string) {
+			if scrapeOnly {
+				connection.RunExporter(f, args)
+				return
+			}
 			connection.Run(f, args, prom)
 		},
 		DisableFlagsInUseLine: true,
 		DisableAutoGenTag:     true,
 	}
+	cmd.Flags().BoolVar(&scrapeOnly, "scrape", false, "scrape the database exporter directly instead of querying prometheus")
 	return cmd
 }
+
+// generate
+var generateLong = templates.LongDesc(`
+		Generate monitoring manifests for a specific database: a PrometheusRule with its alerts or
+		a grafana dashboard. Both are scoped to the database through the app, app_namespace and
+		k8s_resource alert labels and the namespace and service series labels.
+`)
+
+var generateExample = templates.Examples(`
+		# Generate the alerts of a postgres, tuning two of them, and apply them
+		kubectl dba monitor generate alerts postgres pg-demo -n demo \
+		--set PostgresReplicationLag.threshold=60 --set PostgresHighCPU.enabled=false \
+		--labels release=prometheus | kubectl apply -f -
+
+		# Generate the alerts with thresholds from a values file
+		kubectl dba monitor generate alerts mongodb mg-demo -n demo --values=alert-values.yaml
+
+		# Generate a dashboard and validate it against prometheus
+		kubectl dba monitor generate dashboard postgres pg-demo -n demo > pg-demo.json
+		kubectl dba monitor dashboard postgres pg-demo -n demo --file=pg-demo.json
+`)
+
+func GenerateCMD(f cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "generate",
+		Short:   i18n.T("Generate alerts and dashboards for a database"),
+		Long:    generateLong,
+		Example: generateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+
+	var alertOpts generate.AlertOptions
+	alertsCmd := &cobra.Command{
+		Use:   "alerts",
+		Short: i18n.T("Generate a PrometheusRule with the alerts of a database"),
+		Long: templates.LongDesc(`
+			Generate a PrometheusRule with the alerts of a database. Thresholds, durations and
+			severities are tuned per alert with --set <Alert>.<field>=<value> or a values file
+			holding the same fields under rules.<Alert>.
+		`),
+		Run: func(cmd *cobra.Command, args []string) {
+			generate.RunAlerts(f, args, alertOpts)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	alertsCmd.Flags().StringVar(&alertOpts.ValuesFile, "values", "", "file with the alert thresholds, durations and severities")
+	alertsCmd.Flags().StringArrayVar(&alertOpts.Set, "set", nil, "override an alert field, e.g. PostgresHighCPU.threshold=90. One of fields: enabled|threshold|for|severity")
+	alertsCmd.Flags().StringToStringVar(&alertOpts.Labels, "labels", nil, "labels of the PrometheusRule, e.g. to match the ruleSelector of prometheus")
+
+	dashboardCmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: i18n.T("Generate a grafana dashboard of a database"),
+		Run: func(cmd *cobra.Command, args []string) {
+			generate.RunDashboard(f, args)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+
+	cmd.AddCommand(alertsCmd)
+	cmd.AddCommand(dashboardCmd)
+	return cmd
+}
+
+// scrape
+var scrapeLong = templates.LongDesc(`
+		Scrape the exporter of a database directly through its <db>-stats service, without Prometheus.
+		By default the key metrics of the database are summarized; --filter selects metrics by a
+		regular expression and --raw prints the exporter output in the Prometheus text format.
+`)
+
+var scrapeExample = templates.Examples(`
+		kubectl dba monitor scrape [DATABASE] [DATABASE_NAME] -n [NAMESPACE]
+
+		# Summarize the key metrics of a postgres exporter
+		kubectl dba monitor scrape postgres pg-demo -n demo
+
+		# Dump the replication metrics of a mysql exporter
+		kubectl dba monitor scrape mysql my-demo -n demo --raw --filter='^mysql_slave_status_'
+`)
+
+func ScrapeCMD(f cmdutil.Factory) *cobra.Command {
+	var opts scrape.Options
+	cmd := &cobra.Command{
+		Use:     "scrape",
+		Short:   i18n.T("Scrape the exporter of a database without prometheus"),
+		Long:    scrapeLong,
+		Example: scrapeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			scrape.Run(f, args, opts)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "print the exporter output in the prometheus text format")
+	cmd.Flags().StringVar(&opts.Filter, "filter", "", "regular expression selecting the metrics to show")
+	return cmd
+}
+
+// silence
+var silenceLong = templates.LongDesc(`
+		Silence the alerts of a specific database in Alertmanager, e.g. during planned maintenance.
+		The silence matches the k8s_resource, app and app_namespace labels of the database alerts.
+`)
+
+var silenceExample = templates.Examples(`
+		kubectl dba monitor silence [DATABASE] [DATABASE_NAME] -n [NAMESPACE] --for [DURATION] \
+		--alertmanager-svc-name=[AM_SVC_NAME] --alertmanager-svc-namespace=[AM_SVC_NS] --alertmanager-svc-port=[AM_SVC_PORT]
+
+		# Silence the alerts of a postgres for two hours
+		kubectl dba monitor silence postgres sample-postgres -n demo --for 2h --comment "minor version upgrade"
+
+		# List the active silences of the postgres
+		kubectl dba monitor silences postgres sample-postgres -n demo
+
+		# Expire them once the maintenance is over
+		kubectl dba monitor unsilence postgres sample-postgres -n demo
+`)
+
+func SilenceCMD(f cmdutil.Factory) *cobra.Command {
+	var (
+		am   monitor.AlertmanagerSvc
+		opts silence.Options
+	)
+	cmd := &cobra.Command{
+		Use:     "silence",
+		Short:   i18n.T("Silence the alerts of a database in Alertmanager"),
+		Long:    silenceLong,
+		Example: silenceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			silence.Run(f, args, am, opts)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	monitor.AddAlertmanagerFlags(cmd.Flags(), &am)
+	cmd.Flags().DurationVar(&opts.For, "for", 2*time.Hour, "how long the alerts stay silenced")
+	cmd.Flags().StringVar(&opts.Comment, "comment", "", "reason of the silence, shown in Alertmanager")
+	cmd.Flags().StringVar(&opts.Author, "author", silence.DefaultAuthor(), "recorded as the creator of the silence")
+	return cmd
+}
+
+func SilencesCMD(f cmdutil.Factory) *cobra.Command {
+	var am monitor.AlertmanagerSvc
+	cmd := &cobra.Command{
+		Use:     "silences",
+		Short:   i18n.T("List the active Alertmanager silences, optionally of one database"),
+		Long:    silenceLong,
+		Example: silenceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			silence.RunList(f, args, am)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	monitor.AddAlertmanagerFlags(cmd.Flags(), &am)
+	return cmd
+}
+
+func UnsilenceCMD(f cmdutil.Factory) *cobra.Command {
+	var (
+		am  monitor.AlertmanagerSvc
+		ids []string
+	)
+	cmd := &cobra.Command{
+		Use:     "unsilence",
+		Short:   i18n.T("Expire the Alertmanager silences of a database"),
+		Long:    silenceLong,
+		Example: silenceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			silence.RunExpire(f, args, am, ids)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	monitor.AddAlertmanagerFlags(cmd.Flags(), &am)
+	cmd.Flags().StringSliceVar(&ids, "id", nil, "IDs of the silences to expire, instead of all silences of the database")
+	return cmd
+}
+
+// silenceForMaintenance silences the alerts of the database behind info before
+// a maintenance command (pause, restart) acts on it.
+func silenceForMaintenance(f cmdutil.Factory, out io.Writer, am monitor.AlertmanagerSvc, d time.Duration, action string, info *resource.Info) error {
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	id, err := silence.ForDatabase(config, am, info.Mapping.Resource.Resource, info.Name, info.Namespace, silence.Options{
+		For:     d,
+		Comment: fmt.Sprintf("kubectl dba %s %s/%s", action, info.Namespace, info.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to silence alerts of %s/%s: %w", info.Namespace, info.Name, err)
+	}
+	_, _ = fmt.Fprintf(out, "Silenced alerts of %s/%s for %s (silence %s).\n", info.Namespace, info.Name, d, id)
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	_ "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
 	"kubedb.dev/cli/pkg/common"
@@ -43,6 +44,7 @@ func NewCmdMSSQL(f cmdutil.Factory) *cobra.Command {
 		},
 	}
 	cmd.AddCommand(NewCmdDAGConfig(f))
+	cmd.AddCommand(NewCmdDAG(f))
 	return cmd
 }
 
@@ -51,9 +53,13 @@ func NewCmdDAGConfig(f cmdutil.Factory) *cobra.Command {
 	var (
 		namespace  string
 		outputDir  string
-		desLong    = `Generates a YAML file with the necessary secrets for setting up a MSSQLServer Distributed Availability Group (DAG) remote replica.`
-		exampleStr = `  # Generate DAG configuration secrets from MSSQLServer 'ag1' in namespace 'demo'
-  kubectl dba mssql dag-config ag1 -n demo`
+		remote     common.RemoteClusterFlags
+		desLong    = `Exports the secrets and the AppBinding needed to set up a MSSQLServer Distributed Availability Group (DAG) remote replica, and applies them directly to the remote cluster (--remote-kubeconfig/--remote-context) and/or writes them to --output-file with mode 0600.`
+		exampleStr = `  # Apply the DAG configuration of MSSQLServer 'ag1' in namespace 'demo' to the remote cluster
+  kubectl dba mssql dag-config ag1 -n demo --remote-kubeconfig remote.yaml
+
+  # Write it to a file instead; it holds the DAG credentials and endpoint certificate
+  kubectl dba mssql dag-config ag1 -n demo --output-file ag1-dag-config.yaml`
 	)
 
 	cmd := &cobra.Command{
@@ -64,18 +70,26 @@ func NewCmdDAGConfig(f cmdutil.Factory) *cobra.Command {
 		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			mssqlServerName := args[0]
+			if cmd.Flags().Changed("output-dir") && remote.OutputFile == "" {
+				remote.OutputFile = fmt.Sprintf("%s/%s-dag-config.yaml", outputDir, mssqlServerName)
+			}
 			// Pass the command's context for cancellation handling
-			cmdutil.CheckErr(runDAGConfig(cmd.Context(), f, namespace, outputDir, mssqlServerName))
+			cmdutil.CheckErr(runDAGConfig(cmd.Context(), f, namespace, &remote, mssqlServerName))
 		},
 	}
 
 	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the source MSSQLServer")
 	cmd.Flags().StringVar(&outputDir, "output-dir", ".", "Directory where the configuration YAML file will be saved")
+	_ = cmd.Flags().MarkDeprecated("output-dir", "use --output-file, or --remote-kubeconfig to apply the configuration directly")
+	common.AddRemoteClusterFlags(cmd.Flags(), &remote)
 	return cmd
 }
 
 // runDAGConfig is now much simpler. It just orchestrates the steps.
-func runDAGConfig(ctx context.Context, f cmdutil.Factory, namespace, outputDir, mssqlServerName string) error {
+func runDAGConfig(ctx context.Context, f cmdutil.Factory, namespace string, remote *common.RemoteClusterFlags, mssqlServerName string) error {
+	if err := remote.Validate(); err != nil {
+		return err
+	}
 	fmt.Printf("Generating DAG configuration for MSSQLServer '%s' in namespace '%s'...\n", mssqlServerName, namespace)
 
 	opts, err := common.NewMSSQLOpts(f, mssqlServerName, namespace)
@@ -89,19 +103,20 @@ func runDAGConfig(ctx context.Context, f cmdutil.Factory, namespace, outputDir,
 		return err
 	}
 
-	// Write the buffer to a file
-	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory '%s': %w", outputDir, err)
+	if remote.OutputFile != "" {
+		if err := os.MkdirAll(filepath.Dir(remote.OutputFile), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(remote.OutputFile), err)
+		}
 	}
-
-	outputFile := fmt.Sprintf("%s/%s-dag-config.yaml", outputDir, mssqlServerName)
-	if err := os.WriteFile(outputFile, yamlBuffer, 0o644); err != nil {
-		return fmt.Errorf("failed to write DAG config to file '%s': %w", outputFile, err)
+	cfg, err := remote.Emit(ctx, os.Stdout, yamlBuffer)
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("Successfully generated DAG configuration.\n")
-	fmt.Printf("Apply this file in your remote cluster: kubectl apply -f %s\n", outputFile)
-
+	if cfg == nil {
+		fmt.Printf("Apply this file in your remote cluster: kubectl apply -f %s\n", remote.OutputFile)
+	}
 	return nil
 }
 
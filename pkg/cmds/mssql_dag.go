@@ -0,0 +1,706 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dboldapi "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
+	cs "kubedb.dev/apimachinery/client/clientset/versioned"
+	"kubedb.dev/cli/pkg/common"
+
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	exec_util "kmodules.xyz/client-go/tools/exec"
+	"sigs.k8s.io/yaml"
+)
+
+// NewCmdDAG creates the `kubectl dba mssql dag` command group.
+func NewCmdDAG(f cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dag",
+		Short: "Create, inspect and fail over a MSSQLServer Distributed Availability Group",
+		Long: `Commands for a MSSQLServer Distributed Availability Group (DAG) that spans the current cluster and a remote one.
+The source MSSQLServer is read on the current cluster, the remote one on the cluster given by --remote-kubeconfig/--remote-context.
+Both are expected in the same namespace.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.AddCommand(NewCmdDAGCreate(f))
+	cmd.AddCommand(NewCmdDAGStatus(f))
+	cmd.AddCommand(NewCmdDAGFailover(f))
+	return cmd
+}
+
+// dagFlags locate the two MSSQLServers of a DAG.
+type dagFlags struct {
+	namespace  string
+	remoteName string
+	remote     common.RemoteClusterFlags
+}
+
+func (o *dagFlags) addFlags(cmd *cobra.Command, withOutputFile bool) {
+	cmd.Flags().StringVarP(&o.namespace, "namespace", "n", "default", "Namespace of the source MSSQLServer, and of the remote one")
+	cmd.Flags().StringVar(&o.remoteName, "remote-name", "", "Name of the MSSQLServer on the remote cluster (default: the source's name)")
+	if withOutputFile {
+		common.AddRemoteClusterFlags(cmd.Flags(), &o.remote)
+	} else {
+		common.AddRemoteKubeconfigFlags(cmd.Flags(), &o.remote)
+	}
+}
+
+func (o *dagFlags) remoteDBName(source string) string {
+	if o.remoteName != "" {
+		return o.remoteName
+	}
+	return source
+}
+
+// dagSide is one MSSQLServer of the DAG with the clients of its cluster.
+type dagSide struct {
+	where  string
+	config *rest.Config
+	client kubernetes.Interface
+	dbc    cs.Interface
+	db     *dboldapi.MSSQLServer
+	pod    *core.Pod
+}
+
+// openDAGSides loads the source MSSQLServer and the remote one, and finds the
+// primary pod of each.
+func (o *dagFlags) openDAGSides(ctx context.Context, f cmdutil.Factory, name string) (*dagSide, *dagSide, error) {
+	if !o.remote.Remote() {
+		return nil, nil, fmt.Errorf("give --remote-kubeconfig and/or --remote-context to reach the remote cluster")
+	}
+	opts, err := common.NewMSSQLOpts(f, name, o.namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := &dagSide{where: "source", config: opts.Config, client: opts.Client, dbc: opts.DBClient, db: opts.DB}
+
+	cfg, err := o.remote.RESTConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	remote := &dagSide{where: "remote", config: cfg}
+	if remote.client, err = kubernetes.NewForConfig(cfg); err != nil {
+		return nil, nil, err
+	}
+	if remote.dbc, err = cs.NewForConfig(cfg); err != nil {
+		return nil, nil, err
+	}
+	remoteName := o.remoteDBName(name)
+	if remote.db, err = remote.dbc.KubedbV1alpha2().MSSQLServers(o.namespace).Get(ctx, remoteName, metav1.GetOptions{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to get MSSQLServer %s/%s on the remote cluster: %w", o.namespace, remoteName, err)
+	}
+	for _, s := range []*dagSide{local, remote} {
+		if !s.db.IsDistributedAG() {
+			return nil, nil, fmt.Errorf("%s MSSQLServer %s/%s is not in DistributedAG mode", s.where, s.db.Namespace, s.db.Name)
+		}
+		if err := s.findPrimary(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	return local, remote, nil
+}
+
+func (s *dagSide) findPrimary(ctx context.Context) error {
+	selector := s.db.OffshootSelectors(map[string]string{kubedb.LabelRole: kubedb.DatabasePodPrimary})
+	pods, err := s.client.CoreV1().Pods(s.db.Namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.Set(selector).String()})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of %s MSSQLServer %s/%s: %w", s.where, s.db.Namespace, s.db.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("%s MSSQLServer %s/%s has no primary pod", s.where, s.db.Namespace, s.db.Name)
+	}
+	s.pod = &pods.Items[0]
+	return nil
+}
+
+// mssqlSqlcmd runs the query in $1 with sqlcmd as the SA user of the
+// container. The tools live in /opt/mssql-tools18 on SQL Server 2022 images
+// and in /opt/mssql-tools before; -C trusts the server's certificate, which
+// the 18 tools verify by default.
+const mssqlSqlcmd = `S=$(command -v sqlcmd || ls /opt/mssql-tools*/bin/sqlcmd 2>/dev/null | tail -n 1); ` +
+	`export SQLCMDPASSWORD="$MSSQL_SA_PASSWORD"; exec "$S" -S localhost -U "$MSSQL_SA_USERNAME" -C -b -h -1 -W -s '|' -Q "$1"`
+
+// sql runs a T-SQL batch on the side's primary and returns the rows of its
+// result, with NULLs as empty fields.
+func (s *dagSide) sql(query string) ([][]string, error) {
+	out, err := exec_util.ExecIntoPod(s.config, s.pod,
+		exec_util.Command("bash", "-c", mssqlSqlcmd, "sqlcmd", "SET NOCOUNT ON; "+query),
+		exec_util.Container(kubedb.MSSQLContainerName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query on %s pod %s/%s failed: %w", s.where, s.pod.Namespace, s.pod.Name, err)
+	}
+	return parseSQLCmdRows(out), nil
+}
+
+// parseSQLCmdRows splits the output of mssqlSqlcmd into rows of fields.
+func parseSQLCmdRows(out string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		for i := range fields {
+			if fields[i] = strings.TrimSpace(fields[i]); fields[i] == "NULL" {
+				fields[i] = ""
+			}
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// sqlString quotes s as a T-SQL Unicode string literal.
+func sqlString(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// dagReplicaState is one row of dagStateQuery: a replica of an AG or of the
+// DAG (whose replicas are the two AGs) and, per database, how far it is.
+type dagReplicaState struct {
+	group, kind, replica     string
+	mode, role, connected    string
+	health, database, sync   string
+	hardenedLSN              string
+	sendQueueKB, redoQueueKB string
+	lastCommit               string
+}
+
+const dagStateQuery = `SELECT ag.name, CASE ag.is_distributed WHEN 1 THEN 'DAG' ELSE 'AG' END, ar.replica_server_name, ar.availability_mode_desc,
+  ars.role_desc, ars.connected_state_desc, ars.synchronization_health_desc,
+  DB_NAME(drs.database_id), drs.synchronization_state_desc, CONVERT(varchar(40), drs.last_hardened_lsn),
+  CONVERT(varchar(20), drs.log_send_queue_size), CONVERT(varchar(20), drs.redo_queue_size), CONVERT(varchar(30), drs.last_commit_time, 126)
+FROM sys.availability_groups ag
+JOIN sys.availability_replicas ar ON ar.group_id = ag.group_id
+LEFT JOIN sys.dm_hadr_availability_replica_states ars ON ars.replica_id = ar.replica_id
+LEFT JOIN sys.dm_hadr_database_replica_states drs ON drs.replica_id = ar.replica_id
+ORDER BY ag.is_distributed DESC, ag.name, ar.replica_server_name, DB_NAME(drs.database_id)`
+
+func (s *dagSide) replicaStates() ([]dagReplicaState, error) {
+	rows, err := s.sql(dagStateQuery)
+	if err != nil {
+		return nil, err
+	}
+	return parseDAGStates(rows), nil
+}
+
+// parseDAGStates returns the states of the rows of dagStateQuery, skipping
+// the ones that are not, such as a message sqlcmd printed.
+func parseDAGStates(rows [][]string) []dagReplicaState {
+	var states []dagReplicaState
+	for _, r := range rows {
+		if len(r) < 13 {
+			continue
+		}
+		states = append(states, dagReplicaState{
+			group: r[0], kind: r[1], replica: r[2], mode: r[3], role: r[4], connected: r[5], health: r[6],
+			database: r[7], sync: r[8], hardenedLSN: r[9], sendQueueKB: r[10], redoQueueKB: r[11], lastCommit: r[12],
+		})
+	}
+	return states
+}
+
+// dagRows returns the DAG rows of one member AG.
+func (s *dagSide) dagRows(states []dagReplicaState, ag string) []dagReplicaState {
+	var out []dagReplicaState
+	for _, st := range states {
+		if st.kind == "DAG" && st.group == s.db.DistributedAGName() && st.replica == ag {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// dagRole returns the DAG role of the side's own AG, as the side sees it.
+func (s *dagSide) dagRole(states []dagReplicaState) string {
+	for _, st := range s.dagRows(states, s.db.AvailabilityGroupName()) {
+		if st.role != "" {
+			return st.role
+		}
+	}
+	return ""
+}
+
+// lsnBehind returns how far LSN b is behind a, or "-" when either is unknown.
+// LSNs are decimal numbers (VLF sequence, block, slot), so the difference is
+// a distance in LSN space, not in bytes; zero means the hardened log matches.
+func lsnBehind(a, b string) string {
+	x, okA := new(big.Int).SetString(a, 10)
+	y, okB := new(big.Int).SetString(b, 10)
+	if !okA || !okB {
+		return "-"
+	}
+	return new(big.Int).Sub(x, y).String()
+}
+
+func NewCmdDAGCreate(f cmdutil.Factory) *cobra.Command {
+	var (
+		o       dagFlags
+		selfURL string
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "create [mssqlserver-name]",
+		Short: "Create the DAG secondary of a source MSSQLServer on a remote cluster",
+		Long: `Applies to the remote cluster the objects dag-config exports (the DBM login, master key and endpoint certificate
+Secrets, and the AppBinding) together with a MSSQLServer in DistributedAG mode with role Secondary that joins the source's DAG,
+then waits for it to become Ready.
+
+The source must already be in DistributedAG mode with role Primary, and its spec.topology.distributedAG.remote must name
+the availability group of the MSSQLServer created here. The remote MSSQLServer copies the source's version, replicas,
+storage and TLS settings; the TLS issuer it references must exist on the remote cluster.`,
+		Example: `  # Create the DAG secondary 'ag2' of 'ag1' on the remote cluster; --url is where the remote AG's
+  # endpoint is reachable from the source cluster
+  kubectl dba mssql dag create ag1 -n demo --remote-name ag2 --url 10.2.0.64:5022 --remote-kubeconfig remote.yaml`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(runDAGCreate(cmd.Context(), f, &o, args[0], selfURL, timeout))
+		},
+	}
+	o.addFlags(cmd, true)
+	cmd.Flags().StringVar(&selfURL, "url", "", "Endpoint URL of the remote availability group, reachable from the source cluster (spec.topology.distributedAG.self.url of the remote MSSQLServer)")
+	_ = cmd.MarkFlagRequired("url")
+	cmd.Flags().DurationVar(&timeout, "timeout", 20*time.Minute, "How long to wait for the remote MSSQLServer to become Ready (0 does not wait)")
+	return cmd
+}
+
+func runDAGCreate(ctx context.Context, f cmdutil.Factory, o *dagFlags, name, selfURL string, timeout time.Duration) error {
+	if !o.remote.Remote() {
+		return fmt.Errorf("give --remote-kubeconfig and/or --remote-context to apply the DAG secondary to the remote cluster")
+	}
+	opts, err := common.NewMSSQLOpts(f, name, o.namespace)
+	if err != nil {
+		return err
+	}
+	src := opts.DB
+	if !src.IsDistributedAG() || src.Spec.Topology.DistributedAG.Self.Role != dboldapi.DistributedAGRolePrimary {
+		return fmt.Errorf("MSSQLServer %s/%s must be in DistributedAG mode with spec.topology.distributedAG.self.role %s", src.Namespace, src.Name, dboldapi.DistributedAGRolePrimary)
+	}
+	remoteName := o.remoteDBName(name)
+	secondary := &dboldapi.MSSQLServer{ObjectMeta: metav1.ObjectMeta{Name: remoteName, Namespace: o.namespace}}
+	if want, got := secondary.AvailabilityGroupName(), src.Spec.Topology.DistributedAG.Remote.Name; want != got {
+		return fmt.Errorf("MSSQLServer %s/%s expects the remote availability group %q, but MSSQLServer %s forms %q; use --remote-name, or fix spec.topology.distributedAG.remote.name",
+			src.Namespace, src.Name, got, remoteName, want)
+	}
+	if src.Spec.Topology.DistributedAG.Remote.URL != selfURL {
+		fmt.Printf("WARNING: MSSQLServer %s/%s reaches the remote availability group at %s, not at --url %s\n", src.Namespace, src.Name, src.Spec.Topology.DistributedAG.Remote.URL, selfURL)
+	}
+
+	fmt.Printf("Generating DAG secondary for MSSQLServer '%s' in namespace '%s'...\n", name, o.namespace)
+	buffer, err := generateMSSQLDAGConfig(ctx, opts)
+	if err != nil {
+		return err
+	}
+	manifest, err := generateMSSQLDAGSecondary(src, remoteName, selfURL)
+	if err != nil {
+		return err
+	}
+	buffer = append(buffer, manifest...)
+	cfg, err := o.remote.Emit(ctx, os.Stdout, buffer)
+	if err != nil {
+		return err
+	}
+	if timeout == 0 {
+		return nil
+	}
+	dbc, err := cs.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Waiting up to %s for MSSQLServer %s/%s to become Ready on the remote cluster...\n", timeout, o.namespace, remoteName)
+	phase := dboldapi.DatabasePhase("")
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		db, err := dbc.KubedbV1alpha2().MSSQLServers(o.namespace).Get(ctx, remoteName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		if db.Status.Phase != phase {
+			phase = db.Status.Phase
+			fmt.Printf("  phase: %s\n", phase)
+		}
+		return phase == dboldapi.DatabasePhaseReady, nil
+	})
+	if err != nil {
+		return fmt.Errorf("MSSQLServer %s/%s did not become Ready within %s (last phase %q): %w", o.namespace, remoteName, timeout, phase, err)
+	}
+	fmt.Printf("MSSQLServer %s/%s is Ready. Check the DAG with: kubectl dba mssql dag status %s -n %s --remote-name %s\n", o.namespace, remoteName, name, o.namespace, remoteName)
+	return nil
+}
+
+// generateMSSQLDAGSecondary renders the remote MSSQLServer that joins the
+// source's DAG as its secondary. It uses the Secrets dag-config exports, which
+// both sides of a DAG must share.
+func generateMSSQLDAGSecondary(src *dboldapi.MSSQLServer, name, selfURL string) ([]byte, error) {
+	ag := map[string]any{
+		"loginSecretName":        src.DbmLoginSecretName(),
+		"masterKeySecretName":    src.MasterKeySecretName(),
+		"endpointCertSecretName": src.EndpointCertSecretName(),
+	}
+	if src.Spec.Topology.AvailabilityGroup != nil && src.Spec.Topology.AvailabilityGroup.SecondaryAccessMode != "" {
+		ag["secondaryAccessMode"] = src.Spec.Topology.AvailabilityGroup.SecondaryAccessMode
+	}
+	spec := map[string]any{
+		"version": src.Spec.Version,
+		"topology": map[string]any{
+			"mode":              dboldapi.MSSQLServerModeDistributedAG,
+			"availabilityGroup": ag,
+			"distributedAG": map[string]any{
+				"self": map[string]any{
+					"role": dboldapi.DistributedAGRoleSecondary,
+					"url":  selfURL,
+				},
+				"remote": map[string]any{
+					"name": src.AvailabilityGroupName(),
+					"url":  src.Spec.Topology.DistributedAG.Self.URL,
+				},
+			},
+		},
+	}
+	if src.Spec.Replicas != nil {
+		spec["replicas"] = *src.Spec.Replicas
+	}
+	if src.Spec.StorageType != "" {
+		spec["storageType"] = src.Spec.StorageType
+	}
+	if src.Spec.Storage != nil {
+		spec["storage"] = src.Spec.Storage
+	}
+	if src.Spec.TLS != nil {
+		spec["tls"] = src.Spec.TLS
+	}
+	if src.Spec.DeletionPolicy != "" {
+		spec["deletionPolicy"] = src.Spec.DeletionPolicy
+	}
+	out, err := yaml.Marshal(map[string]any{
+		"apiVersion": dboldapi.SchemeGroupVersion.String(),
+		"kind":       dboldapi.ResourceKindMSSQLServer,
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": src.Namespace,
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MSSQLServer %s to YAML: %w", name, err)
+	}
+	return append(out, []byte("---\n")...), nil
+}
+
+func NewCmdDAGStatus(f cmdutil.Factory) *cobra.Command {
+	var o dagFlags
+	cmd := &cobra.Command{
+		Use:   "status [mssqlserver-name]",
+		Short: "Show the synchronization state of a Distributed Availability Group on both clusters",
+		Long: `Queries sys.dm_hadr_availability_replica_states and sys.dm_hadr_database_replica_states on the primary of the source
+MSSQLServer and of the remote one, and prints the role, connection and synchronization state, log send and redo queues and
+hardened LSN of every replica of the local AG and of the DAG, followed by how far each DAG secondary database is behind.`,
+		Example: `  kubectl dba mssql dag status ag1 -n demo --remote-name ag2 --remote-kubeconfig remote.yaml`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(runDAGStatus(cmd.Context(), f, &o, args[0], cmd.OutOrStdout()))
+		},
+	}
+	o.addFlags(cmd, false)
+	return cmd
+}
+
+func runDAGStatus(ctx context.Context, f cmdutil.Factory, o *dagFlags, name string, out io.Writer) error {
+	local, remote, err := o.openDAGSides(ctx, f, name)
+	if err != nil {
+		return err
+	}
+	states := map[*dagSide][]dagReplicaState{}
+	for _, s := range []*dagSide{local, remote} {
+		st, err := s.replicaStates()
+		if err != nil {
+			return err
+		}
+		states[s] = st
+		_, _ = fmt.Fprintf(out, "%s: MSSQLServer %s/%s, AG %s, DAG role %s (queried on pod %s)\n",
+			strings.ToUpper(s.where[:1])+s.where[1:], s.db.Namespace, s.db.Name, s.db.AvailabilityGroupName(), orNone(s.dagRole(st)), s.pod.Name)
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "  GROUP\tTYPE\tREPLICA\tMODE\tROLE\tCONNECTED\tHEALTH\tDATABASE\tSYNC\tHARDENED LSN\tSEND QUEUE KB\tREDO QUEUE KB\tLAST COMMIT")
+		for _, r := range st {
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.group, r.kind, r.replica, orNone(r.mode), orNone(r.role), orNone(r.connected),
+				orNone(r.health), orNone(r.database), orNone(r.sync), orNone(r.hardenedLSN), orNone(r.sendQueueKB), orNone(r.redoQueueKB), orNone(r.lastCommit))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+
+	primary, secondary := local, remote
+	if remote.dagRole(states[remote]) == "PRIMARY" {
+		primary, secondary = remote, local
+	} else if local.dagRole(states[local]) != "PRIMARY" {
+		_, _ = fmt.Fprintln(out, "Neither side reports the DAG PRIMARY role; lag is unknown.")
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "DAG lag of %s (secondary) behind %s (global primary):\n", secondary.db.AvailabilityGroupName(), primary.db.AvailabilityGroupName())
+	lsn := map[string]string{}
+	for _, r := range primary.dagRows(states[primary], primary.db.AvailabilityGroupName()) {
+		lsn[r.database] = r.hardenedLSN
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  DATABASE\tSYNC\tSEND QUEUE KB\tREDO QUEUE KB\tHARDENED LSN BEHIND")
+	for _, r := range primary.dagRows(states[primary], secondary.db.AvailabilityGroupName()) {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", orNone(r.database), orNone(r.sync), orNone(r.sendQueueKB), orNone(r.redoQueueKB), lsnBehind(lsn[r.database], r.hardenedLSN))
+	}
+	return w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func NewCmdDAGFailover(f cmdutil.Factory) *cobra.Command {
+	var (
+		o          dagFlags
+		maxLagKB   int64
+		timeout    time.Duration
+		dryRun     bool
+		updateSpec bool
+	)
+	cmd := &cobra.Command{
+		Use:   "failover [mssqlserver-name]",
+		Short: "Run a planned failover of a Distributed Availability Group to its secondary",
+		Long: `Makes the DAG secondary the global primary without data loss:
+
+  1. refuses to start unless every DAG secondary database is connected and has at most --max-lag-kb of log not yet sent;
+  2. switches both AGs of the DAG to SYNCHRONOUS_COMMIT and waits until every database is SYNCHRONIZED;
+  3. sets the global primary to the SECONDARY role and waits until the hardened LSN of every database matches on both sides;
+  4. fails the DAG over on the forwarder (FORCE_FAILOVER_ALLOW_DATA_LOSS, which loses nothing once the LSNs match);
+  5. restores the previous availability mode and swaps spec.topology.distributedAG.self.role of the two MSSQLServers.
+
+If step 2 or 3 does not complete within --timeout, the global primary is returned to the PRIMARY role and the
+availability mode is restored. Whichever side is the global primary now, the other side becomes it.`,
+		Example: `  # Check the lag gate only
+  kubectl dba mssql dag failover ag1 -n demo --remote-name ag2 --remote-kubeconfig remote.yaml --dry-run
+
+  # Fail over, allowing up to 1 MiB of unsent log when starting
+  kubectl dba mssql dag failover ag1 -n demo --remote-name ag2 --remote-kubeconfig remote.yaml --max-lag-kb 1024`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			local, remote, err := o.openDAGSides(ctx, f, args[0])
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(runDAGFailover(ctx, local, remote, maxLagKB, timeout, dryRun, updateSpec))
+		},
+	}
+	o.addFlags(cmd, false)
+	cmd.Flags().Int64Var(&maxLagKB, "max-lag-kb", 0, "Refuse to start while a DAG secondary database has more than this many KB of log not yet sent")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for the DAG to synchronize, and then for the hardened LSNs to match")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only evaluate the lag gate")
+	cmd.Flags().BoolVar(&updateSpec, "update-spec", true, "Swap spec.topology.distributedAG.self.role of the two MSSQLServers after the failover")
+	return cmd
+}
+
+func runDAGFailover(ctx context.Context, local, remote *dagSide, maxLagKB int64, timeout time.Duration, dryRun, updateSpec bool) error {
+	localStates, err := local.replicaStates()
+	if err != nil {
+		return err
+	}
+	primary, forwarder := local, remote
+	states := localStates
+	if local.dagRole(localStates) != "PRIMARY" {
+		primary, forwarder = remote, local
+		if states, err = remote.replicaStates(); err != nil {
+			return err
+		}
+		if remote.dagRole(states) != "PRIMARY" {
+			return fmt.Errorf("neither side reports the DAG PRIMARY role")
+		}
+	}
+	dag := primary.db.DistributedAGName()
+	pAG, fAG := primary.db.AvailabilityGroupName(), forwarder.db.AvailabilityGroupName()
+	fmt.Printf("Global primary: %s (%s MSSQLServer %s); failing over to %s (%s MSSQLServer %s)\n", pAG, primary.where, primary.db.Name, fAG, forwarder.where, forwarder.db.Name)
+
+	// Step 1: the lag gate.
+	rows := primary.dagRows(states, fAG)
+	if len(rows) == 0 {
+		return fmt.Errorf("the global primary reports no DAG databases on %s", fAG)
+	}
+	modes := map[string]string{}
+	for _, st := range append(primary.dagRows(states, pAG), rows...) {
+		modes[st.replica] = st.mode
+	}
+	if blockers := dagLagBlockers(rows, fAG, maxLagKB); len(blockers) > 0 {
+		return fmt.Errorf("lag gate failed: %s", strings.Join(blockers, "; "))
+	}
+	fmt.Printf("Lag gate passed: %d database(s) connected with at most %d KB unsent.\n", len(rows), maxLagKB)
+	if dryRun {
+		return nil
+	}
+
+	// setMode sets the availability mode of both AGs in the DAG, on both
+	// sides; an empty mode restores the recorded one.
+	setMode := func(mode string) error {
+		pMode, fMode := mode, mode
+		if mode == "" {
+			pMode, fMode = modeOr(modes[pAG], "ASYNCHRONOUS_COMMIT"), modeOr(modes[fAG], "ASYNCHRONOUS_COMMIT")
+		}
+		stmt := fmt.Sprintf("ALTER AVAILABILITY GROUP [%s] MODIFY AVAILABILITY GROUP ON %s WITH (AVAILABILITY_MODE = %s), %s WITH (AVAILABILITY_MODE = %s)",
+			dag, sqlString(pAG), pMode, sqlString(fAG), fMode)
+		for _, s := range []*dagSide{primary, forwarder} {
+			if _, err := s.sql(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	restore := func(cause error) error {
+		if _, err := primary.sql(fmt.Sprintf("ALTER AVAILABILITY GROUP [%s] FORCE_FAILOVER_ALLOW_DATA_LOSS", dag)); err != nil {
+			return fmt.Errorf("%v; returning %s to the PRIMARY role also failed, check the DAG by hand: %w", cause, pAG, err)
+		}
+		if err := setMode(""); err != nil {
+			return fmt.Errorf("%v; %s is the global primary again but restoring the availability mode failed: %w", cause, pAG, err)
+		}
+		return fmt.Errorf("%v; %s is the global primary again", cause, pAG)
+	}
+
+	// Step 2: synchronous commit until every database is SYNCHRONIZED.
+	fmt.Println("Switching the DAG to SYNCHRONOUS_COMMIT...")
+	if err := setMode("SYNCHRONOUS_COMMIT"); err != nil {
+		return err
+	}
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		st, err := primary.replicaStates()
+		if err != nil {
+			return false, nil
+		}
+		for _, r := range primary.dagRows(st, fAG) {
+			if r.sync != "SYNCHRONIZED" {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		if rerr := setMode(""); rerr != nil {
+			return fmt.Errorf("the DAG did not synchronize within %s, and restoring the availability mode failed: %w", timeout, rerr)
+		}
+		return fmt.Errorf("the DAG did not synchronize within %s; the availability mode was restored", timeout)
+	}
+
+	// Step 3: demote the global primary and wait for the LSNs to match.
+	fmt.Printf("Setting %s to the SECONDARY role...\n", pAG)
+	if _, err := primary.sql(fmt.Sprintf("ALTER AVAILABILITY GROUP [%s] SET (ROLE = SECONDARY)", dag)); err != nil {
+		return err
+	}
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		pStates, err := primary.replicaStates()
+		if err != nil {
+			return false, nil
+		}
+		fStates, err := forwarder.replicaStates()
+		if err != nil {
+			return false, nil
+		}
+		want := map[string]string{}
+		for _, r := range primary.dagRows(pStates, pAG) {
+			want[r.database] = r.hardenedLSN
+		}
+		got := forwarder.dagRows(fStates, fAG)
+		if len(got) == 0 || len(got) != len(want) {
+			return false, nil
+		}
+		for _, r := range got {
+			if r.hardenedLSN == "" || want[r.database] != r.hardenedLSN {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return restore(fmt.Errorf("the hardened LSNs did not match within %s", timeout))
+	}
+
+	// Step 4: fail over on the forwarder.
+	fmt.Printf("Failing the DAG over to %s...\n", fAG)
+	if _, err := forwarder.sql(fmt.Sprintf("ALTER AVAILABILITY GROUP [%s] FORCE_FAILOVER_ALLOW_DATA_LOSS", dag)); err != nil {
+		return restore(err)
+	}
+
+	// Step 5: the previous availability mode, and the CRs.
+	if err := setMode(""); err != nil {
+		fmt.Printf("WARNING: %s is the global primary, but restoring the availability mode failed: %v\n", fAG, err)
+	}
+	if updateSpec {
+		for _, c := range []struct {
+			s    *dagSide
+			role dboldapi.DistributedAGRole
+		}{{primary, dboldapi.DistributedAGRoleSecondary}, {forwarder, dboldapi.DistributedAGRolePrimary}} {
+			patch := fmt.Sprintf(`{"spec":{"topology":{"distributedAG":{"self":{"role":%q}}}}}`, c.role)
+			if _, err := c.s.dbc.KubedbV1alpha2().MSSQLServers(c.s.db.Namespace).Patch(ctx, c.s.db.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+				fmt.Printf("WARNING: failed to set the role of %s MSSQLServer %s/%s to %s: %v\n", c.s.where, c.s.db.Namespace, c.s.db.Name, c.role, err)
+			}
+		}
+	}
+	fmt.Printf("DAG %s failed over: %s is the global primary, %s its secondary.\n", dag, fAG, pAG)
+	return nil
+}
+
+// dagLagBlockers returns why the lag gate refuses to fail over to the AG ag,
+// given the global primary's DAG rows of ag: it must be connected, and each
+// database must have at most maxLagKB KB of log not yet sent.
+func dagLagBlockers(rows []dagReplicaState, ag string, maxLagKB int64) []string {
+	var blockers []string
+	for _, r := range rows {
+		if r.connected != "CONNECTED" {
+			return append(blockers, fmt.Sprintf("%s is %s", ag, orNone(r.connected)))
+		}
+		if kb, err := strconv.ParseInt(r.sendQueueKB, 10, 64); err != nil || kb > maxLagKB {
+			blockers = append(blockers, fmt.Sprintf("database %s has %s KB of log not yet sent (--max-lag-kb %d)", orNone(r.database), orNone(r.sendQueueKB), maxLagKB))
+		}
+	}
+	return blockers
+}
+
+// modeOr returns the recorded availability mode, or fallback when the mode
+// was not recorded.
+func modeOr(recorded, fallback string) string {
+	if recorded == "" {
+		return fallback
+	}
+	return recorded
+}
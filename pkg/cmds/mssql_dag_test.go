@@ -0,0 +1,159 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"reflect"
+	"testing"
+
+	dboldapi "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateMSSQLDAGSecondary(t *testing.T) {
+	replicas := int32(3)
+	src := &dboldapi.MSSQLServer{}
+	src.Name, src.Namespace = "ms-primary", "demo"
+	src.Spec.Version = "2022-cu16"
+	src.Spec.Replicas = &replicas
+	src.Spec.DeletionPolicy = dboldapi.DeletionPolicyWipeOut
+	src.Spec.Topology = &dboldapi.MSSQLServerTopology{
+		Mode: ptrMode(dboldapi.MSSQLServerModeDistributedAG),
+		AvailabilityGroup: &dboldapi.MSSQLServerAvailabilityGroupSpec{
+			SecondaryAccessMode: dboldapi.SecondaryAccessModeReadOnly,
+			MasterKeySecretName: "shared-master-key",
+		},
+		DistributedAG: &dboldapi.MSSQLServerDistributedAGSpec{
+			Self: dboldapi.MSSQLServerDistributedAGSelfSpec{Role: dboldapi.DistributedAGRolePrimary, URL: "10.2.0.10:5022"},
+		},
+	}
+
+	out, err := generateMSSQLDAGSecondary(src, "ms-secondary", "10.2.0.64:5022")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got dboldapi.MSSQLServer
+	if err := yaml.Unmarshal(out, &got); err != nil {
+		t.Fatalf("generated manifest is not a valid MSSQLServer: %v\n%s", err, out)
+	}
+	if got.Kind != dboldapi.ResourceKindMSSQLServer || got.APIVersion != dboldapi.SchemeGroupVersion.String() {
+		t.Errorf("got %s %s, want %s %s", got.APIVersion, got.Kind, dboldapi.SchemeGroupVersion, dboldapi.ResourceKindMSSQLServer)
+	}
+	if got.Name != "ms-secondary" || got.Namespace != "demo" {
+		t.Errorf("got %s/%s, want demo/ms-secondary", got.Namespace, got.Name)
+	}
+	if got.Spec.Version != src.Spec.Version || got.Spec.Replicas == nil || *got.Spec.Replicas != replicas || got.Spec.DeletionPolicy != src.Spec.DeletionPolicy {
+		t.Errorf("version, replicas or deletionPolicy not copied from the source: %+v", got.Spec)
+	}
+	topo := got.Spec.Topology
+	if topo == nil || topo.Mode == nil || *topo.Mode != dboldapi.MSSQLServerModeDistributedAG || topo.AvailabilityGroup == nil || topo.DistributedAG == nil {
+		t.Fatalf("topology is not a DistributedAG one: %s", out)
+	}
+	ag := topo.AvailabilityGroup
+	// both sides must share the Secrets dag-config exports
+	if ag.MasterKeySecretName != "shared-master-key" || ag.LoginSecretName != src.DbmLoginSecretName() || ag.EndpointCertSecretName != src.EndpointCertSecretName() {
+		t.Errorf("availabilityGroup secrets = %q, %q, %q; want the source's", ag.LoginSecretName, ag.MasterKeySecretName, ag.EndpointCertSecretName)
+	}
+	if ag.SecondaryAccessMode != dboldapi.SecondaryAccessModeReadOnly {
+		t.Errorf("secondaryAccessMode = %q, want %q", ag.SecondaryAccessMode, dboldapi.SecondaryAccessModeReadOnly)
+	}
+	wantDAG := dboldapi.MSSQLServerDistributedAGSpec{
+		Self:   dboldapi.MSSQLServerDistributedAGSelfSpec{Role: dboldapi.DistributedAGRoleSecondary, URL: "10.2.0.64:5022"},
+		Remote: dboldapi.MSSQLServerDistributedAGRemoteSpec{Name: "msprimary", URL: "10.2.0.10:5022"},
+	}
+	if *topo.DistributedAG != wantDAG {
+		t.Errorf("distributedAG = %+v, want %+v", *topo.DistributedAG, wantDAG)
+	}
+}
+
+func ptrMode(m dboldapi.MSSQLServerMode) *dboldapi.MSSQLServerMode {
+	return &m
+}
+
+func TestLSNBehind(t *testing.T) {
+	cases := []struct {
+		a, b, want string
+	}{
+		{a: "38000000051200001", b: "38000000051200001", want: "0"},
+		{a: "38000000051200001", b: "38000000050400001", want: "800000"},
+		// beyond int64
+		{a: "123456789012345678901234567890", b: "123456789012345678901234567880", want: "10"},
+		{a: "", b: "38000000051200001", want: "-"},
+		{a: "38000000051200001", b: "NULL", want: "-"},
+	}
+	for _, c := range cases {
+		if got := lsnBehind(c.a, c.b); got != c.want {
+			t.Errorf("lsnBehind(%q, %q) = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestParseDAGStates(t *testing.T) {
+	out := "\n" +
+		"dag|DAG|msprimary|ASYNCHRONOUS_COMMIT|PRIMARY|CONNECTED|HEALTHY|agdb1|SYNCHRONIZED|38000000051200001|0|0|2026-10-12T09:14:02.123\n" +
+		"dag|DAG|mssecondary|ASYNCHRONOUS_COMMIT|SECONDARY|CONNECTED|HEALTHY|agdb1|SYNCHRONIZING|38000000050400001|12|3|2026-10-12T09:14:01.997\n" +
+		"msprimary|AG|ms-primary-1|SYNCHRONOUS_COMMIT|NULL|NULL|NULL|NULL|NULL|NULL|NULL|NULL|NULL\n" +
+		"Changed database context to 'master'.\n"
+	got := parseDAGStates(parseSQLCmdRows(out))
+	want := []dagReplicaState{
+		{
+			group: "dag", kind: "DAG", replica: "msprimary", mode: "ASYNCHRONOUS_COMMIT", role: "PRIMARY", connected: "CONNECTED", health: "HEALTHY",
+			database: "agdb1", sync: "SYNCHRONIZED", hardenedLSN: "38000000051200001", sendQueueKB: "0", redoQueueKB: "0", lastCommit: "2026-10-12T09:14:02.123",
+		},
+		{
+			group: "dag", kind: "DAG", replica: "mssecondary", mode: "ASYNCHRONOUS_COMMIT", role: "SECONDARY", connected: "CONNECTED", health: "HEALTHY",
+			database: "agdb1", sync: "SYNCHRONIZING", hardenedLSN: "38000000050400001", sendQueueKB: "12", redoQueueKB: "3", lastCommit: "2026-10-12T09:14:01.997",
+		},
+		{group: "msprimary", kind: "AG", replica: "ms-primary-1", mode: "SYNCHRONOUS_COMMIT"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDAGStates() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDAGLagBlockers(t *testing.T) {
+	row := func(connected, database, sendQueueKB string) dagReplicaState {
+		return dagReplicaState{kind: "DAG", replica: "mssecondary", connected: connected, database: database, sendQueueKB: sendQueueKB}
+	}
+	cases := []struct {
+		name     string
+		rows     []dagReplicaState
+		maxLagKB int64
+		want     []string
+	}{
+		{name: "caught up", rows: []dagReplicaState{row("CONNECTED", "agdb1", "0"), row("CONNECTED", "agdb2", "0")}},
+		{name: "within the budget", rows: []dagReplicaState{row("CONNECTED", "agdb1", "64")}, maxLagKB: 64},
+		{
+			name: "over the budget", rows: []dagReplicaState{row("CONNECTED", "agdb1", "65"), row("CONNECTED", "agdb2", "0")}, maxLagKB: 64,
+			want: []string{"database agdb1 has 65 KB of log not yet sent (--max-lag-kb 64)"},
+		},
+		{
+			name: "unknown queue", rows: []dagReplicaState{row("CONNECTED", "agdb1", "")},
+			want: []string{"database agdb1 has - KB of log not yet sent (--max-lag-kb 0)"},
+		},
+		{
+			name: "disconnected", rows: []dagReplicaState{row("DISCONNECTED", "agdb1", "0"), row("DISCONNECTED", "agdb2", "0")},
+			want: []string{"mssecondary is DISCONNECTED"},
+		},
+	}
+	for _, c := range cases {
+		if got := dagLagBlockers(c.rows, "mssecondary", c.maxLagKB); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: dagLagBlockers() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
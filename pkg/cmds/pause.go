@@ -18,7 +18,9 @@ package cmds
 
 import (
 	"fmt"
+	"time"
 
+	"kubedb.dev/cli/pkg/monitor"
 	"kubedb.dev/cli/pkg/pauser"
 
 	"github.com/spf13/cobra"
@@ -74,6 +76,9 @@ type PauseOptions struct {
 
 	genericclioptions.IOStreams
 
+	silenceFor   time.Duration
+	alertmanager monitor.AlertmanagerSvc
+
 	onlyDb       bool
 	onlyBackup   bool
 	onlyArchiver bool
@@ -105,6 +110,8 @@ func NewCmdPause(parent string, f cmdutil.Factory, streams genericclioptions.IOS
 	cmdutil.AddFilenameOptionFlags(cmd, o.FilenameOptions, usage)
 	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 	cmd.Flags().BoolVar(&o.AllNamespaces, "all-namespaces", o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().DurationVar(&o.silenceFor, "silence", 0, "If provided, silence the alerts of the database in Alertmanager for this duration (e.g. 2h).")
+	monitor.AddAlertmanagerFlags(cmd.Flags(), &o.alertmanager)
 	cmd.Flags().BoolVar(&o.onlyDb, "only-db", false, "If provided, only the database is paused.")
 	cmd.Flags().BoolVar(&o.onlyBackup, "only-backupconfig", false, "If provided, only the backupconfiguration for the database is paused.")
 	cmd.Flags().BoolVar(&o.onlyArchiver, "only-archiver", false, "If provided, only the archiver for the database is paused.")
@@ -167,6 +174,11 @@ func (o *PauseOptions) Run() error {
 
 	errs := sets.NewString()
 	for _, info := range infos {
+		if o.silenceFor > 0 {
+			if err := silenceForMaintenance(o.Factory, o.Out, o.alertmanager, o.silenceFor, "pause", info); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
 		psr, err := pauser.NewPauser(o.Factory, info.Mapping, o.onlyDb, o.onlyBackup, o.onlyArchiver)
 		if err != nil {
 			if errs.Has(err.Error()) {
@@ -0,0 +1,74 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"kubedb.dev/cli/pkg/monitor"
+	"kubedb.dev/cli/pkg/recommend"
+
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var recommendLong = templates.LongDesc(`
+		Recommend compute resources and storage for a database from its usage history in Prometheus.
+
+		For every container the CPU and memory usage percentiles over the window are compared with the
+		current requests and limits, and the volume usage growth is projected over the storage horizon.
+		With --emit-ops a VerticalScaling and a VolumeExpansion OpsRequest are printed to stdout, ready
+		to apply, while the summary goes to stderr. Databases scaled by a <Kind>Autoscaler get no
+		OpsRequest for the resources the autoscaler manages.
+    `)
+
+var recommendExample = templates.Examples(`
+		# Recommend resources for a postgres from the last week
+		kubectl dba recommend postgres pg-demo -n demo --window 7d \
+			--prom-svc-name=prometheus-kube-prometheus-prometheus --prom-svc-namespace=monitoring
+
+		# Apply the recommendation
+		kubectl dba recommend mongodb mg-demo -n demo --emit-ops | kubectl apply -f -
+`)
+
+func NewCmdRecommend(f cmdutil.Factory) *cobra.Command {
+	var (
+		opts  recommend.Options
+		rProm monitor.PromSvc
+	)
+	cmd := &cobra.Command{
+		Use:     "recommend",
+		Short:   i18n.T("Recommend resources and storage of a database from its metrics"),
+		Long:    recommendLong,
+		Example: recommendExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(rProm.Complete(cmd.Flags()))
+			recommend.Run(f, args, rProm, opts)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	monitor.AddPromFlags(cmd.Flags(), &rProm)
+	cmd.Flags().StringVar(&opts.Window, "window", "7d", "usage history to consider, e.g. 24h, 7d, 4w")
+	cmd.Flags().Float64Var(&opts.CPUPercentile, "cpu-percentile", 0.95, "percentile of cpu usage the request is sized for")
+	cmd.Flags().Float64Var(&opts.MemoryPercentile, "memory-percentile", 0.99, "percentile of memory usage the request is sized for")
+	cmd.Flags().Float64Var(&opts.Headroom, "headroom", 1.2, "factor applied on top of the observed usage")
+	cmd.Flags().StringVar(&opts.StorageHorizon, "storage-horizon", "90d", "period of volume growth the storage is sized for")
+	cmd.Flags().BoolVar(&opts.EmitOps, "emit-ops", false, "print VerticalScaling and VolumeExpansion OpsRequests to stdout")
+	cmd.Flags().StringVar(&opts.ExpansionMode, "expansion-mode", "Offline", "mode of the VolumeExpansion OpsRequest. One of: Online|Offline")
+	return cmd
+}
@@ -25,7 +25,7 @@ import (
 
 var (
 	desLong = "generate appbinding , secrets for remote replica"
-	example = "kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name>  <db_name>"
+	example = "kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name> --remote-kubeconfig <remote.yaml> <db_name>"
 )
 
 func NewCmdGenApb(f cmdutil.Factory) *cobra.Command {
@@ -41,5 +41,24 @@ func NewCmdGenApb(f cmdutil.Factory) *cobra.Command {
 	}
 	cmd.AddCommand(remote_replica.MysqlAPP(f))
 	cmd.AddCommand(remote_replica.PostgreSQlAPP(f))
+	cmd.AddCommand(remote_replica.MariaDBAPP(f))
+	cmd.AddCommand(remote_replica.MongoDBAPP(f))
+	return cmd
+}
+
+// NewCmdRemoteReplica groups the commands that operate on a remote replica
+// after remote-config has set it up.
+func NewCmdRemoteReplica(f cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remote-replica",
+		Short: "Inspect and manage remote replicas set up with remote-config",
+		Long:  "Inspect and manage remote replicas set up with remote-config. The source database is read on the current cluster, the replica on the cluster given by --remote-kubeconfig/--remote-context.",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.AddCommand(remote_replica.NewCmdStatus(f))
+	cmd.AddCommand(remote_replica.NewCmdRevoke(f))
+	cmd.AddCommand(remote_replica.NewCmdRotate(f))
 	return cmd
 }
@@ -18,7 +18,9 @@ package cmds
 
 import (
 	"fmt"
+	"time"
 
+	"kubedb.dev/cli/pkg/monitor"
 	"kubedb.dev/cli/pkg/restarter"
 
 	"github.com/spf13/cobra"
@@ -74,6 +76,9 @@ type RestartOptions struct {
 	FilenameOptions *resource.FilenameOptions
 
 	genericclioptions.IOStreams
+
+	silenceFor   time.Duration
+	alertmanager monitor.AlertmanagerSvc
 }
 
 func NewCmdRestart(parent string, f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
@@ -102,6 +107,8 @@ func NewCmdRestart(parent string, f cmdutil.Factory, streams genericclioptions.I
 	cmdutil.AddFilenameOptionFlags(cmd, o.FilenameOptions, usage)
 	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 	cmd.Flags().BoolVar(&o.AllNamespaces, "all-namespaces", o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().DurationVar(&o.silenceFor, "silence", 0, "If provided, silence the alerts of the database in Alertmanager for this duration (e.g. 2h).")
+	monitor.AddAlertmanagerFlags(cmd.Flags(), &o.alertmanager)
 
 	return cmd
 }
@@ -161,6 +168,11 @@ func (o *RestartOptions) Run() error {
 
 	errs := sets.NewString()
 	for _, info := range infos {
+		if o.silenceFor > 0 {
+			if err := silenceForMaintenance(o.Factory, o.Out, o.alertmanager, o.silenceFor, "restart", info); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
 		restarter, err := restarter.NewRestarter(o.Factory, info.Mapping)
 		if err != nil {
 			if errs.Has(err.Error()) {
@@ -64,6 +64,7 @@ func NewKubeDBCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 		{
 			Message: "Troubleshooting and Debugging Commands:",
 			Commands: []*cobra.Command{
+				NewCmdList(f, ioStreams),
 				NewCmdDescribe("kubedb", f, ioStreams),
 				NewCmdCompletion(),
 				v.NewCmdVersion(),
@@ -100,12 +101,14 @@ func NewKubeDBCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Message: "Debug any Database issue",
 			Commands: []*cobra.Command{
 				NewCmdDebug(f),
+				NewCmdGitOps(f, ioStreams),
 			},
 		},
 		{
 			Message: "Generate appbinding and secrets for remote Replica",
 			Commands: []*cobra.Command{
 				NewCmdGenApb(f),
+				NewCmdRemoteReplica(f),
 			},
 		},
 		{
@@ -124,6 +127,8 @@ func NewKubeDBCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Message: "Metric related CMDs",
 			Commands: []*cobra.Command{
 				NewCmdMonitor(f),
+				NewCmdTop(f),
+				NewCmdRecommend(f),
 			},
 		},
 	}
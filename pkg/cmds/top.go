@@ -0,0 +1,70 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"time"
+
+	"kubedb.dev/cli/pkg/monitor"
+	"kubedb.dev/cli/pkg/monitor/top"
+
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var topLong = templates.LongDesc(`
+		Show a live view of a database from Prometheus, refreshed every few seconds.
+
+		For every pod it shows the CPU, memory and disk usage against the requests,
+		limits and volume size. For postgres, mysql, mariadb, perconaxtradb, mongodb,
+		redis and elasticsearch it also shows the key performance indicators of the
+		engine, read from the exporter behind the database's stats service.
+    `)
+
+var topExample = templates.Examples(`
+		# Watch a postgres database
+		kubectl dba top postgres pg-demo -n demo \
+			--prom-svc-name=prometheus-kube-prometheus-prometheus --prom-svc-namespace=monitoring
+
+		# Print a single snapshot and exit
+		kubectl dba top mongodb mg-demo -n demo --once --prom-url=https://thanos-query.example.com
+`)
+
+func NewCmdTop(f cmdutil.Factory) *cobra.Command {
+	var (
+		opts    top.Options
+		topProm monitor.PromSvc
+	)
+	cmd := &cobra.Command{
+		Use:     "top",
+		Short:   i18n.T("Live performance view of a database from Prometheus"),
+		Long:    topLong,
+		Example: topExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(topProm.Complete(cmd.Flags()))
+			top.Run(f, args, topProm, opts)
+		},
+		DisableFlagsInUseLine: true,
+		DisableAutoGenTag:     true,
+	}
+	monitor.AddPromFlags(cmd.Flags(), &topProm)
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 5*time.Second, "refresh interval")
+	cmd.Flags().BoolVar(&opts.Once, "once", false, "print a single snapshot and exit")
+	return cmd
+}
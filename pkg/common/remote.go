@@ -0,0 +1,234 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"gomodules.xyz/pointer"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// FieldManager owns the fields the CLI applies on a remote cluster.
+const FieldManager = "kubectl-dba"
+
+// RemoteClusterFlags says where the generated remote-side objects go: applied
+// directly to the remote cluster, written to a file, or both. The objects carry
+// credentials and private keys, so neither happens unless asked for.
+type RemoteClusterFlags struct {
+	Kubeconfig string
+	Context    string
+	OutputFile string
+}
+
+// AddRemoteClusterFlags registers --remote-kubeconfig, --remote-context and
+// --output-file.
+func AddRemoteClusterFlags(fs *pflag.FlagSet, r *RemoteClusterFlags) {
+	AddRemoteKubeconfigFlags(fs, r)
+	fs.StringVar(&r.OutputFile, "output-file", "", "also write the generated objects to this file, created with mode 0600 (it contains credentials)")
+}
+
+// AddRemoteKubeconfigFlags registers only --remote-kubeconfig and
+// --remote-context, for commands that read from the remote cluster.
+func AddRemoteKubeconfigFlags(fs *pflag.FlagSet, r *RemoteClusterFlags) {
+	fs.StringVar(&r.Kubeconfig, "remote-kubeconfig", "", "kubeconfig of the remote cluster that runs the replica")
+	fs.StringVar(&r.Context, "remote-context", "", "context of the remote cluster (in --remote-kubeconfig, or in the default kubeconfig)")
+}
+
+// Remote reports whether the objects are to be applied to a remote cluster.
+func (r *RemoteClusterFlags) Remote() bool {
+	return r.Kubeconfig != "" || r.Context != ""
+}
+
+// Validate fails when the generated objects would go nowhere.
+func (r *RemoteClusterFlags) Validate() error {
+	if !r.Remote() && r.OutputFile == "" {
+		return errors.New("nowhere to put the generated objects: give --remote-kubeconfig and/or --remote-context to apply them to the remote cluster, or --output-file to write them (with credentials) to a file")
+	}
+	return nil
+}
+
+// RESTConfig loads the remote cluster's config. A context alone selects it from
+// the default kubeconfig.
+func (r *RemoteClusterFlags) RESTConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = r.Kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: r.Context}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the remote cluster's kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// Emit writes buffer to --output-file and applies it to the remote cluster, as
+// requested. It returns the remote config, or nil when nothing was applied.
+func (r *RemoteClusterFlags) Emit(ctx context.Context, out io.Writer, buffer []byte) (*rest.Config, error) {
+	if r.OutputFile != "" {
+		if err := WriteSecretFile(r.OutputFile, buffer); err != nil {
+			return nil, err
+		}
+		_, _ = fmt.Fprintf(out, "Wrote %s (mode 0600; it contains credentials, delete it once applied)\n", r.OutputFile)
+	}
+	if !r.Remote() {
+		return nil, nil
+	}
+	cfg, err := r.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	objs, err := ApplyManifests(ctx, cfg, buffer)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		_, _ = fmt.Fprintf(out, "  applied %s %s/%s on the remote cluster\n", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	return cfg, nil
+}
+
+// WriteSecretFile writes data to path with mode 0600. An existing file is
+// truncated and tightened to 0600 too.
+func WriteSecretFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := f.Chmod(0o600); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to restrict the permissions of %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// DecodeManifests splits a multi-document YAML stream into objects.
+func DecodeManifests(buffer []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(buffer), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("failed to decode the generated manifests: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// ApplyManifests server-side applies every object in buffer, creating their
+// namespaces first. Applying the same buffer again is a no-op.
+func ApplyManifests(ctx context.Context, config *rest.Config, buffer []byte) ([]*unstructured.Unstructured, error) {
+	objs, err := DecodeManifests(buffer)
+	if err != nil {
+		return nil, err
+	}
+	kc, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	disc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disc))
+
+	namespaces := map[string]bool{}
+	for _, obj := range objs {
+		if ns := obj.GetNamespace(); ns != "" {
+			namespaces[ns] = true
+		}
+	}
+	names := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	for _, ns := range names {
+		if err := ensureNamespace(ctx, kc, ns); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("the remote cluster does not serve %s (is KubeDB installed there?): %w", gvk, err)
+		}
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var ri dynamic.ResourceInterface = dc.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			ri = dc.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+		}
+		if _, err := ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: FieldManager,
+			Force:        pointer.TrueP(),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to apply %s %s/%s on the remote cluster: %w", gvk.Kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return objs, nil
+}
+
+func ensureNamespace(ctx context.Context, kc kubernetes.Interface, ns string) error {
+	_, err := kc.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		_, err = kc.CoreV1().Namespaces().Create(ctx, &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{})
+		if kerr.IsAlreadyExists(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to ensure namespace %s on the remote cluster: %w", ns, err)
+	}
+	return nil
+}
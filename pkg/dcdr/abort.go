@@ -25,8 +25,10 @@ import (
 
 // NewCmdAbort aborts an in-flight planned switchover. Acts on the HUB cluster.
 func NewCmdAbort(f cmdutil.Factory) *cobra.Command {
+	var reason string
+	var coordNS string
 	cmd := &cobra.Command{
-		Use:   "abort DB_NAME",
+		Use:   "abort [KIND/]DB_NAME",
 		Short: i18n.T("Abort an in-flight planned switchover and restore writes to the current active DC"),
 		Long: templates.LongDesc(`
 			Sets the dr.kubedb.com/switchover-abort annotation. Its PRESENCE aborts:
@@ -38,32 +40,45 @@ func NewCmdAbort(f cmdutil.Factory) *cobra.Command {
 			so a bare removal silently reappears. This explicit abort signal is
 			propagated and honored scope-wide. A switchover that cannot complete also
 			auto-aborts on its own after the switchover timeout (default 10m,
-			dr.kubedb.com/switchover-timeout to override).
+			dr.kubedb.com/switchover-timeout to override). --reason is required and
+			recorded in the audit trail.
 
 			KUBECONFIG: the hub cluster.`),
 		Example: templates.Examples(`
-			kubectl dba dc-dr abort pg-dcdr -n demo`),
+			kubectl dba dc-dr abort pg-dcdr -n demo --reason "target DC lost its uplink"`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return fmt.Errorf("exactly one database name is required")
 			}
+			if err := requireReason(reason); err != nil {
+				return err
+			}
 			ctx := context.Background()
 			db, dyn, ns, err := getDB(ctx, f, args[0])
 			if err != nil {
 				return err
 			}
+			if err := requireAnnotationContract(db, "abort"); err != nil {
+				return err
+			}
 			if db.GetAnnotations()[AnnSwitchoverTo] == "" && db.GetAnnotations()[AnnQuiesceActive] == "" {
-				cmd.Printf("No switchover appears to be in flight on %s/%s (no %s or %s annotation); setting the abort anyway is harmless.\n", ns, args[0], AnnSwitchoverTo, AnnQuiesceActive)
+				cmd.Printf("No switchover appears to be in flight on %s/%s (no %s or %s annotation); setting the abort anyway is harmless.\n", ns, db.GetName(), AnnSwitchoverTo, AnnQuiesceActive)
 			}
 			v := "true"
-			if err := annotateDB(ctx, dyn, ns, args[0], AnnSwitchoverAbort, &v); err != nil {
+			if err := annotateDB(ctx, dyn, db, AnnSwitchoverAbort, &v); err != nil {
 				return err
 			}
-			cmd.Printf("Abort requested for %s/%s. The hub restores writes to the current active DC and clears the switchover annotations; verify with:\n", ns, args[0])
+			recordAudit(ctx, f, coordNS, printfErr(cmd), AuditRecord{
+				Command: "abort", Database: dbAuditRef(db), Scope: auditScope(ctx, dyn, db),
+				Previous: db.GetAnnotations()[AnnSwitchoverTo], New: "aborted", Reason: reason,
+			})
+			cmd.Printf("Abort requested for %s/%s. The hub restores writes to the current active DC and clears the switchover annotations; verify with:\n", ns, db.GetName())
 			cmd.Printf("  kubectl dba dc-dr status %s -n %s\n", args[0], ns)
 			return nil
 		},
 	}
+	AddReasonFlag(cmd, &reason)
+	AddCoordNamespaceFlag(cmd, &coordNS)
 	return cmd
 }
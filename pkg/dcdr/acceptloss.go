@@ -28,8 +28,10 @@ import (
 // on the HUB cluster.
 func NewCmdAcceptDataLoss(f cmdutil.Factory) *cobra.Command {
 	var yes bool
+	var reason string
+	var coordNS string
 	cmd := &cobra.Command{
-		Use:   "accept-data-loss DB_NAME --yes",
+		Use:   "accept-data-loss [KIND/]DB_NAME --yes",
 		Short: i18n.T("Release a failover held by the RPO budget, explicitly accepting the data loss"),
 		Long: templates.LongDesc(`
 			When the surviving data center lags more than
@@ -41,7 +43,8 @@ func NewCmdAcceptDataLoss(f cmdutil.Factory) *cobra.Command {
 			promotion paths (the hub gate and the coordinator's data-plane gate)
 			honor it within seconds, and the operator removes the annotation
 			automatically once the failover it authorized lands, so it cannot linger
-			and approve a later, unrelated loss.
+			and approve a later, unrelated loss. --reason is required and recorded
+			in the audit trail together with the measured lag.
 
 			Where the hold is visible before you decide:
 			status.disasterRecovery.protectionMessage (the measured lag),
@@ -53,17 +56,23 @@ func NewCmdAcceptDataLoss(f cmdutil.Factory) *cobra.Command {
 			kubectl dba dc-dr status pg-dcdr -n demo
 
 			# Accept it
-			kubectl dba dc-dr accept-data-loss pg-dcdr -n demo --yes`),
+			kubectl dba dc-dr accept-data-loss pg-dcdr -n demo --yes --reason "dc-a lost, outage costs more than the gap"`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return fmt.Errorf("exactly one database name is required")
 			}
+			if err := requireReason(reason); err != nil {
+				return err
+			}
 			ctx := context.Background()
 			db, dyn, ns, err := getDB(ctx, f, args[0])
 			if err != nil {
 				return err
 			}
+			if err := requireAnnotationContract(db, "accept-data-loss"); err != nil {
+				return err
+			}
 			msg, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "protectionMessage")
 			protected, protectedFound, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected")
 			if msg != "" {
@@ -76,14 +85,20 @@ func NewCmdAcceptDataLoss(f cmdutil.Factory) *cobra.Command {
 				return fmt.Errorf("this authorizes losing committed data beyond the configured budget; re-run with --yes to confirm")
 			}
 			v := "true"
-			if err := annotateDB(ctx, dyn, ns, args[0], AnnAcceptDataLoss, &v); err != nil {
+			if err := annotateDB(ctx, dyn, db, AnnAcceptDataLoss, &v); err != nil {
 				return err
 			}
-			cmd.Printf("Data-loss acceptance recorded on %s/%s. The held promotion proceeds within seconds; the annotation is removed automatically once the failover lands.\n", ns, args[0])
+			recordAudit(ctx, f, coordNS, printfErr(cmd), AuditRecord{
+				Command: "accept-data-loss", Database: dbAuditRef(db), Scope: auditScope(ctx, dyn, db),
+				Previous: msg, New: "data loss accepted", Reason: reason,
+			})
+			cmd.Printf("Data-loss acceptance recorded on %s/%s. The held promotion proceeds within seconds; the annotation is removed automatically once the failover lands.\n", ns, db.GetName())
 			cmd.Printf("Monitor:  kubectl dba dc-dr status %s -n %s\n", args[0], ns)
 			return nil
 		},
 	}
 	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm accepting data loss beyond the configured RPO budget")
+	AddReasonFlag(cmd, &reason)
+	AddCoordNamespaceFlag(cmd, &coordNS)
 	return cmd
 }
@@ -33,7 +33,7 @@ func NewCmdActiveDC(f cmdutil.Factory) *cobra.Command {
 	var leaseName string
 	var quiet bool
 	cmd := &cobra.Command{
-		Use:   "active-dc [DB_NAME] [--lease NAME]",
+		Use:   "active-dc [[KIND/]DB_NAME] [--lease NAME]",
 		Short: i18n.T("Print the data center that currently holds the primary role"),
 		Long: templates.LongDesc(`
 			Reads the primary-DC Lease from the coordination control plane, the
@@ -44,7 +44,7 @@ func NewCmdActiveDC(f cmdutil.Factory) *cobra.Command {
 			resolves it) and the matching Lease is read. Given --lease, that Lease is
 			read directly, which also works for a scope whose database is gone.
 
-			KUBECONFIG: the hub cluster (to read the Postgres and its
+			KUBECONFIG: the hub cluster (to read the database and its
 			PlacementPolicy, and by default to read the coordination kubeconfig
 			Secret). The coordination plane itself is reached with the --coord-*
 			flags.`),
@@ -124,7 +124,7 @@ func NewCmdActiveDC(f cmdutil.Factory) *cobra.Command {
 				if err == nil {
 					crActive, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
 					if crActive != "" && crActive != holder {
-						_, _ = fmt.Fprintf(out, "\nNOTE: the database %s/%s still reports activeDC=%s in its status; the Lease is the authority and the status trails it by a reconcile.\n", ns, args[0], crActive)
+						_, _ = fmt.Fprintf(out, "\nNOTE: the database %s/%s still reports activeDC=%s in its status; the Lease is the authority and the status trails it by a reconcile.\n", ns, db.GetName(), crActive)
 					}
 				}
 			}
@@ -0,0 +1,380 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The audit trail is a bounded ConfigMap in the coordination namespace of the
+// cluster the mutating command talked to: the hub for everything but the pins,
+// which act on a spoke and record there, and the override-hold clear of
+// pin-primary --remove --force, which acts on the coordination plane and
+// records there. One JSON record per line, oldest first; the oldest records are dropped beyond auditMaxRecords so the object
+// stays well below the 1MiB limit.
+const (
+	AuditConfigMap  = "dcdr-audit"
+	auditKey        = "audit.jsonl"
+	auditMaxRecords = 1000
+)
+
+// AuditRecord is one mutating dc-dr action.
+type AuditRecord struct {
+	Time     metav1.Time  `json:"time"`
+	Actor    string       `json:"actor"`
+	Command  string       `json:"command"`
+	Database *DatabaseRef `json:"database,omitempty"`
+	Scope    string       `json:"scope,omitempty"`
+	Previous string       `json:"previous,omitempty"`
+	New      string       `json:"new,omitempty"`
+	Reason   string       `json:"reason"`
+}
+
+// AddReasonFlag registers the --reason flag every mutating command requires.
+func AddReasonFlag(cmd *cobra.Command, reason *string) {
+	cmd.Flags().StringVar(reason, "reason", "", "Why this action is taken; recorded in the DC-DR audit trail (required)")
+}
+
+func requireReason(reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason is required: every DC-DR action is recorded in the audit trail (see dc-dr history)")
+	}
+	return nil
+}
+
+// auditActor is the user the current cluster authenticates the command as.
+// Clusters that do not serve SelfSubjectReview get the name of the kubeconfig
+// user of the current context instead, which is only a local label.
+func auditActor(ctx context.Context, f cmdutil.Factory) string {
+	if kc, err := f.KubernetesClientSet(); err == nil {
+		review, err := kc.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+		if err == nil && review.Status.UserInfo.Username != "" {
+			return review.Status.UserInfo.Username
+		}
+	}
+	raw, err := f.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return "unknown"
+	}
+	if c, ok := raw.Contexts[raw.CurrentContext]; ok && c.AuthInfo != "" {
+		return "kubeconfig user " + c.AuthInfo
+	}
+	return "unknown"
+}
+
+func dbAuditRef(db *unstructured.Unstructured) *DatabaseRef {
+	return &DatabaseRef{Kind: db.GetKind(), Namespace: db.GetNamespace(), Name: db.GetName()}
+}
+
+// auditScope is the Lease name of db's scope, or empty when it cannot be
+// resolved; the audit record is still written.
+func auditScope(ctx context.Context, dyn dynamic.Interface, db *unstructured.Unstructured) string {
+	scope, err := ResolveScopeForDB(ctx, dyn, db)
+	if err != nil {
+		return ""
+	}
+	return scope.LeaseName
+}
+
+// recordAudit appends rec to the audit ConfigMap in namespace ns of the current
+// cluster. The action has already happened, so a failure is reported as a
+// warning rather than failing the command.
+func recordAudit(ctx context.Context, f cmdutil.Factory, ns string, warn func(string, ...any), rec AuditRecord) {
+	kc, err := f.KubernetesClientSet()
+	if err != nil {
+		warn("WARNING: the action succeeded but could not be recorded in the audit trail %s/%s: %v\n", ns, AuditConfigMap, err)
+		return
+	}
+	recordAuditTo(ctx, f, kc, ns, warn, rec)
+}
+
+// recordAuditTo is recordAudit for the cluster of kc, for the actions that act
+// on another cluster than the current one.
+func recordAuditTo(ctx context.Context, f cmdutil.Factory, kc kubernetes.Interface, ns string, warn func(string, ...any), rec AuditRecord) {
+	rec.Time = metav1.Now()
+	if rec.Actor == "" {
+		rec.Actor = auditActor(ctx, f)
+	}
+	if err := appendAudit(ctx, kc, ns, rec); err != nil {
+		warn("WARNING: the action succeeded but could not be recorded in the audit trail %s/%s: %v\n", ns, AuditConfigMap, err)
+	}
+}
+
+func appendAudit(ctx context.Context, kc kubernetes.Interface, ns string, rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	cms := kc.CoreV1().ConfigMaps(ns)
+	// other operators append concurrently: re-read and retry on a conflict
+	for attempt := 1; ; attempt++ {
+		err = appendAuditLine(ctx, cms, ns, string(line))
+		if !kerr.IsConflict(err) || attempt == 5 {
+			return err
+		}
+	}
+}
+
+func appendAuditLine(ctx context.Context, cms corev1client.ConfigMapInterface, ns, line string) error {
+	cm, err := cms.Get(ctx, AuditConfigMap, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		_, err = cms.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: AuditConfigMap, Namespace: ns},
+			Data:       map[string]string{auditKey: line + "\n"},
+		}, metav1.CreateOptions{})
+		if kerr.IsAlreadyExists(err) {
+			return kerr.NewConflict(corev1.Resource("configmaps"), AuditConfigMap, err)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lines := append(splitAuditLines(cm.Data[auditKey]), line)
+	if len(lines) > auditMaxRecords {
+		lines = lines[len(lines)-auditMaxRecords:]
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[auditKey] = strings.Join(lines, "\n") + "\n"
+	_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+func splitAuditLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// readAudit returns the records of the audit trail in namespace ns of the
+// cluster of kc.
+func readAudit(ctx context.Context, kc kubernetes.Interface, ns string) ([]AuditRecord, error) {
+	cm, err := kc.CoreV1().ConfigMaps(ns).Get(ctx, AuditConfigMap, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []AuditRecord
+	for _, l := range splitAuditLines(cm.Data[auditKey]) {
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(l), &rec); err == nil {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// historyEntry is one line of the dc-dr history timeline.
+type historyEntry struct {
+	at     time.Time
+	source string
+	text   string
+}
+
+// NewCmdHistory renders the audit trail of a database or scope, merged with the
+// Lease holder transitions recorded on the coordination plane.
+func NewCmdHistory(f cmdutil.Factory) *cobra.Command {
+	var cf CoordFlags
+	var leaseName string
+	var since time.Duration
+	cmd := &cobra.Command{
+		Use:   "history ([KIND/]DB_NAME | --lease NAME)",
+		Short: i18n.T("Show who did what to a database or failover scope, merged with the Lease holder transitions"),
+		Long: templates.LongDesc(`
+			Every mutating dc-dr command (switchover, abort, accept-data-loss,
+			handoff, pin-primary, pin-standby and drill) requires --reason and
+			appends a record to the audit ConfigMap dcdr-audit in the
+			--coord-namespace (default dc-failover) of the cluster it talked to:
+			actor (the authenticated user), command, database, scope, previous and
+			new value, and the reason.
+
+			This prints, oldest first, the records of a database (and the
+			scope-wide actions on its scope), or of a scope given with --lease,
+			merged with the Lease events and the last holder acquisition read from
+			the coordination plane.
+
+			The records of the coordination plane's trail, where pin-primary
+			--remove --force records its Lease override-hold clear, are merged in.
+			The pins otherwise act on a spoke and record their audit there; run
+			history against that spoke to see them.
+
+			KUBECONFIG: the hub cluster; the coordination plane via --coord-*.`),
+		Example: templates.Examples(`
+			kubectl dba dc-dr history pg-dcdr -n demo
+
+			# A whole scope, last 7 days
+			kubectl dba dc-dr history --lease primary-dc --since 168h`),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 0) == (leaseName == "") {
+				return fmt.Errorf("give exactly one of: a database name, or --lease")
+			}
+			ctx := context.Background()
+			out := cmd.OutOrStdout()
+			scope := &Scope{LeaseName: leaseName}
+			var ref *DatabaseRef
+			if len(args) == 1 {
+				db, dyn, _, err := getDB(ctx, f, args[0])
+				if err != nil {
+					return err
+				}
+				if scope, err = ResolveScopeForDB(ctx, dyn, db); err != nil {
+					return err
+				}
+				ref = dbAuditRef(db)
+			}
+
+			kc, err := f.KubernetesClientSet()
+			if err != nil {
+				return err
+			}
+			records, err := readAudit(ctx, kc, cf.LeaseNS)
+			if err != nil {
+				return fmt.Errorf("failed to read the audit trail: %w", err)
+			}
+			// the coordination plane may be the current cluster itself
+			if coord, cerr := cf.CoordClient(ctx, f); cerr == nil {
+				if coordRecords, cerr := readAudit(ctx, coord, cf.LeaseNS); cerr == nil {
+					seen := map[string]bool{}
+					for _, r := range records {
+						seen[r.Time.UTC().Format(time.RFC3339)+renderAuditRecord(r)] = true
+					}
+					for _, r := range coordRecords {
+						if !seen[r.Time.UTC().Format(time.RFC3339)+renderAuditRecord(r)] {
+							records = append(records, r)
+						}
+					}
+				}
+			}
+			var entries []historyEntry
+			for _, r := range records {
+				matchDB := ref != nil && r.Database != nil && r.Database.Kind == ref.Kind && r.Database.Namespace == ref.Namespace && r.Database.Name == ref.Name
+				matchScope := r.Scope == scope.LeaseName && (ref == nil || r.Database == nil)
+				if !matchDB && !matchScope {
+					continue
+				}
+				entries = append(entries, historyEntry{at: r.Time.Time, source: "audit", text: renderAuditRecord(r)})
+			}
+			leaseEntries, lerr := leaseHistory(ctx, f, &cf, scope.LeaseName)
+			entries = append(entries, leaseEntries...)
+
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.Before(entries[j].at) })
+			_, _ = fmt.Fprintf(out, "History of scope %s", scope.LeaseName)
+			if ref != nil {
+				_, _ = fmt.Fprintf(out, " and %s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+			}
+			_, _ = fmt.Fprintln(out)
+			n := 0
+			for _, e := range entries {
+				if since > 0 && time.Since(e.at) > since {
+					continue
+				}
+				n++
+				_, _ = fmt.Fprintf(out, "  %s  %-6s %s\n", e.at.UTC().Format(time.RFC3339), e.source, e.text)
+			}
+			if n == 0 {
+				_, _ = fmt.Fprintln(out, "  no recorded actions or Lease transitions")
+			}
+			if lerr != nil {
+				_, _ = fmt.Fprintf(out, "\nLease transitions unavailable: %v\n", lerr)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&leaseName, "lease", "", "Show the history of this scope's Lease instead of a database")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show entries newer than this (default: everything retained)")
+	AddCoordFlags(cmd, &cf)
+	return cmd
+}
+
+func renderAuditRecord(r AuditRecord) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%s by %s", r.Command, r.Actor)
+	if r.Database != nil {
+		_, _ = fmt.Fprintf(&b, " on %s %s/%s", r.Database.Kind, r.Database.Namespace, r.Database.Name)
+	}
+	if r.Scope != "" {
+		_, _ = fmt.Fprintf(&b, " (scope %s)", r.Scope)
+	}
+	if r.Previous != "" || r.New != "" {
+		_, _ = fmt.Fprintf(&b, ": %s -> %s", orNone(r.Previous), orNone(r.New))
+	}
+	_, _ = fmt.Fprintf(&b, "; reason: %q", r.Reason)
+	return b.String()
+}
+
+// leaseHistory returns the Lease's events and its last acquisition from the
+// coordination plane. The Lease keeps only its current holder, so older
+// transitions are known only while their events are retained.
+func leaseHistory(ctx context.Context, f cmdutil.Factory, cf *CoordFlags, leaseName string) ([]historyEntry, error) {
+	coord, err := cf.CoordClient(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	lease, err := coord.CoordinationV1().Leases(cf.LeaseNS).Get(ctx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if lease.Spec.AcquireTime != nil && lease.Spec.HolderIdentity != nil {
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		entries = append(entries, historyEntry{at: lease.Spec.AcquireTime.Time, source: "lease", text: fmt.Sprintf("%s acquired %s (transition #%d)", *lease.Spec.HolderIdentity, leaseName, transitions)})
+	}
+	events, err := coord.CoreV1().Events(cf.LeaseNS).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Lease,involvedObject.name=" + leaseName,
+	})
+	if err != nil {
+		return entries, nil
+	}
+	for _, ev := range events.Items {
+		at := ev.LastTimestamp.Time
+		if at.IsZero() {
+			at = ev.EventTime.Time
+		}
+		entries = append(entries, historyEntry{at: at, source: "lease", text: fmt.Sprintf("%s: %s", ev.Reason, ev.Message)})
+	}
+	return entries, nil
+}
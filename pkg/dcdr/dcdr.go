@@ -26,6 +26,9 @@ limitations under the License.
 //   - A SPOKE cluster (one data center): the pin commands create their marker
 //     ConfigMaps on a specific DC's spoke, so for those the ordinary kubeconfig
 //     flags must point AT that spoke.
+//
+// Databases are named as KIND/NAME (postgres/pg-dcdr, mysql/my-dcdr, ...), or as
+// a bare NAME for a Postgres. See Engines for the accepted kinds.
 package dcdr
 
 import (
@@ -52,12 +55,12 @@ import (
 // not part of the released apimachinery this CLI vendors, and the commands only
 // need a handful of well-known paths.
 var (
-	PostgresGVR  = schema.GroupVersionResource{Group: "kubedb.com", Version: "v1", Resource: "postgreses"}
+	PostgresGVR  = Engines[0].GVR
 	PlacementGVR = schema.GroupVersionResource{Group: "apps.k8s.appscode.com", Version: "v1", Resource: "placementpolicies"}
-	PgOpsGVR     = schema.GroupVersionResource{Group: "ops.kubedb.com", Version: "v1alpha1", Resource: "postgresopsrequests"}
+	PgOpsGVR     = Engines[0].OpsGVR
 )
 
-// The annotation and naming contract shared with the KubeDB Postgres operator and
+// The annotation and naming contract shared with the KubeDB operators and
 // the dr-controlplane service. Keep byte for byte in sync with
 // postgres/pkg/dcdr/helpers.go and dr-controlplane/pkg/leases/names.go.
 const (
@@ -78,6 +81,11 @@ const (
 	OverrideCMSuffix    = "-override"
 	StandbyHoldCMSuffix = "-standby-hold"
 
+	// The keys of the marker ConfigMap a DC's agent projects from the Lease:
+	// the holder it saw and when it last renewed the marker (RFC 3339).
+	MarkerHolderKey    = "holderIdentity"
+	MarkerRenewTimeKey = "renewTime"
+
 	// DefaultCoordNamespace is where the Leases and marker ConfigMaps live, on the
 	// coordination plane and on every spoke respectively.
 	DefaultCoordNamespace = "dc-failover"
@@ -95,7 +103,15 @@ func NewCmdDCDR(f cmdutil.Factory) *cobra.Command {
 			Operate a KubeDB database that is distributed across data centers:
 			trigger and monitor planned switchovers, accept a held failover's data
 			loss, move the failover authority, pin a data center, and diagnose a
-			failover that is not happening.`),
+			failover that is not happening.
+
+			Databases are given as KIND/NAME (for example mysql/my-dcdr), or as a
+			bare NAME for a Postgres. Switchover, abort and accept-data-loss need
+			an operator that implements the DC-DR annotation contract; the other
+			commands work for any kind that publishes status.disasterRecovery.
+
+			Every command that changes something requires --reason and is
+			recorded in an audit trail; read it back with dc-dr history.`),
 		Run:                   func(cmd *cobra.Command, args []string) {},
 		DisableFlagsInUseLine: true,
 		DisableAutoGenTag:     true,
@@ -103,6 +119,7 @@ func NewCmdDCDR(f cmdutil.Factory) *cobra.Command {
 	cmd.AddCommand(
 		NewCmdSwitchover(f),
 		NewCmdStatus(f),
+		NewCmdWait(f),
 		NewCmdAbort(f),
 		NewCmdAcceptDataLoss(f),
 		NewCmdDebug(f),
@@ -110,6 +127,9 @@ func NewCmdDCDR(f cmdutil.Factory) *cobra.Command {
 		NewCmdPin(f, pinPrimary),
 		NewCmdPin(f, pinStandby),
 		NewCmdActiveDC(f),
+		NewCmdOverview(f),
+		NewCmdDrill(f),
+		NewCmdHistory(f),
 	)
 	return cmd
 }
@@ -136,7 +156,13 @@ func AddCoordFlags(cmd *cobra.Command, cf *CoordFlags) {
 	cmd.Flags().StringVar(&cf.File, "coord-kubeconfig", "", "Path to a kubeconfig file for the coordination control plane (overrides the secret/configmap sources)")
 	cmd.Flags().StringVar(&cf.Secret, "coord-kubeconfig-secret", DefaultCoordNamespace+"/coord-kubeconfig", "Secret ([namespace/]name, key \"kubeconfig\") on the current cluster holding the coordination-plane kubeconfig")
 	cmd.Flags().StringVar(&cf.ConfigMap, "coord-kubeconfig-configmap", "", "ConfigMap ([namespace/]name, key \"kubeconfig\") on the current cluster holding the coordination-plane kubeconfig")
-	cmd.Flags().StringVar(&cf.LeaseNS, "coord-namespace", DefaultCoordNamespace, "Namespace on the coordination plane that holds the primary-DC Leases")
+	AddCoordNamespaceFlag(cmd, &cf.LeaseNS)
+}
+
+// AddCoordNamespaceFlag registers --coord-namespace alone, for the commands that
+// only act on the hub but record their audit trail where history reads it.
+func AddCoordNamespaceFlag(cmd *cobra.Command, ns *string) {
+	cmd.Flags().StringVar(ns, "coord-namespace", DefaultCoordNamespace, "Namespace on the coordination plane that holds the primary-DC Leases")
 }
 
 func splitNSName(s, defaultNS string) (ns, name string) {
@@ -193,12 +219,12 @@ func (cf *CoordFlags) CoordClient(ctx context.Context, f cmdutil.Factory) (kuber
 
 // Scope is a database's failover scope resolved to its Lease name.
 type Scope struct {
-	LeaseName string
+	LeaseName string `json:"leaseName"`
 	// Source explains where the scope came from, for human output.
-	Source string
+	Source string `json:"source"`
 	// MemberDCs are the data-bearing Member DCs from the PlacementPolicy, empty
 	// when the policy was not resolvable.
-	MemberDCs []string
+	MemberDCs []string `json:"memberDCs,omitempty"`
 }
 
 // ResolveScopeForDB mirrors the operator's scopeForDB: the PlacementPolicy's
@@ -270,39 +296,6 @@ func memberDCsFromPP(pp *unstructured.Unstructured) []string {
 	return members
 }
 
-// getDB fetches the Postgres CR unstructured, resolving the namespace from the
-// factory's kubeconfig flags.
-func getDB(ctx context.Context, f cmdutil.Factory, name string) (*unstructured.Unstructured, dynamic.Interface, string, error) {
-	ns, _, err := f.ToRawKubeConfigLoader().Namespace()
-	if err != nil {
-		return nil, nil, "", err
-	}
-	cfg, err := f.ToRESTConfig()
-	if err != nil {
-		return nil, nil, "", err
-	}
-	dyn, err := dynamic.NewForConfig(cfg)
-	if err != nil {
-		return nil, nil, "", err
-	}
-	db, err := dyn.Resource(PostgresGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		return nil, nil, "", fmt.Errorf("failed to get Postgres %s/%s: %w", ns, name, err)
-	}
-	return db, dyn, ns, nil
-}
-
-// annotateDB merge-patches one annotation onto the Postgres CR (nil value removes).
-func annotateDB(ctx context.Context, dyn dynamic.Interface, ns, name, key string, value *string) error {
-	var v any
-	if value != nil {
-		v = *value
-	}
-	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, key, jsonValue(v))
-	_, err := dyn.Resource(PostgresGVR).Namespace(ns).Patch(ctx, name, mergePatchType, []byte(patch), metav1.PatchOptions{})
-	return err
-}
-
 func jsonValue(v any) string {
 	if v == nil {
 		return "null"
@@ -77,8 +77,9 @@ func (fd finding) print(w io.Writer) {
 // nothing failed over".
 func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 	var cf CoordFlags
+	var sf SpokeFlags
 	cmd := &cobra.Command{
-		Use:   "failover DB_NAME",
+		Use:   "failover [KIND/]DB_NAME",
 		Short: i18n.T("Diagnose why a cross-DC failover is not happening"),
 		Long: templates.LongDesc(`
 			Walks every gate between "something is wrong" and "another data center is
@@ -89,15 +90,23 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 			  2. does the Lease exist, who holds it, and is it still being renewed
 			     (a renewed Lease means the holder's agent is alive: by design NO
 			     database-level condition, client errors, QPS, lag, or a crashed
-			     postgres, ever moves it);
+			     database, ever moves it);
 			  3. is a break-glass pin or a standby-hold blocking the move;
 			  4. is the RPO budget holding the promotion (the accept remedy);
 			  5. are there stale or failed ForceFailOver ops, or a tripped retry cap,
 			     that make the hub skip evaluation.
 
-			KUBECONFIG: the hub cluster; the coordination plane via --coord-*.`),
+			Pins and standby-holds live on each data center's own spoke. Give the
+			spokes with --spoke-kubeconfig or --spoke-kubeconfig-secret and they
+			are read too, along with each spoke's marker.
+
+			KUBECONFIG: the hub cluster; the coordination plane via --coord-*; the
+			spokes via --spoke-*.`),
 		Example: templates.Examples(`
-			kubectl dba dc-dr debug failover pg-dcdr -n demo`),
+			kubectl dba dc-dr debug failover pg-dcdr -n demo
+
+			# Including every spoke's marker, pins and standby-holds
+			kubectl dba dc-dr debug failover pg-dcdr -n demo --spoke-kubeconfig dc-a=a.yaml,dc-b=b.yaml`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
@@ -113,7 +122,8 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			_, _ = fmt.Fprintf(out, "Diagnosing failover for %s/%s\n\n", ns, args[0])
+			warnIfNoDRStatus(printfErr(cmd), db)
+			_, _ = fmt.Fprintf(out, "Diagnosing failover for %s/%s\n\n", ns, db.GetName())
 
 			var findings []finding
 
@@ -166,7 +176,7 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 				findings = append(findings, finding{
 					title:  fmt.Sprintf("holder %q is renewing normally, so the authority will NOT move on its own", holder),
 					detail: fmt.Sprintf("the Lease was renewed %s ago, inside its %ds duration: that data center's agent is alive and healthy", age, dur),
-					remedy: "this is by design: no database-level condition (client errors, QPS, lag, a crashed postgres) ever moves the Lease. If the DATABASE is down but the DC is alive, either let the DC's own raft promote a local peer, or move the scope deliberately: kubectl dba dc-dr handoff " + args[0] + " -n " + ns + " --to <other-dc> --yes",
+					remedy: "this is by design: no database-level condition (client errors, QPS, lag, a crashed database) ever moves the Lease. If the DATABASE is down but the DC is alive, either let the DC's own raft promote a local peer, or move the scope deliberately: kubectl dba dc-dr handoff " + args[0] + " -n " + ns + " --to <other-dc> --yes --reason <why>",
 				})
 			default:
 				findings = append(findings, finding{ok: true, title: fmt.Sprintf("Lease is EXPIRED (holder %q last renewed %s ago, duration %ds)", holder, age, dur), detail: "a healthy Member DC should acquire it within one retry tick"})
@@ -174,23 +184,30 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 
 			// 3. pins
 			if pin := lease.Annotations["dr.open-cluster-management.io/override-hold"]; pin != "" {
-				findings = append(findings, finding{title: "no break-glass pin is blocking the move", detail: fmt.Sprintf("scope is PINNED to %q", pin), remedy: "remove that DC's override ConfigMap: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --remove --yes (run against that DC's spoke)", blocker: true})
+				findings = append(findings, finding{title: "no break-glass pin is blocking the move", detail: fmt.Sprintf("scope is PINNED to %q", pin), remedy: "remove that DC's override ConfigMap: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --remove --yes --reason <why> (run against that DC's spoke)", blocker: true})
 			} else {
 				findings = append(findings, finding{ok: true, title: "no break-glass pin on the Lease"})
 			}
 			if ho := lease.Annotations[AnnLeaseHandoffTo]; ho != "" {
-				findings = append(findings, finding{title: "no handoff is stuck in flight", detail: fmt.Sprintf("handoff-to=%q is still set, so the target has not acquired yet", ho), remedy: "if the target is standby-held it will never take it: kubectl dba dc-dr pin-standby --scope " + scope.LeaseName + " --remove --yes (against that DC's spoke)"})
+				findings = append(findings, finding{title: "no handoff is stuck in flight", detail: fmt.Sprintf("handoff-to=%q is still set, so the target has not acquired yet", ho), remedy: "if the target is standby-held it will never take it: kubectl dba dc-dr pin-standby --scope " + scope.LeaseName + " --remove --yes --reason <why> (against that DC's spoke)"})
 			}
 			findings = append(findings, checkStandbyHolds(ctx, coord, cf.LeaseNS, scope, holder)...)
+			if sf.Enabled() {
+				findings = append(findings, spokeFindings(readSpokes(ctx, f, &sf, cf.LeaseNS, scope.LeaseName), scope.LeaseName, holder)...)
+			}
 
 			// 4. the RPO budget
 			protected, protSet, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected")
 			protMsg, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "protectionMessage")
 			if protSet && !protected {
+				remedy := "if this is a real failover and the loss is acceptable: kubectl dba dc-dr accept-data-loss " + args[0] + " -n " + ns + " --yes --reason <why>"
+				if e, err := engineFor(db); err != nil || !e.AnnotationContract {
+					remedy = "the " + db.GetKind() + " operator does not honor the accept-data-loss annotation; if the loss is acceptable, move the scope deliberately: kubectl dba dc-dr handoff " + args[0] + " -n " + ns + " --to <other-dc> --yes --reason <why>"
+				}
 				findings = append(findings, finding{
 					title:  "promotion is not held by the RPO budget",
 					detail: fmt.Sprintf("protected=false: %s", protMsg),
-					remedy: "if this is a real failover and the loss is acceptable: kubectl dba dc-dr accept-data-loss " + args[0] + " -n " + ns + " --yes",
+					remedy: remedy,
 				})
 			} else if protSet {
 				findings = append(findings, finding{ok: true, title: "protection is confirmed (RPO budget satisfied)"})
@@ -200,7 +217,7 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 			}
 
 			// 5. ops objects and conditions
-			findings = append(findings, checkFailoverOps(ctx, f, ns, args[0])...)
+			findings = append(findings, checkFailoverOps(ctx, f, db)...)
 			findings = append(findings, checkConditions(db)...)
 
 			printFindings(out, findings)
@@ -211,12 +228,13 @@ func newCmdDebugFailover(f cmdutil.Factory) *cobra.Command {
 		},
 	}
 	AddCoordFlags(cmd, &cf)
+	AddSpokeFlags(cmd, &sf)
 	return cmd
 }
 
 // checkStandbyHolds reports Member DCs whose health Lease is stale, which is the
 // only cross-DC-visible hint that a DC cannot take over. The standby-hold marker
-// itself is spoke-local and deliberately invisible from here.
+// itself is spoke-local; it is read only when the spokes are given (SpokeFlags).
 func checkStandbyHolds(ctx context.Context, coord kubernetes.Interface, ns string, scope *Scope, holder string) []finding {
 	members := scope.MemberDCs
 	if len(members) == 0 {
@@ -242,13 +260,13 @@ func checkStandbyHolds(ctx context.Context, coord kubernetes.Interface, ns strin
 				out = append(out, finding{title: fmt.Sprintf("candidate DC %q is healthy", dc), detail: fmt.Sprintf("its health Lease is stale (%s old)", age), remedy: "that DC's agent is down or cannot reach the coordination plane; it cannot take over until it returns"})
 				continue
 			}
-			out = append(out, finding{ok: true, title: fmt.Sprintf("candidate DC %q is alive (health renewed %s ago)", dc, age), detail: "if it still refuses to promote, check for a standby-hold ConfigMap on ITS spoke: kubectl -n " + ns + " get cm " + scope.LeaseName + StandbyHoldCMSuffix})
+			out = append(out, finding{ok: true, title: fmt.Sprintf("candidate DC %q is alive (health renewed %s ago)", dc, age), detail: "if it still refuses to promote, check for a standby-hold ConfigMap on ITS spoke (or pass --spoke-kubeconfig): kubectl -n " + ns + " get cm " + scope.LeaseName + StandbyHoldCMSuffix})
 		}
 	}
 	return out
 }
 
-func checkFailoverOps(ctx context.Context, f cmdutil.Factory, ns, dbName string) []finding {
+func checkFailoverOps(ctx context.Context, f cmdutil.Factory, db *unstructured.Unstructured) []finding {
 	cfg, err := f.ToRESTConfig()
 	if err != nil {
 		return nil
@@ -257,7 +275,11 @@ func checkFailoverOps(ctx context.Context, f cmdutil.Factory, ns, dbName string)
 	if err != nil {
 		return nil
 	}
-	list, err := dyn.Resource(PgOpsGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	e, err := engineFor(db)
+	if err != nil {
+		return nil
+	}
+	list, err := dyn.Resource(e.OpsGVR).Namespace(db.GetNamespace()).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil
 	}
@@ -266,7 +288,7 @@ func checkFailoverOps(ctx context.Context, f cmdutil.Factory, ns, dbName string)
 		o := &list.Items[i]
 		dbRef, _, _ := unstructured.NestedString(o.Object, "spec", "databaseRef", "name")
 		typ, _, _ := unstructured.NestedString(o.Object, "spec", "type")
-		if dbRef != dbName || typ != "ForceFailOver" {
+		if dbRef != db.GetName() || typ != "ForceFailOver" {
 			continue
 		}
 		phase, _, _ := unstructured.NestedString(o.Object, "status", "phase")
@@ -327,20 +349,16 @@ func checkConditions(db *unstructured.Unstructured) []finding {
 }
 
 func printFindings(w io.Writer, fs []finding) {
-	blocking := 0
 	for _, fd := range fs {
 		fd.print(w)
-		if !fd.ok && fd.blocker {
-			blocking++
-		}
 	}
-	_, _ = fmt.Fprintf(w, "\n%d blocking condition(s) found.\n", blocking)
+	_, _ = fmt.Fprintf(w, "\n%d blocking condition(s) found.\n", countBlockers(fs))
 }
 
 // newCmdDebugSwitchover explains a planned switchover that will not complete.
 func newCmdDebugSwitchover(f cmdutil.Factory) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "switchover DB_NAME",
+		Use:   "switchover [KIND/]DB_NAME",
 		Short: i18n.T("Diagnose a planned switchover that is not completing"),
 		Long: templates.LongDesc(`
 			Reports which of the switchover's gates is holding and why, including the
@@ -363,8 +381,8 @@ func newCmdDebugSwitchover(f cmdutil.Factory) *cobra.Command {
 			ann := db.GetAnnotations()
 			target := ann[AnnSwitchoverTo]
 			if target == "" && ann[AnnQuiesceActive] == "" {
-				_, _ = fmt.Fprintf(out, "No switchover is in flight on %s/%s.\n", ns, args[0])
-				_, _ = fmt.Fprintf(out, "Start one:  kubectl dba dc-dr switchover %s -n %s --to <dc>\n", args[0], ns)
+				_, _ = fmt.Fprintf(out, "No switchover is in flight on %s/%s.\n", ns, db.GetName())
+				_, _ = fmt.Fprintf(out, "Start one:  kubectl dba dc-dr switchover %s -n %s --to <dc> --reason <why>\n", args[0], ns)
 				return nil
 			}
 			var findings []finding
@@ -421,7 +439,7 @@ func newCmdDebugSwitchover(f cmdutil.Factory) *cobra.Command {
 			}
 			printFindings(out, findings)
 			_, _ = fmt.Fprintf(out, "\n  kubectl dba dc-dr status %s -n %s\n", args[0], ns)
-			_, _ = fmt.Fprintf(out, "  kubectl dba dc-dr abort %s -n %s\n", args[0], ns)
+			_, _ = fmt.Fprintf(out, "  kubectl dba dc-dr abort %s -n %s --reason <why>\n", args[0], ns)
 			return nil
 		},
 	}
@@ -431,8 +449,9 @@ func newCmdDebugSwitchover(f cmdutil.Factory) *cobra.Command {
 // newCmdDebugFence explains a database that is up but refusing writes.
 func newCmdDebugFence(f cmdutil.Factory) *cobra.Command {
 	var cf CoordFlags
+	var sf SpokeFlags
 	cmd := &cobra.Command{
-		Use:   "fence DB_NAME",
+		Use:   "fence [KIND/]DB_NAME",
 		Short: i18n.T("Diagnose a database whose primary is fenced read-only"),
 		Long: templates.LongDesc(`
 			A DC-DR database goes read-only by design when its local marker is
@@ -441,8 +460,21 @@ func newCmdDebugFence(f cmdutil.Factory) *cobra.Command {
 			whether the authority itself is healthy, and what to do when the
 			coordination plane is the thing that is broken.
 
-			KUBECONFIG: the hub cluster; the coordination plane via --coord-*.`),
-		Example:           templates.Examples(`kubectl dba dc-dr debug fence pg-dcdr -n demo`),
+			The marker each data center actually reads lives on its own spoke. With
+			--spoke-kubeconfig or --spoke-kubeconfig-secret every spoke's marker
+			content and age, pin and standby-hold are read as well, so the whole
+			diagnosis comes from one command.
+
+			KUBECONFIG: the hub cluster; the coordination plane via --coord-*; the
+			spokes via --spoke-*.`),
+		Example: templates.Examples(`
+			kubectl dba dc-dr debug fence pg-dcdr -n demo
+
+			# Read the markers on both spokes as well
+			kubectl dba dc-dr debug fence pg-dcdr -n demo --spoke-kubeconfig dc-a=a.yaml,dc-b=b.yaml
+
+			# With the spoke kubeconfigs kept as Secrets on the hub
+			kubectl dba dc-dr debug fence pg-dcdr -n demo --spoke-kubeconfig-secret dc-a=dc-failover/dc-a-kubeconfig,dc-b=dc-failover/dc-b-kubeconfig`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
@@ -461,7 +493,7 @@ func newCmdDebugFence(f cmdutil.Factory) *cobra.Command {
 			var findings []finding
 			coord, cerr := cf.CoordClient(ctx, f)
 			if cerr != nil {
-				findings = append(findings, finding{title: "coordination plane reachable", detail: cerr.Error(), remedy: "if the coordination plane is genuinely down, every DC fences read-only after the marker TTL plus its uncertainty hold. To keep the current primary writable through the outage, pin it: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --yes (run against that DC's spoke)", blocker: true})
+				findings = append(findings, finding{title: "coordination plane reachable", detail: cerr.Error(), remedy: "if the coordination plane is genuinely down, every DC fences read-only after the marker TTL plus its uncertainty hold. To keep the current primary writable through the outage, pin it: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --yes --reason <why> (run against that DC's spoke)", blocker: true})
 				printFindings(out, findings)
 				return nil
 			}
@@ -483,7 +515,7 @@ func newCmdDebugFence(f cmdutil.Factory) *cobra.Command {
 			if lease.Spec.RenewTime != nil {
 				age := time.Since(lease.Spec.RenewTime.Time).Round(time.Second)
 				if age > 30*time.Second {
-					findings = append(findings, finding{title: "the authority is being renewed", detail: fmt.Sprintf("last renewed %s ago; the marker projected onto each spoke goes stale after 30s and the fence closes fail-closed", age), remedy: "the holder's agent cannot write to the coordination plane. Fix that, or pin the primary to ride out the outage: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --yes", blocker: true})
+					findings = append(findings, finding{title: "the authority is being renewed", detail: fmt.Sprintf("last renewed %s ago; the marker projected onto each spoke goes stale after 30s and the fence closes fail-closed", age), remedy: "the holder's agent cannot write to the coordination plane. Fix that, or pin the primary to ride out the outage: kubectl dba dc-dr pin-primary --scope " + scope.LeaseName + " --yes --reason <why>", blocker: true})
 				} else {
 					findings = append(findings, finding{ok: true, title: fmt.Sprintf("the authority is fresh (renewed %s ago)", age)})
 				}
@@ -492,13 +524,19 @@ func newCmdDebugFence(f cmdutil.Factory) *cobra.Command {
 			if activeDC != "" && holder != "" && activeDC != holder {
 				findings = append(findings, finding{title: "the database agrees with the authority", detail: fmt.Sprintf("status says %q, the Lease says %q", activeDC, holder), remedy: "the status trails the Lease by a reconcile; if it persists, the hub operator is not reconciling this database"})
 			}
-			_, _ = fmt.Fprintf(out, "Fence diagnosis for %s/%s (scope %s)\n\n", ns, args[0], scope.LeaseName)
+			if sf.Enabled() {
+				findings = append(findings, spokeFindings(readSpokes(ctx, f, &sf, cf.LeaseNS, scope.LeaseName), scope.LeaseName, holder)...)
+			}
+			_, _ = fmt.Fprintf(out, "Fence diagnosis for %s/%s (scope %s)\n\n", ns, db.GetName(), scope.LeaseName)
 			printFindings(out, findings)
-			_, _ = fmt.Fprintf(out, "\nThe marker each data center actually reads lives on its OWN spoke:\n")
-			_, _ = fmt.Fprintf(out, "  kubectl --kubeconfig <spoke> -n %s get cm %s -o jsonpath='{.data}'\n", cf.LeaseNS, scope.LeaseName)
+			if !sf.Enabled() {
+				_, _ = fmt.Fprintf(out, "\nThe marker each data center actually reads lives on its OWN spoke. Pass --spoke-kubeconfig dc=path,... to read them here, or:\n")
+				_, _ = fmt.Fprintf(out, "  kubectl --kubeconfig <spoke> -n %s get cm %s -o jsonpath='{.data}'\n", cf.LeaseNS, scope.LeaseName)
+			}
 			return nil
 		},
 	}
 	AddCoordFlags(cmd, &cf)
+	AddSpokeFlags(cmd, &sf)
 	return cmd
 }
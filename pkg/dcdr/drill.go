@@ -0,0 +1,438 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Results of a drill.
+const (
+	DrillPassed             = "Passed"
+	DrillFailed             = "Failed"
+	DrillRefusedByPreflight = "RefusedByPreflight"
+)
+
+// DrillReport is the audit evidence `dc-dr drill` writes, as Markdown or JSON.
+type DrillReport struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Database   DatabaseRef      `json:"database"`
+	Scope      Scope            `json:"scope"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Result     string           `json:"result"`
+	Actor      string           `json:"actor"`
+	Reason     string           `json:"reason"`
+	Error      string           `json:"error,omitempty"`
+	Legs       []DrillLeg       `json:"legs"`
+	Probe      *ProbeResult     `json:"probe,omitempty"`
+	Preflight  []PreflightCheck `json:"preflight,omitempty"`
+}
+
+// DrillLeg is one switchover of the drill: there and, with --return, back.
+type DrillLeg struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Duration   string           `json:"duration"`
+	Preflight  []PreflightCheck `json:"preflight"`
+	Events     []string         `json:"events"`
+	Aborted    bool             `json:"aborted"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// PreflightCheck is a finding in the report.
+type PreflightCheck struct {
+	Check   string `json:"check"`
+	OK      bool   `json:"ok"`
+	Blocker bool   `json:"blocker"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// NewCmdDrill runs a guided DR rehearsal: preflight, write probe, switchover,
+// verification, and optionally the switch back, recorded as a report.
+func NewCmdDrill(f cmdutil.Factory) *cobra.Command {
+	var cf CoordFlags
+	var sf SpokeFlags
+	var to, reportFile, reportFormat, reason string
+	var back bool
+	var timeout, probeInterval, settle time.Duration
+	cmd := &cobra.Command{
+		Use:   "drill [KIND/]DB_NAME --to DC [--return]",
+		Short: i18n.T("Rehearse a DR switchover end to end and write a verified audit report"),
+		Long: templates.LongDesc(`
+			Orchestrates the quarterly DR rehearsal:
+
+			  1. runs the switchover preflight (dc-dr switchover --dry-run) and stops
+			     before touching anything if a gate blocks;
+			  2. starts a write probe: one sequence-numbered INSERT per
+			     --probe-interval into the primary of whichever data center is
+			     active, recording every write the database acknowledged;
+			  3. triggers the switchover and follows its steps, exactly as dc-dr
+			     wait does;
+			  4. with --return, preflights and switches back the same way;
+			  5. reads the probe rows back from the final primary, reports every
+			     acknowledged write that is missing, and the longest gap between
+			     two acknowledged writes (the observed write outage).
+
+			On any failure after the switchover was triggered, the drill aborts it
+			through the regular abort path (dr.kubedb.com/switchover-abort) and
+			waits for the hub to restore writes before reporting. Ctrl-C or SIGTERM
+			sets the abort too, but does not wait for it; the report is still
+			written, with result Failed.
+
+			The report is timestamped Markdown (or JSON with --report-format json),
+			written to --report-file. The probe writes to the table
+			kubedb_dcdr_drill; the drill's own rows are deleted afterwards. Every
+			switchover and abort the drill issues is recorded in the audit trail
+			with its --reason, which is required and also printed in the report.
+
+			If the database pods run on the spokes, give them with
+			--spoke-kubeconfig so the probe can follow the primary; otherwise it
+			writes through the current cluster.
+
+			KUBECONFIG: the hub cluster; the coordination plane via --coord-*; the
+			spokes via --spoke-*.`),
+		Example: templates.Examples(`
+			# Rehearse moving to dc-b and back, with an audit report
+			kubectl dba dc-dr drill pg-dcdr -n demo --to dc-b --return --reason "Q3 DR rehearsal" \
+			  --spoke-kubeconfig dc-a=a.yaml,dc-b=b.yaml --report-file drill-q3.md`),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("exactly one database name is required")
+			}
+			if to == "" {
+				return fmt.Errorf("--to is required (the data center to drill a switchover to)")
+			}
+			if reportFormat != "markdown" && reportFormat != "json" {
+				return fmt.Errorf("--report-format must be one of: markdown|json")
+			}
+			if err := requireReason(reason); err != nil {
+				return err
+			}
+			// Ctrl-C or SIGTERM fails the drill like any other error: the leg in
+			// flight is aborted, the probe cleaned up and the report written.
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			db, dyn, _, err := getDB(ctx, f, args[0])
+			if err != nil {
+				return err
+			}
+			if err := requireAnnotationContract(db, "drill"); err != nil {
+				return err
+			}
+			scope, err := ResolveScopeForDB(ctx, dyn, db)
+			if err != nil {
+				return err
+			}
+			from, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+			if from == to {
+				return fmt.Errorf("%s is already the active data center; drill to another Member DC", to)
+			}
+
+			start := time.Now()
+			if reportFile == "" {
+				ext := "md"
+				if reportFormat == "json" {
+					ext = "json"
+				}
+				reportFile = fmt.Sprintf("dcdr-drill-%s-%s.%s", db.GetName(), start.UTC().Format("20060102T150405Z"), ext)
+			}
+			d := &drill{
+				f: f, cf: &cf, sf: &sf, dyn: dyn, out: cmd.OutOrStdout(), timeout: timeout,
+				warn: printfErr(cmd), scope: scope.LeaseName,
+				report: &DrillReport{
+					APIVersion: StatusSchemaVersion,
+					Kind:       "DCDRDrill",
+					Database:   DatabaseRef{Kind: db.GetKind(), Namespace: db.GetNamespace(), Name: db.GetName()},
+					Scope:      *scope,
+					StartedAt:  start.UTC(),
+					Actor:      auditActor(ctx, f),
+					Reason:     reason,
+					Legs:       []DrillLeg{},
+				},
+			}
+			d.report.Database.Phase, _, _ = unstructured.NestedString(db.Object, "status", "phase")
+
+			probe, err := newWriteProbe(f, &sf, dyn, db, fmt.Sprintf("%s-%d", db.GetName(), start.Unix()), probeInterval)
+			if err != nil {
+				return err
+			}
+			runErr := d.run(ctx, db, scope, from, to, back, probe, settle)
+			d.report.FinishedAt = time.Now().UTC()
+			if werr := writeDrillReport(reportFile, reportFormat, d.report); werr != nil {
+				return fmt.Errorf("failed to write the drill report: %w", werr)
+			}
+			_, _ = fmt.Fprintf(d.out, "\nDrill %s. Report written to %s\n", d.report.Result, reportFile)
+			return runErr
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "", "Data center to switch over to")
+	cmd.Flags().BoolVar(&back, "return", false, "Switch back to the original active data center after verifying")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "Give up on a switchover leg after this long, and abort it")
+	cmd.Flags().DurationVar(&probeInterval, "probe-interval", time.Second, "Interval between two probe writes")
+	cmd.Flags().DurationVar(&settle, "settle", 10*time.Second, "Keep probing this long after the last switchover before verifying")
+	cmd.Flags().StringVar(&reportFile, "report-file", "", "Where to write the report (default dcdr-drill-<name>-<timestamp>.md|json)")
+	cmd.Flags().StringVar(&reportFormat, "report-format", "markdown", "Report format. One of: markdown|json")
+	AddReasonFlag(cmd, &reason)
+	AddCoordFlags(cmd, &cf)
+	AddSpokeFlags(cmd, &sf)
+	return cmd
+}
+
+type drill struct {
+	f       cmdutil.Factory
+	cf      *CoordFlags
+	sf      *SpokeFlags
+	dyn     dynamic.Interface
+	out     io.Writer
+	timeout time.Duration
+	report  *DrillReport
+	warn    func(string, ...any)
+	scope   string
+}
+
+// audit records one switchover or abort the drill issued.
+func (d *drill) audit(ctx context.Context, db *unstructured.Unstructured, command, previous, next string) {
+	recordAudit(ctx, d.f, d.cf.LeaseNS, d.warn, AuditRecord{
+		Actor: d.report.Actor, Command: command, Database: dbAuditRef(db), Scope: d.scope,
+		Previous: previous, New: next, Reason: d.report.Reason,
+	})
+}
+
+func (d *drill) logf(format string, a ...any) {
+	_, _ = fmt.Fprintf(d.out, "%s  %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, a...))
+}
+
+// fail records err as the drill's outcome and returns it.
+func (d *drill) fail(err error) error {
+	d.report.Result = DrillFailed
+	d.report.Error = err.Error()
+	return err
+}
+
+func (d *drill) run(ctx context.Context, db *unstructured.Unstructured, scope *Scope, from, to string, back bool, probe *writeProbe, settle time.Duration) error {
+	// Preflight before anything is written, the probe table included.
+	findings := switchoverPreflight(ctx, d.f, d.cf, d.sf, d.dyn, db, scope, to)
+	d.report.Preflight = preflightChecks(findings)
+	if n := countBlockers(findings); n > 0 {
+		printFindings(d.out, findings)
+		d.report.Result = DrillRefusedByPreflight
+		d.report.Error = fmt.Sprintf("%d blocking condition(s); nothing was changed", n)
+		return fmt.Errorf("preflight refused the drill: %s", d.report.Error)
+	}
+	d.logf("preflight passed (%d checks)", len(findings))
+
+	if err := probe.Start(ctx); err != nil {
+		return d.fail(err)
+	}
+	d.logf("write probe started (run %s, every %s)", probe.run, probe.interval)
+	defer func() {
+		probe.Stop()
+		if err := probe.Cleanup(context.WithoutCancel(ctx)); err != nil {
+			d.logf("WARNING: could not delete the probe rows of run %s: %v", probe.run, err)
+		}
+	}()
+
+	if err := d.leg(ctx, db, from, to, nil); err != nil {
+		return d.fail(err)
+	}
+	if back {
+		e, err := engineFor(db)
+		if err != nil {
+			return d.fail(err)
+		}
+		cur, err := d.dyn.Resource(e.GVR).Namespace(db.GetNamespace()).Get(ctx, db.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return d.fail(err)
+		}
+		findings := switchoverPreflight(ctx, d.f, d.cf, d.sf, d.dyn, cur, scope, from)
+		if err := d.leg(ctx, cur, to, from, findings); err != nil {
+			return d.fail(err)
+		}
+	}
+
+	d.logf("probing %s more before verifying", settle)
+	select {
+	case <-ctx.Done():
+		return d.fail(fmt.Errorf("interrupted while settling: %w", ctx.Err()))
+	case <-time.After(settle):
+	}
+	probe.Stop()
+	res := probe.Verify(ctx)
+	d.report.Probe = &res
+	switch {
+	case !res.Verified:
+		return d.fail(fmt.Errorf("could not read the probe rows back: %s", res.VerifyError))
+	case len(res.Lost) > 0:
+		return d.fail(fmt.Errorf("%d acknowledged write(s) lost: %v", len(res.Lost), res.Lost))
+	}
+	d.logf("verified: all %d acknowledged writes present; longest write gap %s", res.Acknowledged, res.MaxWriteGap)
+	d.report.Result = DrillPassed
+	return nil
+}
+
+// leg switches db over from one DC to another and follows it to completion. A
+// failure after the switchover was requested, an interrupt included, aborts it
+// through the abort path. preflight is nil for the first leg, whose preflight
+// already ran.
+func (d *drill) leg(ctx context.Context, db *unstructured.Unstructured, from, to string, preflight []finding) error {
+	var events bytes.Buffer
+	leg := DrillLeg{From: from, To: to, StartedAt: time.Now().UTC(), Preflight: preflightChecks(preflight)}
+	defer func() {
+		leg.FinishedAt = time.Now().UTC()
+		leg.Duration = leg.FinishedAt.Sub(leg.StartedAt).Round(time.Second).String()
+		leg.Events = strings.Split(strings.TrimSpace(events.String()), "\n")
+		d.report.Legs = append(d.report.Legs, leg)
+	}()
+	out := io.MultiWriter(d.out, &events)
+
+	if n := countBlockers(preflight); n > 0 {
+		printFindings(out, preflight)
+		leg.Error = fmt.Sprintf("preflight of the switch back refused it: %d blocking condition(s)", n)
+		return fmt.Errorf("%s", leg.Error)
+	}
+	_, _ = fmt.Fprintf(out, "%s  switchover %s -> %s requested\n", time.Now().Format("15:04:05"), from, to)
+	if err := annotateDB(ctx, d.dyn, db, AnnSwitchoverTo, &to); err != nil {
+		leg.Error = err.Error()
+		return err
+	}
+	d.audit(ctx, db, "drill switchover", from, to)
+	err := pollUntil(ctx, d.dyn, db, &waiter{out: out, forState: WaitSwitchoverComplete, target: to}, d.timeout, 2*time.Second)
+	if err == nil {
+		return nil
+	}
+	leg.Error = err.Error()
+	leg.Aborted = true
+	v := "true"
+	_, _ = fmt.Fprintf(out, "%s  FAILED: %v; aborting via %s\n", time.Now().Format("15:04:05"), err, AnnSwitchoverAbort)
+	// The abort must go through even when ctx was cancelled by an interrupt.
+	actx := context.WithoutCancel(ctx)
+	if aerr := annotateDB(actx, d.dyn, db, AnnSwitchoverAbort, &v); aerr != nil {
+		_, _ = fmt.Fprintf(out, "%s  could not set the abort annotation: %v; run kubectl dba dc-dr abort by hand\n", time.Now().Format("15:04:05"), aerr)
+		return err
+	}
+	d.audit(actx, db, "drill abort", to, "aborted")
+	if ctx.Err() != nil {
+		_, _ = fmt.Fprintf(out, "%s  abort requested; follow it with kubectl dba dc-dr wait %s -n %s --for steady\n", time.Now().Format("15:04:05"), db.GetName(), db.GetNamespace())
+		return err
+	}
+	if serr := pollUntil(ctx, d.dyn, db, &waiter{out: out, forState: WaitSteady}, 5*time.Minute, 2*time.Second); serr != nil {
+		_, _ = fmt.Fprintf(out, "%s  abort did not settle: %v\n", time.Now().Format("15:04:05"), serr)
+	}
+	return err
+}
+
+func preflightChecks(fs []finding) []PreflightCheck {
+	checks := make([]PreflightCheck, 0, len(fs))
+	for _, fd := range fs {
+		detail := fd.detail
+		if !fd.ok && fd.remedy != "" {
+			detail = strings.TrimSpace(detail + " -> " + fd.remedy)
+		}
+		checks = append(checks, PreflightCheck{Check: fd.title, OK: fd.ok, Blocker: !fd.ok && fd.blocker, Detail: detail})
+	}
+	return checks
+}
+
+func writeDrillReport(file, format string, r *DrillReport) error {
+	var b []byte
+	if format == "json" {
+		var err error
+		if b, err = json.MarshalIndent(r, "", "  "); err != nil {
+			return err
+		}
+		b = append(b, '\n')
+	} else {
+		b = renderDrillMarkdown(r)
+	}
+	return os.WriteFile(file, b, 0o644)
+}
+
+func renderDrillMarkdown(r *DrillReport) []byte {
+	var b bytes.Buffer
+	p := func(format string, a ...any) { _, _ = fmt.Fprintf(&b, format, a...) }
+	p("# DC-DR drill: %s %s/%s\n\n", r.Database.Kind, r.Database.Namespace, r.Database.Name)
+	p("| | |\n|---|---|\n")
+	p("| Result | **%s** |\n", r.Result)
+	if r.Error != "" {
+		p("| Error | %s |\n", r.Error)
+	}
+	p("| Started | %s |\n| Finished | %s |\n", r.StartedAt.Format(time.RFC3339), r.FinishedAt.Format(time.RFC3339))
+	p("| Failover scope | %s (%s) |\n", r.Scope.LeaseName, r.Scope.Source)
+	p("| Run by | %s |\n| Reason | %s |\n", r.Actor, r.Reason)
+
+	writeChecks := func(checks []PreflightCheck) {
+		p("| check | result | detail |\n|---|---|---|\n")
+		for _, c := range checks {
+			res := "OK"
+			if !c.OK {
+				res = "WARN"
+				if c.Blocker {
+					res = "BLOCKER"
+				}
+			}
+			p("| %s | %s | %s |\n", c.Check, res, c.Detail)
+		}
+	}
+	p("\n## Preflight\n\n")
+	writeChecks(r.Preflight)
+
+	for i, leg := range r.Legs {
+		p("\n## Switchover %d: %s -> %s\n\n", i+1, leg.From, leg.To)
+		p("Started %s, finished %s, took %s.", leg.StartedAt.Format(time.RFC3339), leg.FinishedAt.Format(time.RFC3339), leg.Duration)
+		if leg.Aborted {
+			p(" **Aborted**: %s", leg.Error)
+		} else if leg.Error != "" {
+			p(" **Failed**: %s", leg.Error)
+		}
+		p("\n\n")
+		if len(leg.Preflight) > 0 {
+			writeChecks(leg.Preflight)
+			p("\n")
+		}
+		p("```\n%s\n```\n", strings.Join(leg.Events, "\n"))
+	}
+
+	if pr := r.Probe; pr != nil {
+		p("\n## Write probe\n\n| | |\n|---|---|\n")
+		p("| Interval | %s |\n| Attempted | %d |\n| Acknowledged | %d |\n| Failed (outage) | %d |\n", pr.Interval, pr.Attempted, pr.Acknowledged, pr.Failed)
+		p("| Acknowledged writes lost | %d %v |\n", len(pr.Lost), pr.Lost)
+		p("| Longest write gap | %s (%s to %s) |\n", pr.MaxWriteGap, orNone(pr.GapFrom), orNone(pr.GapTo))
+	}
+	return b.Bytes()
+}
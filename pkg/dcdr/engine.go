@@ -0,0 +1,170 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// Engine is how the dc-dr commands address one KubeDB database kind: the CR
+// itself, and the OpsRequests the hub mints for it (ForceFailOver and friends).
+type Engine struct {
+	Kind    string
+	GVR     schema.GroupVersionResource
+	OpsGVR  schema.GroupVersionResource
+	aliases []string
+	// AnnotationContract is true when the engine's operator honors the
+	// switchover, abort and accept-data-loss annotations. The read-only commands
+	// and the Lease-level ones (status, debug, active-dc, handoff, pins) only
+	// need status.disasterRecovery and work for every engine.
+	AnnotationContract bool
+}
+
+// Engines are the kinds the dc-dr commands accept, as KIND/NAME. A bare NAME is
+// a Postgres, which was the only engine before the others published
+// status.disasterRecovery. MSSQLServer is not served as kubedb.com/v1 yet.
+var Engines = []Engine{
+	newEngine("Postgres", "v1", "postgreses", true, "pg"),
+	newEngine("MySQL", "v1", "mysqls", false, "my"),
+	newEngine("MariaDB", "v1", "mariadbs", false, "md"),
+	newEngine("MongoDB", "v1", "mongodbs", false, "mg"),
+	newEngine("Redis", "v1", "redises", false, "rd"),
+	newEngine("MSSQLServer", "v1alpha2", "mssqlservers", false, "ms", "mssql"),
+	newEngine("PerconaXtraDB", "v1", "perconaxtradbs", false, "px"),
+}
+
+func newEngine(kind, version, resource string, contract bool, aliases ...string) Engine {
+	return Engine{
+		Kind:               kind,
+		GVR:                schema.GroupVersionResource{Group: "kubedb.com", Version: version, Resource: resource},
+		OpsGVR:             schema.GroupVersionResource{Group: "ops.kubedb.com", Version: "v1alpha1", Resource: strings.ToLower(kind) + "opsrequests"},
+		aliases:            append([]string{strings.ToLower(kind), resource}, aliases...),
+		AnnotationContract: contract,
+	}
+}
+
+// parseDBArg splits a [KIND/]NAME argument. KIND matches the kind, its plural
+// or its short name, case-insensitively.
+func parseDBArg(arg string) (*Engine, string, error) {
+	kind, name, found := strings.Cut(arg, "/")
+	if !found {
+		return &Engines[0], arg, nil
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("%q has no database name after the kind", arg)
+	}
+	for i := range Engines {
+		for _, a := range Engines[i].aliases {
+			if strings.EqualFold(kind, a) {
+				return &Engines[i], name, nil
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("unsupported database kind %q; dc-dr supports %s", kind, strings.Join(engineKinds(false), ", "))
+}
+
+// engineFor returns the engine of a CR fetched by getDB.
+func engineFor(db *unstructured.Unstructured) (*Engine, error) {
+	for i := range Engines {
+		if Engines[i].Kind == db.GetKind() {
+			return &Engines[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported database kind %q; dc-dr supports %s", db.GetKind(), strings.Join(engineKinds(false), ", "))
+}
+
+func engineKinds(contractOnly bool) []string {
+	var kinds []string
+	for _, e := range Engines {
+		if !contractOnly || e.AnnotationContract {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	return kinds
+}
+
+// requireAnnotationContract refuses to write a switchover, abort or
+// accept-data-loss annotation on an engine whose operator would ignore it:
+// the command would report success and nothing would ever happen.
+func requireAnnotationContract(db *unstructured.Unstructured, what string) error {
+	e, err := engineFor(db)
+	if err != nil {
+		return err
+	}
+	if !e.AnnotationContract {
+		return fmt.Errorf("%s is not supported for %s: its operator does not implement the dr.kubedb.com annotation contract yet (supported: %s); use dc-dr handoff to move the failover authority instead",
+			what, e.Kind, strings.Join(engineKinds(true), ", "))
+	}
+	return nil
+}
+
+// warnIfNoDRStatus prints a note when the CR does not publish
+// status.disasterRecovery, which every dc-dr verdict is derived from.
+func warnIfNoDRStatus(out func(string, ...any), db *unstructured.Unstructured) {
+	if _, found, _ := unstructured.NestedMap(db.Object, "status", "disasterRecovery"); !found {
+		out("NOTE: %s %s/%s does not publish status.disasterRecovery; either it is not DC-DR distributed or its operator predates DC-DR, so the per-DC view below is empty\n", db.GetKind(), db.GetNamespace(), db.GetName())
+	}
+}
+
+// getDB fetches the database CR named by a [KIND/]NAME argument unstructured,
+// resolving the namespace from the factory's kubeconfig flags.
+func getDB(ctx context.Context, f cmdutil.Factory, arg string) (*unstructured.Unstructured, dynamic.Interface, string, error) {
+	engine, name, err := parseDBArg(arg)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	ns, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	db, err := dyn.Resource(engine.GVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to get %s %s/%s: %w", engine.Kind, ns, name, err)
+	}
+	return db, dyn, ns, nil
+}
+
+// annotateDB merge-patches one annotation onto the database CR (nil value removes).
+func annotateDB(ctx context.Context, dyn dynamic.Interface, db *unstructured.Unstructured, key string, value *string) error {
+	var v any
+	if value != nil {
+		v = *value
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, key, jsonValue(v))
+	e, err := engineFor(db)
+	if err != nil {
+		return err
+	}
+	_, err = dyn.Resource(e.GVR).Namespace(db.GetNamespace()).Patch(ctx, db.GetName(), mergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
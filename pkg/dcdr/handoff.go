@@ -31,10 +31,10 @@ import (
 // on the COORDINATION plane (and on the hub only to resolve a database's scope).
 func NewCmdHandoff(f cmdutil.Factory) *cobra.Command {
 	var cf CoordFlags
-	var leaseName, to string
+	var leaseName, to, reason string
 	var yes bool
 	cmd := &cobra.Command{
-		Use:   "handoff (DB_NAME | --lease NAME) --to DC",
+		Use:   "handoff ([KIND/]DB_NAME | --lease NAME) --to DC",
 		Short: i18n.T("Move the failover authority for a scope by handing off its primary-DC Lease"),
 		Long: templates.LongDesc(`
 			Writes dr.open-cluster-management.io/handoff-to on the scope's primary-DC
@@ -49,17 +49,18 @@ func NewCmdHandoff(f cmdutil.Factory) *cobra.Command {
 
 			It moves EVERY database sharing the scope. Do NOT stop a DC's agent to
 			force a failover instead: one agent serves every scope its DC holds, so
-			that expires all of them together.
+			that expires all of them together. --reason is required and recorded in
+			the audit trail on the hub.
 
 			KUBECONFIG: the hub cluster (to resolve a database's scope and read the
 			coordination kubeconfig Secret). The Lease is written on the coordination
 			plane via the --coord-* flags.`),
 		Example: templates.Examples(`
 			# Fail a database's scope over to dc-b
-			kubectl dba dc-dr handoff pg-dcdr -n demo --to dc-b --yes
+			kubectl dba dc-dr handoff pg-dcdr -n demo --to dc-b --yes --reason "dc-a database down, DC alive"
 
 			# Move a scope by Lease name (works with no database left)
-			kubectl dba dc-dr handoff --lease primary-dc-orders --to dc-a --yes`),
+			kubectl dba dc-dr handoff --lease primary-dc-orders --to dc-a --yes --reason "return after dc-b maintenance"`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if (len(args) == 0) == (leaseName == "") {
@@ -68,14 +69,19 @@ func NewCmdHandoff(f cmdutil.Factory) *cobra.Command {
 			if to == "" {
 				return fmt.Errorf("--to is required (the target data center)")
 			}
+			if err := requireReason(reason); err != nil {
+				return err
+			}
 			ctx := context.Background()
 			out := cmd.OutOrStdout()
 			scope := &Scope{LeaseName: leaseName, Source: "--lease flag"}
+			var ref *DatabaseRef
 			if len(args) == 1 {
 				db, dyn, _, err := getDB(ctx, f, args[0])
 				if err != nil {
 					return err
 				}
+				ref = dbAuditRef(db)
 				scope, err = ResolveScopeForDB(ctx, dyn, db)
 				if err != nil {
 					return err
@@ -128,6 +134,10 @@ func NewCmdHandoff(f cmdutil.Factory) *cobra.Command {
 			if _, err := coord.CoordinationV1().Leases(cf.LeaseNS).Patch(ctx, scope.LeaseName, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
 				return fmt.Errorf("failed to annotate Lease %s/%s: %w", cf.LeaseNS, scope.LeaseName, err)
 			}
+			recordAudit(ctx, f, cf.LeaseNS, printfErr(cmd), AuditRecord{
+				Command: "handoff", Database: ref, Scope: scope.LeaseName,
+				Previous: holder, New: to, Reason: reason,
+			})
 			_, _ = fmt.Fprintf(out, "Handoff of %s requested: %s -> %s.\n", scope.LeaseName, orNone(holder), to)
 			_, _ = fmt.Fprintf(out, "The holder releases within seconds and %s acquires on its next retry tick; the annotation clears itself.\n", to)
 			_, _ = fmt.Fprintf(out, "Verify:  kubectl dba dc-dr active-dc --lease %s\n", scope.LeaseName)
@@ -138,6 +148,7 @@ func NewCmdHandoff(f cmdutil.Factory) *cobra.Command {
 	cmd.Flags().StringVar(&leaseName, "lease", "", "Act on this Lease directly instead of resolving a database's scope")
 	cmd.Flags().StringVar(&to, "to", "", "Target data center")
 	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm the handoff (it moves every database in the scope)")
+	AddReasonFlag(cmd, &reason)
 	AddCoordFlags(cmd, &cf)
 	return cmd
 }
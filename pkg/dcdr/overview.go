@@ -0,0 +1,283 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// overviewRow is one distributed database in `dc-dr overview`.
+type overviewRow struct {
+	db        *unstructured.Unstructured
+	scope     *Scope
+	activeDC  string
+	drPhase   string
+	protected string
+	worstLag  string
+	// err is why the scope could not be resolved; the scope is then "?".
+	err string
+}
+
+// NewCmdOverview lists every distributed database on the hub, grouped by the
+// failover scope it moves with, to show the blast radius of a DC outage at once.
+func NewCmdOverview(f cmdutil.Factory) *cobra.Command {
+	var cf CoordFlags
+	var allNamespaces bool
+	cmd := &cobra.Command{
+		Use:   "overview [-A]",
+		Short: i18n.T("List every distributed database and failover scope with its active DC and health"),
+		Long: templates.LongDesc(`
+			Lists every DC-DR distributed database of every supported kind, with its
+			resolved failover scope, active data center, DR phase, protection and
+			worst cross-DC lag. Then groups them by scope and reads each scope's
+			primary-DC Lease from the coordination plane: holder, renew age, and
+			whether it is STALE (expired), PINNED (break-glass override) or in a
+			HANDOFF. Moving a scope moves every database listed under it.
+
+			If the coordination plane cannot be reached the database table is still
+			printed, and the scope table says why the Lease columns are missing. An
+			engine that cannot be listed, or a database whose scope cannot be
+			resolved, is reported below the tables; the rest is still listed.
+
+			KUBECONFIG: the hub cluster; the coordination plane via --coord-*.`),
+		Example: templates.Examples(`
+			# Every distributed database in the current namespace
+			kubectl dba dc-dr overview
+
+			# The whole hub
+			kubectl dba dc-dr overview -A`),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			out := cmd.OutOrStdout()
+			ns := metav1.NamespaceAll
+			if !allNamespaces {
+				var err error
+				if ns, _, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+					return err
+				}
+			}
+			cfg, err := f.ToRESTConfig()
+			if err != nil {
+				return err
+			}
+			dyn, err := dynamic.NewForConfig(cfg)
+			if err != nil {
+				return err
+			}
+			rows, unlisted := listDistributed(ctx, dyn, ns)
+			if len(rows) == 0 {
+				_, _ = fmt.Fprintln(out, "No DC-DR distributed databases found.")
+			} else {
+				printOverviewDatabases(out, rows, allNamespaces)
+				_, _ = fmt.Fprintln(out)
+				printOverviewScopes(ctx, out, f, &cf, rows)
+			}
+			printOverviewErrors(out, rows, unlisted)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "List distributed databases across all namespaces")
+	AddCoordFlags(cmd, &cf)
+	return cmd
+}
+
+// listDistributed lists the spec.distributed databases of every engine in ns.
+// The engines it could not list, including those the hub does not serve, are
+// returned in unlisted with the reason; a database whose scope does not
+// resolve is listed with the error.
+func listDistributed(ctx context.Context, dyn dynamic.Interface, ns string) (rows []overviewRow, unlisted []string) {
+	for _, e := range Engines {
+		list, err := dyn.Resource(e.GVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			reason := err.Error()
+			if kerr.IsNotFound(err) {
+				reason = "not served by the hub; is its CRD installed?"
+			}
+			unlisted = append(unlisted, fmt.Sprintf("%s (%s %s): %s", e.Kind, e.GVR.GroupVersion(), e.GVR.Resource, reason))
+			continue
+		}
+		for i := range list.Items {
+			db := &list.Items[i]
+			if distributed, _, _ := unstructured.NestedBool(db.Object, "spec", "distributed"); !distributed {
+				continue
+			}
+			scope, err := ResolveScopeForDB(ctx, dyn, db)
+			if err != nil {
+				r := newOverviewRow(db, &Scope{LeaseName: "?"})
+				r.err = err.Error()
+				rows = append(rows, r)
+				continue
+			}
+			rows = append(rows, newOverviewRow(db, scope))
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].scope.LeaseName != rows[j].scope.LeaseName {
+			return rows[i].scope.LeaseName < rows[j].scope.LeaseName
+		}
+		if rows[i].db.GetNamespace() != rows[j].db.GetNamespace() {
+			return rows[i].db.GetNamespace() < rows[j].db.GetNamespace()
+		}
+		return rows[i].db.GetName() < rows[j].db.GetName()
+	})
+	return rows, unlisted
+}
+
+func newOverviewRow(db *unstructured.Unstructured, scope *Scope) overviewRow {
+	r := overviewRow{db: db, scope: scope, protected: "-", worstLag: "-"}
+	r.activeDC, _, _ = unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+	r.drPhase, _, _ = unstructured.NestedString(db.Object, "status", "disasterRecovery", "phase")
+	if protected, found, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected"); found {
+		r.protected = fmt.Sprintf("%v", protected)
+	}
+	dcs, _, _ := unstructured.NestedSlice(db.Object, "status", "disasterRecovery", "dataCenters")
+	worst := int64(-1)
+	for _, d := range dcs {
+		dm, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		if lag, ok := toInt64(dm["lagBytes"]); ok && lag > worst {
+			worst = lag
+		}
+	}
+	if worst >= 0 {
+		r.worstLag = fmt.Sprintf("%d", worst)
+	}
+	return r
+}
+
+func printOverviewDatabases(out io.Writer, rows []overviewRow, allNamespaces bool) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if allNamespaces {
+		_, _ = fmt.Fprint(w, "NAMESPACE\t")
+	}
+	_, _ = fmt.Fprintln(w, "KIND\tNAME\tSCOPE\tACTIVE DC\tDR PHASE\tPROTECTED\tWORST LAG(BYTES)")
+	for _, r := range rows {
+		if allNamespaces {
+			_, _ = fmt.Fprintf(w, "%s\t", r.db.GetNamespace())
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.db.GetKind(), r.db.GetName(), r.scope.LeaseName, orNone(r.activeDC), orNone(r.drPhase), r.protected, r.worstLag)
+	}
+	_ = w.Flush()
+}
+
+// printOverviewScopes groups rows by scope and reports each scope's Lease.
+func printOverviewScopes(ctx context.Context, out io.Writer, f cmdutil.Factory, cf *CoordFlags, rows []overviewRow) {
+	var scopes []string
+	members := map[string][]string{}
+	for _, r := range rows {
+		if r.err != "" {
+			continue
+		}
+		name := r.scope.LeaseName
+		if _, ok := members[name]; !ok {
+			scopes = append(scopes, name)
+		}
+		members[name] = append(members[name], r.db.GetNamespace()+"/"+r.db.GetName())
+	}
+
+	coord, cerr := cf.CoordClient(ctx, f)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SCOPE\tHOLDER\tRENEWED\tFLAGS\tDATABASES")
+	for _, name := range scopes {
+		holder, renewed, flags := "?", "?", "-"
+		if cerr == nil {
+			holder, renewed, flags = leaseSummary(ctx, coord.CoordinationV1().Leases(cf.LeaseNS), name)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, holder, renewed, flags, strings.Join(members[name], ","))
+	}
+	_ = w.Flush()
+	if cerr != nil {
+		_, _ = fmt.Fprintf(out, "\nLease columns unavailable: %v\n", cerr)
+	}
+}
+
+// printOverviewErrors reports what overview could not list or resolve.
+func printOverviewErrors(out io.Writer, rows []overviewRow, unlisted []string) {
+	var lines []string
+	for _, u := range unlisted {
+		lines = append(lines, "  not listed: "+u)
+	}
+	for _, r := range rows {
+		if r.err != "" {
+			lines = append(lines, fmt.Sprintf("  scope of %s %s/%s not resolved: %s", r.db.GetKind(), r.db.GetNamespace(), r.db.GetName(), r.err))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(out, "\nIncomplete:\n%s\n", strings.Join(lines, "\n"))
+}
+
+// leaseSummary renders the holder, renew age and flags of one primary-DC Lease.
+func leaseSummary(ctx context.Context, leases coordinationv1.LeaseInterface, name string) (string, string, string) {
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kerr.IsNotFound(err) {
+			return "<none>", "-", "NO-LEASE"
+		}
+		return "?", "?", "ERROR: " + err.Error()
+	}
+	holder := "<none>"
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		holder = *lease.Spec.HolderIdentity
+	}
+	renewed := "never"
+	var flags []string
+	// Like the switchover preflight, a Lease never renewed counts as stale.
+	if lease.Spec.RenewTime == nil {
+		flags = append(flags, "STALE")
+	} else {
+		age := time.Since(lease.Spec.RenewTime.Time).Round(time.Second)
+		renewed = age.String() + " ago"
+		dur := int32(45)
+		if lease.Spec.LeaseDurationSeconds != nil {
+			dur = *lease.Spec.LeaseDurationSeconds
+		}
+		if age > time.Duration(dur)*time.Second {
+			flags = append(flags, "STALE")
+		}
+	}
+	if pin := lease.Annotations["dr.open-cluster-management.io/override-hold"]; pin != "" {
+		flags = append(flags, "PINNED("+pin+")")
+	}
+	if ho := lease.Annotations[AnnLeaseHandoffTo]; ho != "" {
+		flags = append(flags, "HANDOFF(->"+ho+")")
+	}
+	if len(flags) == 0 {
+		return holder, renewed, "-"
+	}
+	return holder, renewed, strings.Join(flags, ",")
+}
@@ -38,9 +38,11 @@ const (
 //
 // Both act on a SPOKE cluster: the marker ConfigMap is human-owned and lives on the
 // data center it governs, which is exactly why it still works when the hub is
-// unreachable. So the ordinary kubeconfig flags must point at that DC's spoke.
+// unreachable. So the ordinary kubeconfig flags must point at that DC's spoke,
+// and the audit record lands in that spoke's trail too. The Lease clear of
+// pin-primary --remove --force is recorded on the coordination plane instead.
 func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
-	var scopeName, dbName string
+	var scopeName, dbName, reason string
 	var remove, force, yes bool
 	var cf CoordFlags
 
@@ -61,6 +63,9 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 			if (scopeName == "") == (dbName == "") {
 				return fmt.Errorf("give exactly one of --scope (a primary-DC Lease name) or --db (resolve the scope from a database)")
 			}
+			if err := requireReason(reason); err != nil {
+				return err
+			}
 			ctx := context.Background()
 			out := cmd.OutOrStdout()
 			lease := scopeName
@@ -100,9 +105,15 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 				// what actually blocks the failover is the override-hold annotation on
 				// the Lease, which only that DC's own (dead) agent would ever clear.
 				_, _ = fmt.Fprintf(out, "Could not remove ConfigMap %s/%s on the current cluster (%v); proceeding to clear the Lease annotation because --force is set.\n", cf.LeaseNS, cmName, err)
-				action = "unreachable, skipped"
+			} else {
+				_, _ = fmt.Fprintf(out, "ConfigMap %s/%s %s on the current cluster.\n", cf.LeaseNS, cmName, action)
+				rec := AuditRecord{Command: cmd.Name(), Scope: lease, Previous: "absent", New: cmName, Reason: reason}
+				if remove {
+					rec.Command += " --remove"
+					rec.Previous, rec.New = cmName, "absent"
+				}
+				recordAudit(ctx, f, cf.LeaseNS, printfErr(cmd), rec)
 			}
-			_, _ = fmt.Fprintf(out, "ConfigMap %s/%s %s on the current cluster.\n", cf.LeaseNS, cmName, action)
 			if kind == pinPrimary && remove && force {
 				coord, cerr := cf.CoordClient(ctx, f)
 				if cerr != nil {
@@ -112,6 +123,10 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 				if _, err := coord.CoordinationV1().Leases(cf.LeaseNS).Patch(ctx, lease, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
 					return fmt.Errorf("failed to clear override-hold on Lease %s/%s: %w", cf.LeaseNS, lease, err)
 				}
+				recordAuditTo(ctx, f, coord, cf.LeaseNS, printfErr(cmd), AuditRecord{
+					Command: cmd.Name() + " --remove --force", Scope: lease,
+					Previous: "override-hold on Lease " + lease, New: "cleared", Reason: reason,
+				})
 				_, _ = fmt.Fprintf(out, "Cleared the override-hold annotation on Lease %s/%s directly (the pinned DC's agent is not alive to do it).\n", cf.LeaseNS, lease)
 				_, _ = fmt.Fprintf(out, "Members now contend normally; the surviving DC can acquire once its holds are removed.\n")
 			}
@@ -124,7 +139,7 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 					_, _ = fmt.Fprintf(out, "  - its coordinator keeps the local leader writable even if the marker goes stale (a control-plane outage no longer fences it).\n")
 					_, _ = fmt.Fprintf(out, "  IMPORTANT: only honored on the scope's LAST KNOWN HOLDER. On any other DC the agent refuses it and logs why; it never promotes a standby.\n")
 					_, _ = fmt.Fprintf(out, "  IMPORTANT: while pinned, that DC dying means NO failover happens. Remove the pin as soon as the emergency ends:\n")
-					_, _ = fmt.Fprintf(out, "    kubectl dba dc-dr pin-primary --scope %s --remove --yes\n", lease)
+					_, _ = fmt.Fprintf(out, "    kubectl dba dc-dr pin-primary --scope %s --remove --yes --reason <why>\n", lease)
 				}
 			} else {
 				if remove {
@@ -132,14 +147,14 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 				} else {
 					_, _ = fmt.Fprintf(out, "This data center is now HELD as a standby for scope %s: it never contends for the Lease, never promotes, and refuses destructive cross-DC rewinds of its data.\n", lease)
 					_, _ = fmt.Fprintf(out, "  It is ignored while this DC is the ACTIVE one (demoting the active DC without a quiesce is unsafe): move the primary away with a switchover first.\n")
-					_, _ = fmt.Fprintf(out, "  Remove with:  kubectl dba dc-dr pin-standby --scope %s --remove --yes\n", lease)
+					_, _ = fmt.Fprintf(out, "  Remove with:  kubectl dba dc-dr pin-standby --scope %s --remove --yes --reason <why>\n", lease)
 				}
 			}
 			return nil
 		},
 	}
 	cmd.Flags().StringVar(&scopeName, "scope", "", "Primary-DC Lease name of the scope (for example primary-dc or primary-dc-orders)")
-	cmd.Flags().StringVar(&dbName, "db", "", "Resolve the scope from this database instead (requires the kubeconfig to reach the hub)")
+	cmd.Flags().StringVar(&dbName, "db", "", "Resolve the scope from this database ([KIND/]NAME) instead (requires the kubeconfig to reach the hub)")
 	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the pin instead of creating it")
 	if kind == pinPrimary {
 		cmd.Flags().BoolVar(&force, "force", false, "With --remove: also clear the override-hold annotation directly on the Lease, for when the pinned DC is dead and its agent cannot clear it (run against any live cluster; needs the --coord-* flags to reach the coordination plane)")
@@ -148,11 +163,12 @@ func NewCmdPin(f cmdutil.Factory, kind pinKind) *cobra.Command {
 		cmd.Flags().StringVar(&cf.LeaseNS, "coord-namespace", DefaultCoordNamespace, "Namespace on this spoke that holds the marker ConfigMaps")
 	}
 	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm")
+	AddReasonFlag(cmd, &reason)
 	return cmd
 }
 
 func pinPrimaryTexts() (use, short, long, example string) {
-	return "pin-primary (--scope LEASE | --db DB_NAME)",
+	return "pin-primary (--scope LEASE | --db [KIND/]DB_NAME)",
 		"Pin this data center as primary (break-glass override): no failover, writable through a control-plane outage",
 		`Creates the human-owned break-glass override ConfigMap <scope>-override in
 			the coordination namespace of the CURRENT cluster, which must be the data
@@ -169,16 +185,17 @@ func pinPrimaryTexts() (use, short, long, example string) {
 			scope over" policy. While it stands there is no split-brain protection for
 			the scope, and nothing takes over if this DC dies.
 
-			KUBECONFIG: the SPOKE of the data center being pinned.`,
+			KUBECONFIG: the SPOKE of the data center being pinned. The --reason is
+			recorded in that spoke's audit trail (dc-dr history against it).`,
 		`# Keep dc-b primary for the global scope, come what may (run against dc-b)
-			kubectl dba dc-dr pin-primary --scope primary-dc --yes --kubeconfig ~/.kube/dc-b.yaml
+			kubectl dba dc-dr pin-primary --scope primary-dc --yes --reason "coordination plane down" --kubeconfig ~/.kube/dc-b.yaml
 
 			# Clear it once the emergency is over
-			kubectl dba dc-dr pin-primary --scope primary-dc --remove --yes --kubeconfig ~/.kube/dc-b.yaml`
+			kubectl dba dc-dr pin-primary --scope primary-dc --remove --yes --reason "coordination plane back" --kubeconfig ~/.kube/dc-b.yaml`
 }
 
 func pinStandbyTexts() (use, short, long, example string) {
-	return "pin-standby (--scope LEASE | --db DB_NAME)",
+	return "pin-standby (--scope LEASE | --db [KIND/]DB_NAME)",
 		"Pin this data center as a standby (standby-hold): it never promotes",
 		`Creates the human-owned standby-hold ConfigMap <scope>-standby-hold in the
 			coordination namespace of the CURRENT cluster, which must be the data
@@ -194,10 +211,11 @@ func pinStandbyTexts() (use, short, long, example string) {
 			because demoting the active DC without a quiesce is unsafe; move the
 			primary away with a planned switchover first.
 
-			KUBECONFIG: the SPOKE of the data center being held.`,
+			KUBECONFIG: the SPOKE of the data center being held. The --reason is
+			recorded in that spoke's audit trail (dc-dr history against it).`,
 		`# Never let dc-a take the primary role for this scope (run against dc-a)
-			kubectl dba dc-dr pin-standby --scope primary-dc --yes --kubeconfig ~/.kube/dc-a.yaml
+			kubectl dba dc-dr pin-standby --scope primary-dc --yes --reason "dc-a storage degraded" --kubeconfig ~/.kube/dc-a.yaml
 
 			# Release it
-			kubectl dba dc-dr pin-standby --scope primary-dc --remove --yes --kubeconfig ~/.kube/dc-a.yaml`
+			kubectl dba dc-dr pin-standby --scope primary-dc --remove --yes --reason "dc-a storage replaced" --kubeconfig ~/.kube/dc-a.yaml`
 }
@@ -0,0 +1,236 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// switchoverPreflight evaluates, before anything is written, every gate the
+// operator will check once dr.kubedb.com/switchover-to is set. It reads the hub,
+// the coordination plane for the Lease gates and, when sf has one for it, the
+// target's spoke for the standby-hold gate. The caller has already checked that
+// to is a Member DC and not the active one.
+func switchoverPreflight(ctx context.Context, f cmdutil.Factory, cf *CoordFlags, sf *SpokeFlags, dyn dynamic.Interface, db *unstructured.Unstructured, scope *Scope, to string) []finding {
+	var findings []finding
+	ann := db.GetAnnotations()
+
+	distributed, _, _ := unstructured.NestedBool(db.Object, "spec", "distributed")
+	if !distributed {
+		findings = append(findings, finding{title: "database is DC-DR distributed", detail: "spec.distributed is false", remedy: "DC-DR does not apply to this database", blocker: true})
+	}
+
+	// Nothing else may be moving this database.
+	if cur := ann[AnnSwitchoverTo]; cur != "" && cur != to {
+		findings = append(findings, finding{title: "no other switchover is in flight", detail: fmt.Sprintf("a switchover to %q is already requested", cur), remedy: "wait for it (dc-dr wait) or abort it (dc-dr abort) first", blocker: true})
+	}
+	if ann[AnnQuiesceActive] == "true" || ann[AnnSwitchoverAbort] != "" {
+		findings = append(findings, finding{title: "no quiesce or abort is pending", detail: "the previous switchover is still unwinding", remedy: "wait for the switchover annotations to clear", blocker: true})
+	}
+	findings = append(findings, checkCompetingOps(ctx, dyn, db)...)
+
+	// The target: healthy, lag known and within the budget.
+	activeDC, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+	dcs, _, _ := unstructured.NestedSlice(db.Object, "status", "disasterRecovery", "dataCenters")
+	var target, active map[string]any
+	for _, d := range dcs {
+		dm, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch name, _ := dm["clusterName"].(string); name {
+		case to:
+			target = dm
+		case activeDC:
+			active = dm
+		}
+	}
+	if target == nil {
+		findings = append(findings, finding{title: fmt.Sprintf("target %q is a known data center", to), detail: "it is not present in status.disasterRecovery.dataCenters", remedy: "check the target name against the PlacementPolicy's Member DCs", blocker: true})
+	} else {
+		if healthy, _ := target["healthy"].(bool); healthy {
+			findings = append(findings, finding{ok: true, title: fmt.Sprintf("target %q is healthy", to)})
+		} else {
+			findings = append(findings, finding{title: fmt.Sprintf("target %q is healthy", to), detail: "status reports it unhealthy", remedy: "the switchover refuses an unhealthy target; fix that DC's agent first", blocker: true})
+		}
+		findings = append(findings, checkTargetLag(target, ann[AnnMaxLagBytes]))
+	}
+
+	// The active primary: every gate measures by dialing it.
+	if w, ok := active["writable"].(bool); ok && w {
+		if healthy, _ := active["healthy"].(bool); healthy {
+			findings = append(findings, finding{ok: true, title: fmt.Sprintf("active primary on %q is reachable and writable", activeDC)})
+		} else {
+			findings = append(findings, finding{title: fmt.Sprintf("active DC %q is healthy", activeDC), detail: "it is writable but reported unhealthy", remedy: "the quiesce and catch-up gates dial the active primary and fail closed; fix it or use the failover path (dc-dr handoff)", blocker: true})
+		}
+	} else {
+		findings = append(findings, finding{title: fmt.Sprintf("active primary on %q is reachable and writable", orNone(activeDC)), detail: "status does not report it writable", remedy: "a switchover needs a live primary to quiesce; a dead one can never be switched away from. Use the failover path: dc-dr handoff", blocker: true})
+	}
+
+	findings = append(findings, checkLeaseForSwitchover(ctx, f, cf, scope, activeDC, to)...)
+	return append(findings, checkTargetStandbyHold(ctx, f, sf, cf.LeaseNS, scope, to))
+}
+
+// checkTargetLag compares the target's lag against dr.kubedb.com/switchover-max-lag-bytes
+// when the database sets one.
+func checkTargetLag(target map[string]any, budget string) finding {
+	lag, ok := toInt64(target["lagBytes"])
+	if !ok {
+		return finding{title: "target lag is known", detail: "no lagBytes reported for the target", remedy: "the hub measures lag by dialing the ACTIVE primary; an unreachable primary makes this permanently unknown and the switchover can never start", blocker: true}
+	}
+	if budget == "" {
+		return finding{ok: true, title: fmt.Sprintf("target lag is %d bytes", lag), detail: fmt.Sprintf("no %s set; the operator's default budget applies", AnnMaxLagBytes)}
+	}
+	maxLag, err := strconv.ParseInt(budget, 10, 64)
+	if err != nil {
+		return finding{title: "switchover lag budget is valid", detail: fmt.Sprintf("%s=%q is not a number", AnnMaxLagBytes, budget), remedy: "fix the annotation; the operator cannot evaluate the budget", blocker: true}
+	}
+	if lag > maxLag {
+		return finding{title: "target lag is within the switchover budget", detail: fmt.Sprintf("lag is %d bytes, budget %d", lag, maxLag), remedy: "wait for the target to catch up, or raise " + AnnMaxLagBytes, blocker: true}
+	}
+	return finding{ok: true, title: fmt.Sprintf("target lag is %d bytes, within the %d byte switchover budget", lag, maxLag)}
+}
+
+// checkCompetingOps reports OpsRequests of the database that have not finished;
+// the operator does not start a switchover under one.
+func checkCompetingOps(ctx context.Context, dyn dynamic.Interface, db *unstructured.Unstructured) []finding {
+	e, err := engineFor(db)
+	if err != nil {
+		return []finding{{title: "no competing OpsRequests", detail: err.Error(), remedy: "could not list the database's OpsRequests"}}
+	}
+	list, err := dyn.Resource(e.OpsGVR).Namespace(db.GetNamespace()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []finding{{title: "no competing OpsRequests", detail: err.Error(), remedy: "could not list the database's OpsRequests"}}
+	}
+	var running []string
+	for i := range list.Items {
+		o := &list.Items[i]
+		dbRef, _, _ := unstructured.NestedString(o.Object, "spec", "databaseRef", "name")
+		if dbRef != db.GetName() {
+			continue
+		}
+		switch phase, _, _ := unstructured.NestedString(o.Object, "status", "phase"); phase {
+		case "Successful", "Failed", "Skipped", "Denied":
+		default:
+			typ, _, _ := unstructured.NestedString(o.Object, "spec", "type")
+			running = append(running, fmt.Sprintf("%s (%s, %s)", o.GetName(), typ, orNone(phase)))
+		}
+	}
+	if len(running) > 0 {
+		return []finding{{title: "no competing OpsRequests", detail: "not finished: " + strings.Join(running, ", "), remedy: "wait for them to finish or delete them first", blocker: true}}
+	}
+	return []finding{{ok: true, title: "no competing OpsRequests"}}
+}
+
+// checkLeaseForSwitchover checks the scope's Lease and the target's health Lease
+// on the coordination plane.
+func checkLeaseForSwitchover(ctx context.Context, f cmdutil.Factory, cf *CoordFlags, scope *Scope, activeDC, to string) []finding {
+	coord, err := cf.CoordClient(ctx, f)
+	if err != nil {
+		return []finding{{title: "coordination plane reachable", detail: err.Error(), remedy: "pass --coord-kubeconfig / --coord-kubeconfig-secret to evaluate the Lease gates", blocker: true}}
+	}
+	lease, err := coord.CoordinationV1().Leases(cf.LeaseNS).Get(ctx, scope.LeaseName, metav1.GetOptions{})
+	if err != nil {
+		return []finding{{title: "primary-DC Lease exists", detail: err.Error(), remedy: "an unregistered scope has no Lease to hand off; check the PlacementPolicy failoverPolicy", blocker: true}}
+	}
+	var out []finding
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	dur := int32(45)
+	if lease.Spec.LeaseDurationSeconds != nil {
+		dur = *lease.Spec.LeaseDurationSeconds
+	}
+	switch {
+	case lease.Spec.RenewTime == nil || time.Since(lease.Spec.RenewTime.Time) > time.Duration(dur)*time.Second:
+		out = append(out, finding{title: fmt.Sprintf("Lease %s is fresh", scope.LeaseName), detail: fmt.Sprintf("holder %q is not renewing it", holder), remedy: "an expired Lease means a failover is about to happen, not a switchover; see dc-dr debug failover", blocker: true})
+	case holder != activeDC:
+		out = append(out, finding{title: "Lease holder matches the active DC", detail: fmt.Sprintf("Lease held by %q, status reports %q", holder, activeDC), remedy: "the status trails the Lease by a reconcile; retry once they agree"})
+	default:
+		out = append(out, finding{ok: true, title: fmt.Sprintf("Lease %s is fresh and held by %q", scope.LeaseName, holder)})
+	}
+	if pin := lease.Annotations["dr.open-cluster-management.io/override-hold"]; pin != "" {
+		out = append(out, finding{title: "no break-glass pin holds the scope", detail: fmt.Sprintf("scope is PINNED to %q", pin), remedy: "remove that DC's override ConfigMap first (dc-dr pin-primary --remove)", blocker: true})
+	}
+	if ho := lease.Annotations[AnnLeaseHandoffTo]; ho != "" {
+		out = append(out, finding{title: "no handoff is in flight", detail: fmt.Sprintf("handoff-to=%q is set", ho), remedy: "wait for the handoff to land", blocker: true})
+	}
+
+	// A stale health Lease means the target's agent cannot take over.
+	hl, err := coord.CoordinationV1().Leases(cf.LeaseNS).Get(ctx, "dc-health-"+to, metav1.GetOptions{})
+	switch {
+	case err != nil:
+		out = append(out, finding{title: fmt.Sprintf("target %q is reporting health", to), detail: err.Error(), remedy: "a DC with no health Lease has no running agent; it cannot acquire the primary role", blocker: true})
+	case hl.Spec.RenewTime == nil:
+		out = append(out, finding{title: fmt.Sprintf("target %q agent is alive", to), detail: "its health Lease has never been renewed", remedy: "that DC's agent is down or cannot reach the coordination plane", blocker: true})
+	default:
+		hdur := int32(15)
+		if hl.Spec.LeaseDurationSeconds != nil {
+			hdur = *hl.Spec.LeaseDurationSeconds
+		}
+		if age := time.Since(hl.Spec.RenewTime.Time).Round(time.Second); age > 3*time.Duration(hdur)*time.Second {
+			out = append(out, finding{title: fmt.Sprintf("target %q agent is alive", to), detail: fmt.Sprintf("its health Lease is stale (%s old)", age), remedy: "that DC's agent is down or cannot reach the coordination plane", blocker: true})
+		} else {
+			out = append(out, finding{ok: true, title: fmt.Sprintf("target %q agent is alive (health renewed %s ago)", to, age)})
+		}
+	}
+	return out
+}
+
+// checkTargetStandbyHold reads the standby-hold ConfigMap on the target's spoke;
+// a held DC never promotes, so the switchover would stall after the quiesce.
+func checkTargetStandbyHold(ctx context.Context, f cmdutil.Factory, sf *SpokeFlags, ns string, scope *Scope, to string) finding {
+	title := fmt.Sprintf("target %q is not standby-held", to)
+	if !sf.Has(to) {
+		return finding{title: title, detail: "its spoke is not readable from here: kubectl -n " + ns + " get cm " + scope.LeaseName + StandbyHoldCMSuffix, remedy: "pass --spoke-kubeconfig / --spoke-kubeconfig-secret for " + to + " to evaluate this gate"}
+	}
+	target := SpokeFlags{}
+	if file, ok := sf.Files[to]; ok {
+		target.Files = map[string]string{to: file}
+	}
+	if secret, ok := sf.Secrets[to]; ok {
+		target.Secrets = map[string]string{to: secret}
+	}
+	states := readSpokes(ctx, f, &target, ns, scope.LeaseName)
+	switch s := states[0]; {
+	case s.Error != "":
+		return finding{title: title, detail: s.Error, remedy: "check its --spoke-kubeconfig / --spoke-kubeconfig-secret", blocker: true}
+	case s.Held:
+		return finding{title: title, detail: fmt.Sprintf("ConfigMap %s exists on its spoke: it never promotes", scope.LeaseName+StandbyHoldCMSuffix), remedy: "remove it first: kubectl dba dc-dr pin-standby --scope " + scope.LeaseName + " --remove --yes --reason <why> (against that spoke)", blocker: true}
+	}
+	return finding{ok: true, title: title}
+}
+
+func countBlockers(fs []finding) int {
+	n := 0
+	for _, fd := range fs {
+		if !fd.ok && fd.blocker {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,248 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	meta_util "kmodules.xyz/client-go/meta"
+	exec_util "kmodules.xyz/client-go/tools/exec"
+)
+
+// drillTable holds the drill's probe writes. Rows are keyed by run, so
+// concurrent or earlier drills never confuse the verification.
+const drillTable = "kubedb_dcdr_drill"
+
+// writeProbe inserts one sequence-numbered row per interval into the primary of
+// whichever data center is active at that moment, and records which inserts
+// the database acknowledged.
+type writeProbe struct {
+	run      string
+	interval time.Duration
+	primary  func(ctx context.Context) (*rest.Config, *core.Pod, error)
+
+	mu       sync.Mutex
+	seq      int64
+	acked    []probeAck
+	failures int
+	lastErr  string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type probeAck struct {
+	seq int64
+	at  time.Time
+}
+
+// newWriteProbe returns a probe that resolves the primary through the database
+// CR's activeDC: on that DC's spoke when sf has one for it, else on the current
+// cluster.
+func newWriteProbe(f cmdutil.Factory, sf *SpokeFlags, dyn dynamic.Interface, db *unstructured.Unstructured, run string, interval time.Duration) (*writeProbe, error) {
+	e, err := engineFor(db)
+	if err != nil {
+		return nil, err
+	}
+	gvr := e.GVR
+	sel := labels.SelectorFromSet(map[string]string{
+		meta_util.NameLabelKey:     gvr.Resource + "." + kubedb.GroupName,
+		meta_util.InstanceLabelKey: db.GetName(),
+		kubedb.LabelRole:           "primary",
+	})
+	return &writeProbe{
+		run:      run,
+		interval: interval,
+		primary: func(ctx context.Context) (*rest.Config, *core.Pod, error) {
+			cur, err := dyn.Resource(gvr).Namespace(db.GetNamespace()).Get(ctx, db.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return nil, nil, err
+			}
+			activeDC, _, _ := unstructured.NestedString(cur.Object, "status", "disasterRecovery", "activeDC")
+			var cfg *rest.Config
+			if sf.Has(activeDC) {
+				cfg, err = sf.SpokeConfig(ctx, f, activeDC)
+			} else {
+				cfg, err = f.ToRESTConfig()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			kc, err := kubernetes.NewForConfig(cfg)
+			if err != nil {
+				return nil, nil, err
+			}
+			pods, err := kc.CoreV1().Pods(db.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(pods.Items) == 0 {
+				return nil, nil, fmt.Errorf("no primary pod of %s/%s in data center %s", db.GetNamespace(), db.GetName(), orNone(activeDC))
+			}
+			return cfg, &pods.Items[0], nil
+		},
+	}, nil
+}
+
+func (p *writeProbe) psql(ctx context.Context, query string) (string, error) {
+	cfg, pod, err := p.primary(ctx)
+	if err != nil {
+		return "", err
+	}
+	return exec_util.ExecIntoPodWithContext(ctx, cfg, pod,
+		exec_util.Container("postgres"),
+		exec_util.Command("psql", "-tA", "-v", "ON_ERROR_STOP=1", "-c", query),
+	)
+}
+
+// Start creates the probe table and begins writing in the background.
+func (p *writeProbe) Start(ctx context.Context) error {
+	if _, err := p.psql(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (run text NOT NULL, seq bigint NOT NULL, at timestamptz NOT NULL DEFAULT now(), PRIMARY KEY (run, seq))", drillTable)); err != nil {
+		return fmt.Errorf("failed to create the probe table: %w", err)
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.write(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *writeProbe) write(ctx context.Context) {
+	p.mu.Lock()
+	p.seq++
+	seq := p.seq
+	p.mu.Unlock()
+
+	wctx, cancel := context.WithTimeout(ctx, p.interval+5*time.Second)
+	defer cancel()
+	_, err := p.psql(wctx, fmt.Sprintf("INSERT INTO %s (run, seq) VALUES ('%s', %d)", drillTable, p.run, seq))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if ctx.Err() == nil {
+			p.failures++
+			p.lastErr = err.Error()
+		}
+		return
+	}
+	p.acked = append(p.acked, probeAck{seq: seq, at: time.Now()})
+}
+
+// Stop ends the writes and waits for the one in flight.
+func (p *writeProbe) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+}
+
+// ProbeResult is the write probe's part of the drill report.
+type ProbeResult struct {
+	Interval     string  `json:"interval"`
+	Attempted    int64   `json:"attempted"`
+	Acknowledged int     `json:"acknowledged"`
+	Failed       int     `json:"failed"`
+	LastError    string  `json:"lastError,omitempty"`
+	Lost         []int64 `json:"lost"`
+	// MaxWriteGap is the longest time between two consecutive acknowledged
+	// writes, i.e. the observed write outage plus up to one probe interval.
+	MaxWriteGap string `json:"maxWriteGap"`
+	GapFrom     string `json:"gapFrom,omitempty"`
+	GapTo       string `json:"gapTo,omitempty"`
+	Verified    bool   `json:"verified"`
+	VerifyError string `json:"verifyError,omitempty"`
+}
+
+// Verify reads the run's rows back from the current primary and reports every
+// acknowledged write that is missing.
+func (p *writeProbe) Verify(ctx context.Context) ProbeResult {
+	p.mu.Lock()
+	acked := append([]probeAck(nil), p.acked...)
+	r := ProbeResult{
+		Interval:     p.interval.String(),
+		Attempted:    p.seq,
+		Acknowledged: len(p.acked),
+		Failed:       p.failures,
+		LastError:    p.lastErr,
+		Lost:         []int64{},
+	}
+	p.mu.Unlock()
+
+	sort.Slice(acked, func(i, j int) bool { return acked[i].at.Before(acked[j].at) })
+	var gap time.Duration
+	for i := 1; i < len(acked); i++ {
+		if d := acked[i].at.Sub(acked[i-1].at); d > gap {
+			gap = d
+			r.GapFrom, r.GapTo = acked[i-1].at.UTC().Format(time.RFC3339), acked[i].at.UTC().Format(time.RFC3339)
+		}
+	}
+	r.MaxWriteGap = gap.Round(time.Millisecond).String()
+
+	out, err := p.psql(ctx, fmt.Sprintf("SELECT seq FROM %s WHERE run = '%s'", drillTable, p.run))
+	if err != nil {
+		r.VerifyError = err.Error()
+		return r
+	}
+	present := map[int64]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		if n, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64); err == nil {
+			present[n] = true
+		}
+	}
+	for _, a := range acked {
+		if !present[a.seq] {
+			r.Lost = append(r.Lost, a.seq)
+		}
+	}
+	sort.Slice(r.Lost, func(i, j int) bool { return r.Lost[i] < r.Lost[j] })
+	r.Verified = true
+	return r
+}
+
+// Cleanup deletes the run's rows; the table is left for later drills.
+func (p *writeProbe) Cleanup(ctx context.Context) error {
+	_, err := p.psql(ctx, fmt.Sprintf("DELETE FROM %s WHERE run = '%s'", drillTable, p.run))
+	return err
+}
@@ -0,0 +1,245 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// markerTTL is how long a spoke's marker stays valid without a renewal before
+// the local coordinator fences its database read-only.
+const markerTTL = 30 * time.Second
+
+// SpokeFlags resolves a kubeconfig per data center, so that commands running
+// against the hub can also read the spoke-local ConfigMaps: the projected marker,
+// the break-glass override and the standby-hold. Per DC, a file wins over a
+// Secret.
+//
+//   - --spoke-kubeconfig dc-a=/path/a.yaml,dc-b=/path/b.yaml: kubeconfig FILES.
+//   - --spoke-kubeconfig-secret dc-a=[ns/]name,...: key "kubeconfig" of that
+//     Secret on the CURRENT cluster (normally the hub).
+type SpokeFlags struct {
+	Files   map[string]string
+	Secrets map[string]string
+}
+
+// AddSpokeFlags registers the spoke kubeconfig flags on cmd.
+func AddSpokeFlags(cmd *cobra.Command, sf *SpokeFlags) {
+	cmd.Flags().StringToStringVar(&sf.Files, "spoke-kubeconfig", nil, "Kubeconfig file per data center (dc-a=path,dc-b=path) used to read the spoke-local marker, pin and standby-hold ConfigMaps")
+	cmd.Flags().StringToStringVar(&sf.Secrets, "spoke-kubeconfig-secret", nil, "Secret ([namespace/]name, key \"kubeconfig\") on the current cluster per data center (dc-a=ns/name,...) holding that spoke's kubeconfig")
+}
+
+// Enabled reports whether any spoke was given.
+func (sf *SpokeFlags) Enabled() bool {
+	return len(sf.Files) > 0 || len(sf.Secrets) > 0
+}
+
+// Has reports whether a kubeconfig was given for data center dc.
+func (sf *SpokeFlags) Has(dc string) bool {
+	_, file := sf.Files[dc]
+	_, secret := sf.Secrets[dc]
+	return file || secret
+}
+
+// DataCenters returns the data centers a kubeconfig was given for, sorted.
+func (sf *SpokeFlags) DataCenters() []string {
+	set := map[string]bool{}
+	for dc := range sf.Files {
+		set[dc] = true
+	}
+	for dc := range sf.Secrets {
+		set[dc] = true
+	}
+	dcs := make([]string, 0, len(set))
+	for dc := range set {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+	return dcs
+}
+
+// SpokeClient builds a client for the spoke of data center dc.
+func (sf *SpokeFlags) SpokeClient(ctx context.Context, f cmdutil.Factory, dc string) (kubernetes.Interface, error) {
+	cfg, err := sf.SpokeConfig(ctx, f, dc)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
+
+// SpokeConfig resolves the rest config of the spoke of data center dc.
+func (sf *SpokeFlags) SpokeConfig(ctx context.Context, f cmdutil.Factory, dc string) (*rest.Config, error) {
+	var kubeconfigBytes []byte
+	if file, ok := sf.Files[dc]; ok {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the kubeconfig file of spoke %s: %w", dc, err)
+		}
+		kubeconfigBytes = b
+	} else if ref, ok := sf.Secrets[dc]; ok {
+		cur, err := f.KubernetesClientSet()
+		if err != nil {
+			return nil, err
+		}
+		ns, name := splitNSName(ref, DefaultCoordNamespace)
+		sec, err := cur.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the kubeconfig Secret %s/%s of spoke %s from the current cluster: %w", ns, name, dc, err)
+		}
+		kubeconfigBytes = sec.Data["kubeconfig"]
+	} else {
+		return nil, fmt.Errorf("no kubeconfig given for spoke %s", dc)
+	}
+	if len(kubeconfigBytes) == 0 {
+		return nil, fmt.Errorf("resolved an empty kubeconfig for spoke %s (expected key \"kubeconfig\")", dc)
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigBytes)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig of spoke %s is not valid: %w", dc, err)
+	}
+	return cfg, nil
+}
+
+// SpokeState is what one data center's spoke holds for a scope.
+type SpokeState struct {
+	DataCenter string `json:"dataCenter"`
+	// Error is set when the spoke, or one of the ConfigMaps, could not be read.
+	Error string `json:"error,omitempty"`
+	// Marker is the data of the marker ConfigMap the DC's agent projects from
+	// the Lease, nil when it does not exist.
+	Marker map[string]string `json:"marker,omitempty"`
+	// MarkerAge is the time since the agent last renewed the marker, nil when
+	// the marker has no valid renewTime.
+	MarkerAge *metav1.Duration `json:"markerAge,omitempty"`
+	Pinned    bool             `json:"pinned"`
+	Held      bool             `json:"standbyHold"`
+}
+
+// MarkerStale reports whether the marker is missing, has no valid renewTime or
+// is older than its TTL, any of which fences the DC's database.
+func (s SpokeState) MarkerStale() bool {
+	return s.Marker == nil || s.MarkerAge == nil || s.MarkerAge.Duration > markerTTL
+}
+
+// readSpokes reads the scope's marker, override and standby-hold ConfigMaps on
+// every spoke in sf.
+func readSpokes(ctx context.Context, f cmdutil.Factory, sf *SpokeFlags, ns, lease string) []SpokeState {
+	var states []SpokeState
+	for _, dc := range sf.DataCenters() {
+		s := SpokeState{DataCenter: dc}
+		cs, err := sf.SpokeClient(ctx, f, dc)
+		if err != nil {
+			s.Error = err.Error()
+			states = append(states, s)
+			continue
+		}
+		cms := cs.CoreV1().ConfigMaps(ns)
+		var errs []string
+		marker, err := cms.Get(ctx, lease, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			s.Marker = marker.Data
+			if s.Marker == nil {
+				s.Marker = map[string]string{}
+			}
+			if renewed, err := time.Parse(time.RFC3339Nano, s.Marker[MarkerRenewTimeKey]); err == nil {
+				s.MarkerAge = &metav1.Duration{Duration: time.Since(renewed).Round(time.Second)}
+			}
+		case !kerr.IsNotFound(err):
+			s.Error = err.Error()
+			states = append(states, s)
+			continue
+		}
+		// A pin or a hold that cannot be read is reported, never taken as absent:
+		// the agent itself assumes a standby-hold it cannot read.
+		for _, cm := range []struct {
+			suffix string
+			exists *bool
+		}{{OverrideCMSuffix, &s.Pinned}, {StandbyHoldCMSuffix, &s.Held}} {
+			_, err := cms.Get(ctx, lease+cm.suffix, metav1.GetOptions{})
+			switch {
+			case err == nil:
+				*cm.exists = true
+			case !kerr.IsNotFound(err):
+				errs = append(errs, fmt.Sprintf("failed to read ConfigMap %s: %v", lease+cm.suffix, err))
+			}
+		}
+		s.Error = strings.Join(errs, "; ")
+		states = append(states, s)
+	}
+	return states
+}
+
+func renderMarker(data map[string]string) string {
+	if data == nil {
+		return "<missing>"
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+data[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+// spokeFindings turns the spokes' state into findings. holder is the Lease
+// holder, the only DC whose marker may say it is active.
+func spokeFindings(states []SpokeState, lease, holder string) []finding {
+	var out []finding
+	for _, s := range states {
+		if s.Error != "" {
+			out = append(out, finding{title: fmt.Sprintf("spoke %s is readable", s.DataCenter), detail: s.Error, remedy: "check its --spoke-kubeconfig / --spoke-kubeconfig-secret"})
+			continue
+		}
+		switch {
+		case s.Marker == nil:
+			out = append(out, finding{title: fmt.Sprintf("%s has a marker", s.DataCenter), detail: fmt.Sprintf("ConfigMap %s is missing on its spoke, so its database is fenced", lease), remedy: "the DC's agent projects it from the Lease; check that the agent is running and reaches the coordination plane", blocker: s.DataCenter == holder})
+		case s.MarkerAge == nil:
+			out = append(out, finding{title: fmt.Sprintf("%s marker is fresh", s.DataCenter), detail: fmt.Sprintf("it has no valid %s: %s", MarkerRenewTimeKey, renderMarker(s.Marker)), remedy: "the marker is not the one the DC's agent writes; its database fences fail-closed until the agent rewrites it", blocker: s.DataCenter == holder})
+		case s.MarkerStale():
+			out = append(out, finding{title: fmt.Sprintf("%s marker is fresh", s.DataCenter), detail: fmt.Sprintf("last renewed %s ago, past its %s TTL: %s", s.MarkerAge.Duration, markerTTL, renderMarker(s.Marker)), remedy: "the DC's agent stopped renewing it; its database fences fail-closed until it does, or until pinned with dc-dr pin-primary", blocker: s.DataCenter == holder})
+		case holder != "" && s.DataCenter == holder && s.Marker[MarkerHolderKey] != holder:
+			out = append(out, finding{title: fmt.Sprintf("%s marker names it active", s.DataCenter), detail: renderMarker(s.Marker), remedy: "the marker trails the Lease by one agent tick; if it persists the agent is not projecting the Lease", blocker: true})
+		default:
+			out = append(out, finding{ok: true, title: fmt.Sprintf("%s marker is fresh (renewed %s ago)", s.DataCenter, s.MarkerAge.Duration), detail: renderMarker(s.Marker)})
+		}
+		if s.Pinned {
+			out = append(out, finding{title: fmt.Sprintf("%s is not pinned", s.DataCenter), detail: fmt.Sprintf("ConfigMap %s exists on its spoke: it stays primary and writable regardless of the Lease", lease+OverrideCMSuffix), remedy: "remove it once the outage is over: kubectl dba dc-dr pin-primary --scope " + lease + " --remove --yes --reason <why> (against that spoke)"})
+		}
+		if s.Held {
+			out = append(out, finding{title: fmt.Sprintf("%s is not standby-held", s.DataCenter), detail: fmt.Sprintf("ConfigMap %s exists on its spoke: it never promotes", lease+StandbyHoldCMSuffix), remedy: "remove it to let that DC take over again: kubectl dba dc-dr pin-standby --scope " + lease + " --remove --yes --reason <why> (against that spoke)"})
+		}
+	}
+	return out
+}
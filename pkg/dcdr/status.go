@@ -36,6 +36,24 @@ const (
 	stepSkipped
 )
 
+// String is the enum value of the state in `dc-dr status -o json|yaml`.
+func (s stepState) String() string {
+	switch s {
+	case stepDone:
+		return "Done"
+	case stepCurrent:
+		return "Current"
+	case stepSkipped:
+		return "Skipped"
+	default:
+		return "Pending"
+	}
+}
+
+func (s stepState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 func (s stepState) mark() string {
 	switch s {
 	case stepDone:
@@ -53,8 +71,11 @@ func (s stepState) mark() string {
 // switchover is in flight, which of its steps are complete and what happens next.
 // Deliberately NOT a watch: it prints once and exits, so the user re-runs it.
 func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
+	var output string
+	var sf SpokeFlags
+	var markerNS string
 	cmd := &cobra.Command{
-		Use:   "status DB_NAME",
+		Use:   "status [KIND/]DB_NAME",
 		Short: i18n.T("Show DC-DR state and switchover progress once (re-run to see further progress)"),
 		Long: templates.LongDesc(`
 			Prints the database's failover scope, per data center state, protection
@@ -63,15 +84,34 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 
 			One-shot by design: it does not follow. Run it again to see the next
 			state, which keeps its output readable in tickets and transcripts.
+			Scripts that need to block use "dc-dr wait" instead.
+
+			With --spoke-kubeconfig or --spoke-kubeconfig-secret it also reads, on
+			every given data center's spoke, the scope's marker (content and age),
+			break-glass pin and standby-hold.
+
+			With -o json|yaml the same state is printed as a versioned DCDRStatus
+			document (apiVersion dcdr.kubedb.com/v1alpha1) for automation. Its
+			switchover step states (Done, Current, Pending, Skipped) come from the
+			same computation as the text output.
 
 			KUBECONFIG: the hub cluster.`),
 		Example: templates.Examples(`
-			kubectl dba dc-dr status pg-dcdr -n demo`),
+			kubectl dba dc-dr status pg-dcdr -n demo
+
+			# Any engine that publishes status.disasterRecovery, as KIND/NAME
+			kubectl dba dc-dr status mysql/my-dcdr -n demo
+
+			# For automation
+			kubectl dba dc-dr status pg-dcdr -n demo -o json`),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return fmt.Errorf("exactly one database name is required")
 			}
+			if output != "" && output != "json" && output != "yaml" {
+				return fmt.Errorf("unsupported output format %q, must be one of: json|yaml", output)
+			}
 			ctx := context.Background()
 			db, dyn, ns, err := getDB(ctx, f, args[0])
 			if err != nil {
@@ -82,6 +122,16 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 				return err
 			}
 			out := cmd.OutOrStdout()
+			warnIfNoDRStatus(printfErr(cmd), db)
+			var spokes []SpokeState
+			if sf.Enabled() {
+				spokes = readSpokes(ctx, f, &sf, markerNS, scope.LeaseName)
+			}
+			if output != "" {
+				r := newStatusReport(db, scope)
+				r.Spokes = spokes
+				return printStatusReport(out, r, output)
+			}
 			ann := db.GetAnnotations()
 			dbPhase, _, _ := unstructured.NestedString(db.Object, "status", "phase")
 			activeDC, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
@@ -89,7 +139,7 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 			protected, protectedSet, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected")
 			protMsg, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "protectionMessage")
 
-			_, _ = fmt.Fprintf(out, "Database:      %s/%s (%s)\n", ns, args[0], dbPhase)
+			_, _ = fmt.Fprintf(out, "Database:      %s/%s (%s)\n", ns, db.GetName(), dbPhase)
 			_, _ = fmt.Fprintf(out, "Failover scope: %s\n                (%s)\n", scope.LeaseName, scope.Source)
 			if len(scope.MemberDCs) > 0 {
 				_, _ = fmt.Fprintf(out, "Member DCs:    %s\n", strings.Join(scope.MemberDCs, ", "))
@@ -126,6 +176,10 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 				}
 			}
 
+			if len(spokes) > 0 {
+				renderSpokes(out, spokes)
+			}
+
 			// Switchover progress, only when one is in flight or was just requested.
 			target := ann[AnnSwitchoverTo]
 			quiesced := ann[AnnQuiesceActive] == "true"
@@ -148,8 +202,9 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 					return nil
 				}
 				renderSwitchoverSteps(out, target, activeDC, quiesced, dcs)
-				_, _ = fmt.Fprintf(out, "\n  Abort:  kubectl dba dc-dr abort %s -n %s\n", args[0], ns)
+				_, _ = fmt.Fprintf(out, "\n  Abort:  kubectl dba dc-dr abort %s -n %s --reason <why>\n", args[0], ns)
 				_, _ = fmt.Fprintf(out, "  Re-run this command to see the next step; it does not follow.\n")
+				_, _ = fmt.Fprintf(out, "  To block until done:  kubectl dba dc-dr wait %s -n %s --for switchover-complete\n", args[0], ns)
 				return nil
 			}
 
@@ -158,20 +213,29 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 				_, _ = fmt.Fprintf(out, "DR phase is FailingOver with no switchover annotation, so this is an UNPLANNED failover.\n")
 				_, _ = fmt.Fprintf(out, "  If it is not completing: kubectl dba dc-dr debug failover %s -n %s\n", args[0], ns)
 			}
+			if e, err := engineFor(db); err != nil {
+				return err
+			} else if !e.AnnotationContract {
+				_, _ = fmt.Fprintf(out, "Move it:      kubectl dba dc-dr handoff %s -n %s --to <dc> --reason <why>\n", args[0], ns)
+				return nil
+			}
 			if protectedSet && !protected {
 				_, _ = fmt.Fprintf(out, "Protection is NOT confirmed. If a promotion is held by the RPO budget:\n")
-				_, _ = fmt.Fprintf(out, "  kubectl dba dc-dr accept-data-loss %s -n %s --yes\n", args[0], ns)
+				_, _ = fmt.Fprintf(out, "  kubectl dba dc-dr accept-data-loss %s -n %s --yes --reason <why>\n", args[0], ns)
 			}
-			_, _ = fmt.Fprintf(out, "Trigger one:  kubectl dba dc-dr switchover %s -n %s --to <dc>\n", args[0], ns)
+			_, _ = fmt.Fprintf(out, "Trigger one:  kubectl dba dc-dr switchover %s -n %s --to <dc> --reason <why>\n", args[0], ns)
 			return nil
 		},
 	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format. One of: json|yaml (default: human-readable text)")
+	cmd.Flags().StringVar(&markerNS, "coord-namespace", DefaultCoordNamespace, "Namespace on each spoke that holds the marker ConfigMaps")
+	AddSpokeFlags(cmd, &sf)
 	return cmd
 }
 
-// renderSwitchoverSteps prints the operator's switchover sequence with each step's
-// state derived from the SAME status fields the operator's own gates read, so the
-// output cannot drift from the real decision:
+// switchoverProgress is the state of each of the operator's six switchover steps,
+// derived from the SAME status fields the operator's own gates read, so neither
+// the text nor the json output can drift from the real decision:
 //
 //	1 target validated (healthy, lag known and within the switchover budget)
 //	2 quiesce requested   (annotation set)
@@ -179,7 +243,12 @@ func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
 //	4 target caught up     (target lagBytes <= 8 KiB)
 //	5 Lease handed off     (activeDC == target)
 //	6 old DC demoted, annotations cleared, phase Steady
-func renderSwitchoverSteps(out interface{ Write([]byte) (int, error) }, target, activeDC string, quiesced bool, dcs []any) {
+type switchoverProgress struct {
+	steps     [6]stepState
+	targetLag any
+}
+
+func computeSwitchoverProgress(target, activeDC string, quiesced bool, dcs []any) switchoverProgress {
 	var targetLag any
 	targetHealthy, targetKnown := false, false
 	activeWritable, activeWritableKnown := true, false
@@ -247,6 +316,18 @@ func renderSwitchoverSteps(out interface{ Write([]byte) (int, error) }, target,
 	if handedOff {
 		step6 = stepCurrent
 	}
+	return switchoverProgress{
+		steps:     [6]stepState{step1, step2, step3, step4, step5, step6},
+		targetLag: targetLag,
+	}
+}
+
+// renderSwitchoverSteps prints the switchoverProgress of a switchover to target
+// and what happens next.
+func renderSwitchoverSteps(out interface{ Write([]byte) (int, error) }, target, activeDC string, quiesced bool, dcs []any) {
+	p := computeSwitchoverProgress(target, activeDC, quiesced, dcs)
+	step1, step2, step3, step4, step5, step6 := p.steps[0], p.steps[1], p.steps[2], p.steps[3], p.steps[4], p.steps[5]
+	targetLag := p.targetLag
 
 	_, _ = fmt.Fprintf(out, "  %s 1. target %q validated: healthy and lag known, within the switchover budget\n", step1.mark(), target)
 	_, _ = fmt.Fprintf(out, "  %s 2. quiesce requested on the active DC (%s)\n", step2.mark(), orNone(activeDC))
@@ -276,6 +357,28 @@ func renderSwitchoverSteps(out interface{ Write([]byte) (int, error) }, target,
 	}
 }
 
+func renderSpokes(out interface{ Write([]byte) (int, error) }, spokes []SpokeState) {
+	_, _ = fmt.Fprintf(out, "\nSpokes:\n")
+	_, _ = fmt.Fprintf(out, "  %-10s %-12s %-7s %-12s %s\n", "DC", "MARKER AGE", "PINNED", "STANDBY-HOLD", "MARKER")
+	for _, s := range spokes {
+		if s.Error != "" {
+			_, _ = fmt.Fprintf(out, "  %-10s unreadable: %s\n", s.DataCenter, s.Error)
+			continue
+		}
+		age := "-"
+		switch {
+		case s.MarkerAge != nil:
+			age = s.MarkerAge.Duration.String()
+			if s.MarkerStale() {
+				age += " STALE"
+			}
+		case s.Marker != nil:
+			age = "? STALE"
+		}
+		_, _ = fmt.Fprintf(out, "  %-10s %-12s %-7v %-12v %s\n", s.DataCenter, age, s.Pinned, s.Held, renderMarker(s.Marker))
+	}
+}
+
 func toInt64(v any) (int64, bool) {
 	switch n := v.(type) {
 	case int64:
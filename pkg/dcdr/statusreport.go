@@ -0,0 +1,181 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// StatusSchemaVersion is the apiVersion of the document `dc-dr status -o json|yaml`
+// prints. Adding a field is compatible; renaming, removing or re-typing one is not
+// and needs a new version.
+const (
+	StatusSchemaVersion = "dcdr.kubedb.com/v1alpha1"
+	StatusKind          = "DCDRStatus"
+)
+
+// StatusReport is the machine-readable form of `dc-dr status`. It is built from
+// the same CR fields, and its switchover steps from the same computation, as the
+// text output, so the two cannot disagree.
+type StatusReport struct {
+	APIVersion  string             `json:"apiVersion"`
+	Kind        string             `json:"kind"`
+	Database    DatabaseRef        `json:"database"`
+	Scope       Scope              `json:"scope"`
+	ActiveDC    string             `json:"activeDC,omitempty"`
+	DRPhase     string             `json:"drPhase,omitempty"`
+	Protection  *ProtectionStatus  `json:"protection,omitempty"`
+	DataCenters []DataCenterStatus `json:"dataCenters"`
+	Switchover  *SwitchoverStatus  `json:"switchover,omitempty"`
+	// Spokes is set only when spoke kubeconfigs were given.
+	Spokes []SpokeState `json:"spokes,omitempty"`
+}
+
+type DatabaseRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase,omitempty"`
+}
+
+type ProtectionStatus struct {
+	Protected bool   `json:"protected"`
+	Message   string `json:"message,omitempty"`
+}
+
+// DataCenterStatus is one entry of status.disasterRecovery.dataCenters. The
+// pointers are nil when the operator has not reported the field yet.
+type DataCenterStatus struct {
+	Name     string `json:"name"`
+	Role     string `json:"role,omitempty"`
+	Writable *bool  `json:"writable,omitempty"`
+	Healthy  *bool  `json:"healthy,omitempty"`
+	LagBytes *int64 `json:"lagBytes,omitempty"`
+	Streamer string `json:"streamer,omitempty"`
+}
+
+// SwitchoverStatus is present only while a planned switchover is in flight or
+// was just requested. Steps is empty once an abort has been requested.
+type SwitchoverStatus struct {
+	Target         string           `json:"target,omitempty"`
+	StartedAt      string           `json:"startedAt,omitempty"`
+	Quiesced       bool             `json:"quiesced"`
+	AbortRequested bool             `json:"abortRequested"`
+	Steps          []SwitchoverStep `json:"steps,omitempty"`
+}
+
+type SwitchoverStep struct {
+	Number int       `json:"number"`
+	Name   string    `json:"name"`
+	State  stepState `json:"state"`
+}
+
+// switchoverStepNames are the stable names of the steps renderSwitchoverSteps
+// prints, in order.
+var switchoverStepNames = [6]string{
+	"TargetValidated",
+	"QuiesceRequested",
+	"QuiesceInEffect",
+	"TargetCaughtUp",
+	"LeaseHandedOff",
+	"Finalized",
+}
+
+// newStatusReport assembles the report of db, whose failover scope is scope.
+func newStatusReport(db *unstructured.Unstructured, scope *Scope) *StatusReport {
+	ann := db.GetAnnotations()
+	r := &StatusReport{
+		APIVersion: StatusSchemaVersion,
+		Kind:       StatusKind,
+		Database:   DatabaseRef{Kind: db.GetKind(), Namespace: db.GetNamespace(), Name: db.GetName()},
+		Scope:      *scope,
+	}
+	r.Database.Phase, _, _ = unstructured.NestedString(db.Object, "status", "phase")
+	r.ActiveDC, _, _ = unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+	r.DRPhase, _, _ = unstructured.NestedString(db.Object, "status", "disasterRecovery", "phase")
+	if protected, found, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected"); found {
+		r.Protection = &ProtectionStatus{Protected: protected}
+		r.Protection.Message, _, _ = unstructured.NestedString(db.Object, "status", "disasterRecovery", "protectionMessage")
+	}
+
+	dcs, _, _ := unstructured.NestedSlice(db.Object, "status", "disasterRecovery", "dataCenters")
+	r.DataCenters = []DataCenterStatus{}
+	for _, d := range dcs {
+		dm, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		dc := DataCenterStatus{}
+		dc.Name, _ = dm["clusterName"].(string)
+		dc.Role, _ = dm["role"].(string)
+		dc.Streamer, _ = dm["crossDCStreamer"].(string)
+		if v, ok := dm["writable"].(bool); ok {
+			dc.Writable = &v
+		}
+		if v, ok := dm["healthy"].(bool); ok {
+			dc.Healthy = &v
+		}
+		if v, ok := toInt64(dm["lagBytes"]); ok {
+			dc.LagBytes = &v
+		}
+		r.DataCenters = append(r.DataCenters, dc)
+	}
+
+	target := ann[AnnSwitchoverTo]
+	quiesced := ann[AnnQuiesceActive] == "true"
+	aborting := ann[AnnSwitchoverAbort] != ""
+	if target != "" || quiesced || aborting {
+		r.Switchover = &SwitchoverStatus{
+			Target:         target,
+			StartedAt:      ann[AnnSwitchoverStart],
+			Quiesced:       quiesced,
+			AbortRequested: aborting,
+		}
+		if !aborting {
+			p := computeSwitchoverProgress(target, r.ActiveDC, quiesced, dcs)
+			for i, s := range p.steps {
+				r.Switchover.Steps = append(r.Switchover.Steps, SwitchoverStep{Number: i + 1, Name: switchoverStepNames[i], State: s})
+			}
+		}
+	}
+	return r
+}
+
+// printStatusReport writes r as json or yaml.
+func printStatusReport(out io.Writer, r *StatusReport, format string) error {
+	var b []byte
+	var err error
+	switch format {
+	case "json":
+		b, err = json.MarshalIndent(r, "", "  ")
+		b = append(b, '\n')
+	case "yaml":
+		b, err = yaml.Marshal(r)
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: json|yaml", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(b)
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func switchoverFixture(ann map[string]string, activeDC string, dcs ...map[string]any) *unstructured.Unstructured {
+	items := make([]any, 0, len(dcs))
+	for _, dc := range dcs {
+		items = append(items, dc)
+	}
+	db := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "kubedb.com/v1",
+		"kind":       "Postgres",
+		"metadata":   map[string]any{"name": "pg-dcdr", "namespace": "demo"},
+		"status": map[string]any{
+			"phase": "Ready",
+			"disasterRecovery": map[string]any{
+				"activeDC":    activeDC,
+				"phase":       "Switchover",
+				"dataCenters": items,
+			},
+		},
+	}}
+	db.SetAnnotations(ann)
+	return db
+}
+
+// TestStatusTextAndJSONStepsAgree renders each fixture as text and as json and
+// checks both report the same state for every switchover step.
+func TestStatusTextAndJSONStepsAgree(t *testing.T) {
+	dcA := func(healthy bool, lag any) map[string]any {
+		return map[string]any{"clusterName": "dc-a", "role": "Standby", "writable": false, "healthy": healthy, "lagBytes": lag}
+	}
+	dcB := func(writable bool) map[string]any {
+		return map[string]any{"clusterName": "dc-b", "role": "Primary", "writable": writable, "healthy": true, "lagBytes": int64(0)}
+	}
+	cases := []struct {
+		name string
+		db   *unstructured.Unstructured
+	}{
+		{"requested", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a"}, "dc-b", dcA(true, int64(40960)), dcB(true))},
+		{"target unhealthy", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a"}, "dc-b", dcA(false, nil), dcB(true))},
+		{"quiescing", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a", AnnQuiesceActive: "true"}, "dc-b", dcA(true, int64(40960)), dcB(true))},
+		{"catching up", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a", AnnQuiesceActive: "true"}, "dc-b", dcA(true, int64(40960)), dcB(false))},
+		{"caught up", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a", AnnQuiesceActive: "true"}, "dc-b", dcA(true, int64(512)), dcB(false))},
+		{"handed off", switchoverFixture(map[string]string{AnnSwitchoverTo: "dc-a", AnnQuiesceActive: "true"}, "dc-a", dcA(true, int64(0)), dcB(false))},
+	}
+	textStep := regexp.MustCompile(`^  (\[[^\]]+\])\s+(\d)\. `)
+	markState := map[string]string{"[done]": "Done", "[NOW]": "Current", "[pending]": "Pending", "[n/a]": "Skipped"}
+	for _, c := range cases {
+		// the inputs dc-dr status reads for its text output
+		ann := c.db.GetAnnotations()
+		activeDC, _, _ := unstructured.NestedString(c.db.Object, "status", "disasterRecovery", "activeDC")
+		dcs, _, _ := unstructured.NestedSlice(c.db.Object, "status", "disasterRecovery", "dataCenters")
+		var text bytes.Buffer
+		renderSwitchoverSteps(&text, ann[AnnSwitchoverTo], activeDC, ann[AnnQuiesceActive] == "true", dcs)
+		var textStates []string
+		for _, line := range bytes.Split(text.Bytes(), []byte("\n")) {
+			if m := textStep.FindSubmatch(line); m != nil {
+				textStates = append(textStates, markState[string(m[1])])
+			}
+		}
+
+		var js bytes.Buffer
+		if err := printStatusReport(&js, newStatusReport(c.db, &Scope{LeaseName: GlobalPrimaryLease}), "json"); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var report struct {
+			Switchover *struct {
+				Steps []struct {
+					Number int    `json:"number"`
+					Name   string `json:"name"`
+					State  string `json:"state"`
+				} `json:"steps"`
+			} `json:"switchover"`
+		}
+		if err := json.Unmarshal(js.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if report.Switchover == nil {
+			t.Errorf("%s: json has no switchover", c.name)
+			continue
+		}
+		if len(textStates) != 6 || len(report.Switchover.Steps) != 6 {
+			t.Errorf("%s: text has %d steps, json %d; want 6 each\n%s", c.name, len(textStates), len(report.Switchover.Steps), text.String())
+			continue
+		}
+		for i, s := range report.Switchover.Steps {
+			if s.Number != i+1 || s.Name != switchoverStepNames[i] {
+				t.Errorf("%s: json step %d is %d %s, want %d %s", c.name, i, s.Number, s.Name, i+1, switchoverStepNames[i])
+			}
+			if s.State != textStates[i] {
+				t.Errorf("%s: step %d (%s) is %s in json but %s in text", c.name, i+1, s.Name, s.State, textStates[i])
+			}
+		}
+	}
+}
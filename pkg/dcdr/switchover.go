@@ -32,11 +32,15 @@ const mergePatchType = types.MergePatchType
 // database. Acts on the HUB cluster (ordinary kubeconfig flags).
 func NewCmdSwitchover(f cmdutil.Factory) *cobra.Command {
 	var to string
+	var dryRun, preflight, force, yes bool
+	var reason string
+	var cf CoordFlags
+	var sf SpokeFlags
 	cmd := &cobra.Command{
-		Use:   "switchover DB_NAME --to DC",
+		Use:   "switchover [KIND/]DB_NAME --to DC",
 		Short: i18n.T("Trigger a planned zero-RPO switchover of a distributed database to another data center"),
 		Long: templates.LongDesc(`
-			Sets the dr.kubedb.com/switchover-to annotation on the Postgres. The hub
+			Sets the dr.kubedb.com/switchover-to annotation on the database. The hub
 			operator then quiesces the active primary (write-locked), waits for the
 			target data center to catch up to the frozen LSN, hands off the primary-DC
 			Lease, and clears the annotation. Requires the active primary to be up and
@@ -45,10 +49,33 @@ func NewCmdSwitchover(f cmdutil.Factory) *cobra.Command {
 			path instead: dc-dr handoff, and dc-dr accept-data-loss if the RPO budget
 			holds it).
 
-			KUBECONFIG: the hub cluster (where the Postgres CR lives).`),
+			--dry-run and --preflight evaluate, before anything is written, every
+			gate the operator will check: target healthy, target lag known and
+			within dr.kubedb.com/switchover-max-lag-bytes, active primary reachable
+			and writable, the scope's Lease fresh and neither pinned nor mid-handoff,
+			the target's agent alive, the target not standby-held (read from its
+			spoke with --spoke-kubeconfig), and no competing switchover or
+			OpsRequest.
+			--dry-run only reports. --preflight switches over when no gate blocks,
+			and refuses otherwise unless overridden with --force --yes; the
+			operator still enforces its gates, so a forced switchover may simply
+			stall until they clear (or auto-abort).
+
+			--reason is required (except with --dry-run) and recorded in the
+			audit trail; see dc-dr history.
+
+			KUBECONFIG: the hub cluster (where the database CR lives). Only engines
+			whose operator implements the switchover annotation contract (today
+			Postgres) accept it; the others are moved with dc-dr handoff.`),
 		Example: templates.Examples(`
 			# Move demo/pg-dcdr to data center dc-a, with zero data loss
-			kubectl dba dc-dr switchover pg-dcdr -n demo --to dc-a
+			kubectl dba dc-dr switchover pg-dcdr -n demo --to dc-a --reason "dc-b maintenance"
+
+			# Check every gate first, without changing anything
+			kubectl dba dc-dr switchover pg-dcdr -n demo --to dc-a --dry-run
+
+			# Switch over only if every gate passes
+			kubectl dba dc-dr switchover pg-dcdr -n demo --to dc-a --preflight --reason "dc-b maintenance"
 
 			# Watch the progress (one-shot, run repeatedly)
 			kubectl dba dc-dr status pg-dcdr -n demo`),
@@ -60,11 +87,25 @@ func NewCmdSwitchover(f cmdutil.Factory) *cobra.Command {
 			if to == "" {
 				return fmt.Errorf("--to is required (the target data center)")
 			}
+			if dryRun && preflight {
+				return fmt.Errorf("--dry-run and --preflight are mutually exclusive")
+			}
+			if (force || yes) && !preflight {
+				return fmt.Errorf("--force and --yes only apply with --preflight")
+			}
+			if !dryRun {
+				if err := requireReason(reason); err != nil {
+					return err
+				}
+			}
 			ctx := context.Background()
 			db, dyn, ns, err := getDB(ctx, f, args[0])
 			if err != nil {
 				return err
 			}
+			if err := requireAnnotationContract(db, "switchover"); err != nil {
+				return err
+			}
 			requireDistributed(printfErr(cmd), db)
 
 			scope, err := ResolveScopeForDB(ctx, dyn, db)
@@ -74,17 +115,43 @@ func NewCmdSwitchover(f cmdutil.Factory) *cobra.Command {
 			if len(scope.MemberDCs) > 0 && !slices.Contains(scope.MemberDCs, to) {
 				return fmt.Errorf("%q is not a Member data center of this database (members: %v); an Arbiter or Witness DC can never become primary", to, scope.MemberDCs)
 			}
-			if active, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC"); active == to {
-				cmd.Printf("No-op: %s is already the active data center of %s/%s.\n", to, ns, args[0])
+			active, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+			if active == to {
+				cmd.Printf("No-op: %s is already the active data center of %s/%s.\n", to, ns, db.GetName())
 				return nil
 			}
-			if err := annotateDB(ctx, dyn, ns, args[0], AnnSwitchoverTo, &to); err != nil {
+			command := "switchover"
+			if dryRun || preflight {
+				findings := switchoverPreflight(ctx, f, &cf, &sf, dyn, db, scope, to)
+				cmd.Printf("Preflight for the switchover of %s/%s to %q (scope %s):\n\n", ns, db.GetName(), to, scope.LeaseName)
+				printFindings(cmd.OutOrStdout(), findings)
+				blockers := countBlockers(findings)
+				switch {
+				case dryRun && blockers > 0:
+					return fmt.Errorf("dry run: the switchover would be refused by %d blocking condition(s); nothing was changed", blockers)
+				case dryRun:
+					cmd.Printf("Dry run: every gate passes, the switchover would proceed; nothing was changed.\n")
+					return nil
+				case blockers > 0 && !force:
+					return fmt.Errorf("refusing to switch over: %d blocking condition(s); fix them, or override with --force --yes", blockers)
+				case blockers > 0 && !yes:
+					return fmt.Errorf("--force overrides %d blocking condition(s) that the operator still enforces; re-run with --force --yes to confirm", blockers)
+				case blockers > 0:
+					cmd.Printf("Overriding %d blocking condition(s) (--force --yes).\n\n", blockers)
+					command = fmt.Sprintf("switchover --force (%d blocker(s) overridden)", blockers)
+				}
+			}
+			if err := annotateDB(ctx, dyn, db, AnnSwitchoverTo, &to); err != nil {
 				return err
 			}
-			cmd.Printf("Switchover of %s/%s to %q requested (scope %s, from %s).\n", ns, args[0], to, scope.LeaseName, scope.Source)
+			recordAudit(ctx, f, cf.LeaseNS, printfErr(cmd), AuditRecord{
+				Command: command, Database: dbAuditRef(db), Scope: scope.LeaseName,
+				Previous: active, New: to, Reason: reason,
+			})
+			cmd.Printf("Switchover of %s/%s to %q requested (scope %s, from %s).\n", ns, db.GetName(), to, scope.LeaseName, scope.Source)
 			cmd.Printf("The operator will quiesce, wait for catch-up, and hand off; zero committed rows are lost.\n")
 			cmd.Printf("Monitor:  kubectl dba dc-dr status %s -n %s\n", args[0], ns)
-			cmd.Printf("Abort:    kubectl dba dc-dr abort %s -n %s\n", args[0], ns)
+			cmd.Printf("Abort:    kubectl dba dc-dr abort %s -n %s --reason <why>\n", args[0], ns)
 			if scope.LeaseName == GlobalPrimaryLease {
 				cmd.Printf("NOTE: this database follows the GLOBAL scope; every database in that scope switches with it.\n")
 			}
@@ -92,6 +159,13 @@ func NewCmdSwitchover(f cmdutil.Factory) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&to, "to", "", "Target data center (must be a Member DC of the database's PlacementPolicy)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Evaluate every switchover gate and report, without changing anything")
+	cmd.Flags().BoolVar(&preflight, "preflight", false, "Evaluate every switchover gate first and switch over only if none blocks")
+	cmd.Flags().BoolVar(&force, "force", false, "With --preflight, switch over despite blocking conditions (needs --yes)")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Acknowledge the blocking conditions overridden by --force")
+	AddReasonFlag(cmd, &reason)
+	AddCoordFlags(cmd, &cf)
+	AddSpokeFlags(cmd, &sf)
 	return cmd
 }
 
@@ -0,0 +1,278 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dcdr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The terminal states `dc-dr wait --for` accepts.
+const (
+	WaitSwitchoverComplete = "switchover-complete"
+	WaitSteady             = "steady"
+	WaitProtected          = "protected"
+)
+
+// Exit codes of `dc-dr wait`, so a pipeline can branch on the outcome. 0 means
+// the condition was reached; 1 stays the generic error of every command.
+const (
+	WaitExitAborted     = 2
+	WaitExitTimedOut    = 3
+	WaitExitGateFailing = 4
+)
+
+// ExitError is an error that asks the process to exit with Code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+func (e *ExitError) Unwrap() error { return e.Err }
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// NewCmdWait blocks until a database reaches a DC-DR terminal state. It is the
+// scripting counterpart of the deliberately one-shot status command, and shares
+// its switchover step computation.
+func NewCmdWait(f cmdutil.Factory) *cobra.Command {
+	var forState string
+	var timeout, interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "wait [KIND/]DB_NAME --for (switchover-complete | steady | protected)",
+		Short: i18n.T("Block until a switchover or failover reaches a terminal state"),
+		Long: templates.LongDesc(`
+			Polls the database's DC-DR status and annotations and returns once the
+			requested state is reached, printing one line for every switchover step
+			or DR phase transition it observes on the way:
+
+			  switchover-complete  the in-flight switchover handed off to its
+			                       target, cleared its annotations and is Steady
+			  steady               DR phase Steady with no switchover in flight
+			  protected            status.disasterRecovery.protected is true
+
+			The step states are computed exactly as "dc-dr status" renders them.
+			A failed read of the database (a timeout, throttling, a server error)
+			is retried until --timeout; only NotFound and Forbidden end the wait.
+
+			Exit codes: 0 reached, 2 the switchover was aborted (explicitly or by
+			its own timeout), 3 --timeout expired, 4 a gate is failing (the
+			ForceFailOver retry cap tripped or the promotion is reported stalled),
+			1 any other error.
+
+			KUBECONFIG: the hub cluster.`),
+		Example: templates.Examples(`
+			# Trigger a switchover and block until it is done
+			kubectl dba dc-dr switchover pg-dcdr -n demo --to dc-a --reason "dc-b maintenance"
+			kubectl dba dc-dr wait pg-dcdr -n demo --for switchover-complete --timeout 10m
+
+			# Wait for an unplanned failover to settle
+			kubectl dba dc-dr wait pg-dcdr -n demo --for steady`),
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("exactly one database name is required")
+			}
+			switch forState {
+			case WaitSwitchoverComplete, WaitSteady, WaitProtected:
+			default:
+				return fmt.Errorf("--for must be one of: %s|%s|%s", WaitSwitchoverComplete, WaitSteady, WaitProtected)
+			}
+			ctx := context.Background()
+			db, dyn, _, err := getDB(ctx, f, args[0])
+			if err != nil {
+				return err
+			}
+			return pollUntil(ctx, dyn, db, &waiter{out: cmd.OutOrStdout(), forState: forState}, timeout, interval)
+		},
+	}
+	cmd.Flags().StringVar(&forState, "for", WaitSwitchoverComplete, "State to wait for. One of: switchover-complete|steady|protected")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "Give up after this long (exit code 3)")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "How often to re-read the database")
+	return cmd
+}
+
+// pollUntil re-reads db every interval and hands it to w until w ends the wait
+// or timeout expires. A failed read is retried until the deadline unless the
+// database is gone or the caller may not read it.
+func pollUntil(ctx context.Context, dyn dynamic.Interface, db *unstructured.Unstructured, w *waiter, timeout, interval time.Duration) error {
+	e, err := engineFor(db)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	var readErr error
+	for {
+		if readErr == nil {
+			done, err := w.observe(db)
+			if done || err != nil {
+				return err
+			}
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("timed out after %s waiting for %s on %s/%s", timeout, w.forState, db.GetNamespace(), db.GetName())
+			if readErr != nil {
+				err = fmt.Errorf("%w; last read failed: %v", err, readErr)
+			}
+			return &ExitError{Code: WaitExitTimedOut, Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted while waiting for %s on %s/%s: %w", w.forState, db.GetNamespace(), db.GetName(), ctx.Err())
+		case <-time.After(interval):
+		}
+		cur, err := dyn.Resource(e.GVR).Namespace(db.GetNamespace()).Get(ctx, db.GetName(), metav1.GetOptions{})
+		switch {
+		case err == nil:
+			db, readErr = cur, nil
+		case kerr.IsNotFound(err), kerr.IsForbidden(err):
+			return err
+		default:
+			if readErr == nil {
+				w.logf("failed to read %s/%s, retrying: %v", db.GetNamespace(), db.GetName(), err)
+			}
+			readErr = err
+		}
+	}
+}
+
+// waiter remembers what it has already printed, so each transition is reported
+// once.
+type waiter struct {
+	out      io.Writer
+	forState string
+
+	target    string
+	steps     [6]stepState
+	stepsSeen bool
+	phase     string
+	phaseSeen bool
+	protected *bool
+	aborting  bool
+}
+
+func (w *waiter) logf(format string, a ...any) {
+	_, _ = fmt.Fprintf(w.out, "%s  %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, a...))
+}
+
+// observe reports transitions in db since the previous call and whether the
+// wait is over; a non-nil error ends it with that error's exit code.
+func (w *waiter) observe(db *unstructured.Unstructured) (bool, error) {
+	ann := db.GetAnnotations()
+	activeDC, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "activeDC")
+	drPhase, _, _ := unstructured.NestedString(db.Object, "status", "disasterRecovery", "phase")
+	protected, protectedSet, _ := unstructured.NestedBool(db.Object, "status", "disasterRecovery", "protected")
+	dcs, _, _ := unstructured.NestedSlice(db.Object, "status", "disasterRecovery", "dataCenters")
+
+	target := ann[AnnSwitchoverTo]
+	quiesced := ann[AnnQuiesceActive] == "true"
+	aborting := ann[AnnSwitchoverAbort] != ""
+	inFlight := target != "" || quiesced || aborting
+	if target != "" {
+		w.target = target
+	}
+
+	if !w.phaseSeen || drPhase != w.phase {
+		if w.phaseSeen {
+			w.logf("DR phase %s -> %s (active DC %s)", orNone(w.phase), orNone(drPhase), orNone(activeDC))
+		} else {
+			w.logf("DR phase %s (active DC %s)", orNone(drPhase), orNone(activeDC))
+		}
+		w.phase, w.phaseSeen = drPhase, true
+	}
+	if protectedSet && (w.protected == nil || *w.protected != protected) {
+		w.logf("protected=%v", protected)
+		w.protected = &protected
+	}
+	if aborting && !w.aborting {
+		w.logf("abort requested (%s is set); the hub is restoring writes to %s", AnnSwitchoverAbort, orNone(activeDC))
+		w.aborting = true
+	}
+	if inFlight && !aborting {
+		w.logSteps(computeSwitchoverProgress(w.target, activeDC, quiesced, dcs).steps)
+	}
+
+	if gate, msg := failingGate(db); gate != "" {
+		return true, &ExitError{Code: WaitExitGateFailing, Err: fmt.Errorf("gate failing on %s/%s: %s: %s", db.GetNamespace(), db.GetName(), gate, msg)}
+	}
+
+	switch w.forState {
+	case WaitSteady:
+		return drPhase == "Steady" && !inFlight, nil
+	case WaitProtected:
+		return protectedSet && protected, nil
+	}
+	if inFlight {
+		return false, nil
+	}
+	if w.target == "" {
+		return true, fmt.Errorf("no switchover is in flight on %s/%s; start one with dc-dr switchover first", db.GetNamespace(), db.GetName())
+	}
+	if activeDC != w.target {
+		return true, &ExitError{Code: WaitExitAborted, Err: fmt.Errorf("the switchover to %q was aborted; %s is still the active data center", w.target, orNone(activeDC))}
+	}
+	if drPhase != "" && drPhase != "Steady" {
+		return false, nil
+	}
+	w.logSteps([6]stepState{stepDone, stepDone, stepDone, stepDone, stepDone, stepDone})
+	w.logf("switchover to %q complete", w.target)
+	return true, nil
+}
+
+func (w *waiter) logSteps(steps [6]stepState) {
+	for i, s := range steps {
+		if w.stepsSeen && w.steps[i] == s {
+			continue
+		}
+		if w.stepsSeen {
+			w.logf("step %d/6 %s: %s -> %s", i+1, switchoverStepNames[i], w.steps[i], s)
+		} else {
+			w.logf("step %d/6 %s: %s", i+1, switchoverStepNames[i], s)
+		}
+	}
+	w.steps, w.stepsSeen = steps, true
+}
+
+// failingGate returns the first condition that means the state being waited for
+// will not be reached without a human: the retry cap or a stalled promotion.
+func failingGate(db *unstructured.Unstructured) (string, string) {
+	conds, _, _ := unstructured.NestedSlice(db.Object, "status", "conditions")
+	for _, c := range conds {
+		cm, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		typ, _ := cm["type"].(string)
+		status, _ := cm["status"].(string)
+		msg, _ := cm["message"].(string)
+		if status == "True" && (typ == "ForceFailOverRetryCapReached" || typ == "DCDRPromotionStalled") {
+			return typ, msg
+		}
+	}
+	return "", ""
+}
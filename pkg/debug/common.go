@@ -266,13 +266,8 @@ func (opts *dbOpts) collectOtherYamls() error {
 		}
 	}
 
-	var autoscalers unstructured.UnstructuredList
-	autoscalers.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   autoscalerapi.SchemeGroupVersion.Group,
-		Version: autoscalerapi.SchemeGroupVersion.Version,
-		Kind:    opts.kind + "Autoscaler",
-	})
-	if err := opts.kc.List(context.Background(), &autoscalers, client.InNamespace(opts.db.GetNamespace())); err != nil {
+	autoscalers, err := ListAutoscalers(opts.kc, opts.kind, opts.db)
+	if err != nil {
 		return err
 	}
 
@@ -281,17 +276,36 @@ func (opts *dbOpts) collectOtherYamls() error {
 	if err != nil {
 		return err
 	}
+	for _, sc := range autoscalers {
+		err = writeYaml(&sc, scalerYamlDir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAutoscalers returns the <kind>Autoscaler objects referring to db.
+func ListAutoscalers(kc client.Client, kind string, db metav1.ObjectMeta) ([]unstructured.Unstructured, error) {
+	var autoscalers unstructured.UnstructuredList
+	autoscalers.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   autoscalerapi.SchemeGroupVersion.Group,
+		Version: autoscalerapi.SchemeGroupVersion.Version,
+		Kind:    kind + "Autoscaler",
+	})
+	if err := kc.List(context.Background(), &autoscalers, client.InNamespace(db.GetNamespace())); err != nil {
+		return nil, err
+	}
+
+	var found []unstructured.Unstructured
 	for _, sc := range autoscalers.Items {
 		var autoscaler Autoscaler
 		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(sc.Object, &autoscaler); err != nil {
-			return fmt.Errorf("failed to unmarshal binding %s: %w", sc.GetName(), err)
+			return nil, fmt.Errorf("failed to unmarshal binding %s: %w", sc.GetName(), err)
 		}
-		if autoscaler.Spec.DatabaseRef.Name == opts.db.GetName() {
-			err = writeYaml(&sc, scalerYamlDir)
-			if err != nil {
-				return err
-			}
+		if autoscaler.Spec.DatabaseRef.Name == db.GetName() {
+			found = append(found, sc)
 		}
 	}
-	return nil
+	return found, nil
 }
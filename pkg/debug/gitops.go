@@ -120,6 +120,16 @@ func (g *gitOpsOpts) collectGitOpsDatabase() error {
 
 	g.summary = append(g.summary, fmt.Sprintf("GitOps Database Status for: %s/%s is %s", g.db.namespace, g.db.name, status))
 
+	if entry, ok := lookupDatabase(g.db.kind); ok {
+		// Drift is best effort: without it the status and OpsRequests are still
+		// worth collecting.
+		if report, err := computeDrift(g.kc, entry.gvk, &uns); err != nil {
+			g.summary = append(g.summary, fmt.Sprintf("WARNING: could not compute the GitOps drift of %s/%s: %v", g.db.namespace, g.db.name, err))
+		} else {
+			g.summary = append(g.summary, report.Summary()...)
+		}
+	}
+
 	if err := g.collectOpsRequests(gitOpsObj.Status); err != nil {
 		return err
 	}
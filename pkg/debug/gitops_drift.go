@@ -0,0 +1,369 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	gitops "kubedb.dev/apimachinery/apis/gitops/v1alpha1"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Drift states. A supported drift carries the phase of the OpsRequest that is
+// meant to fix it.
+const (
+	DriftUnsupported  = "Unsupported"
+	DriftNoOpsRequest = "NoOpsRequest"
+	DriftPending      = "Pending"
+	DriftProgressing  = "Progressing"
+	DriftFailed       = "Failed"
+	// DriftUnreconciled means the OpsRequest succeeded but the live spec
+	// still differs from the desired one.
+	DriftUnreconciled = "Unreconciled"
+)
+
+// driftRules maps a spec field path (without the leading "spec.") to the
+// OpsRequest type the GitOps operator creates for it. Fields that match no
+// rule can not be changed by any OpsRequest and are never reconciled.
+var driftRules = []struct {
+	path    *regexp.Regexp
+	opsType string
+}{
+	{regexp.MustCompile(`^version$`), "UpdateVersion"},
+	{regexp.MustCompile(`(^|\.)(replicas|shards)$`), "HorizontalScaling"},
+	{regexp.MustCompile(`(^|\.)podTemplate\.spec\.(containers|initContainers)\[[^\]]+\]\.resources(\.|$)`), "VerticalScaling"},
+	{regexp.MustCompile(`(^|\.)storage\.resources\.requests\.storage$`), "VolumeExpansion"},
+	{regexp.MustCompile(`(^|\.)(configSecret|configuration)(\.|$)`), "Reconfigure"},
+	{regexp.MustCompile(`^tls(\.|$)`), "ReconfigureTLS"},
+	{regexp.MustCompile(`^authSecret(\.|$)`), "RotateAuth"},
+}
+
+// Drift is a single spec field whose desired value in the GitOps object
+// differs from the live database.
+type Drift struct {
+	Path       string `json:"path"`
+	Desired    string `json:"desired"`
+	Live       string `json:"live"`
+	OpsType    string `json:"opsType,omitempty"`
+	State      string `json:"state"`
+	OpsRequest string `json:"opsRequest,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+type DriftReport struct {
+	Kind      string  `json:"kind"`
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Drifts    []Drift `json:"drifts"`
+}
+
+type opsInfo struct {
+	name    string
+	opsType string
+	phase   opsapi.OpsRequestPhase
+	message string
+}
+
+// GitOpsDrift compares the GitOps object of the database named by ref
+// (<kind>/<name>) with the live database.
+func GitOpsDrift(f cmdutil.Factory, ref string) (*DriftReport, error) {
+	kindName, name, ok := strings.Cut(ref, "/")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("expected <kind>/<name>, got %q", ref)
+	}
+	entry, ok := lookupDatabase(kindName)
+	if !ok {
+		return nil, fmt.Errorf("unknown database kind %q", kindName)
+	}
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	kc, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	var gitOpsObj unstructured.Unstructured
+	gitOpsObj.SetGroupVersionKind(gitops.SchemeGroupVersion.WithKind(entry.gvk.Kind))
+	if err := kc.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, &gitOpsObj); err != nil {
+		return nil, fmt.Errorf("failed to get GitOps %s %s/%s: %w", entry.gvk.Kind, namespace, name, err)
+	}
+	return computeDrift(kc, entry.gvk, &gitOpsObj)
+}
+
+func computeDrift(kc client.Client, gvk schema.GroupVersionKind, gitOpsObj *unstructured.Unstructured) (*DriftReport, error) {
+	var live unstructured.Unstructured
+	live.SetGroupVersionKind(gvk)
+	if err := kc.Get(context.TODO(), client.ObjectKeyFromObject(gitOpsObj), &live); err != nil {
+		return nil, fmt.Errorf("failed to get %s %s/%s: %w", gvk.Kind, gitOpsObj.GetNamespace(), gitOpsObj.GetName(), err)
+	}
+
+	var status GitOps
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(gitOpsObj.Object, &status); err != nil {
+		return nil, err
+	}
+	report := &DriftReport{
+		Kind:      gvk.Kind,
+		Namespace: gitOpsObj.GetNamespace(),
+		Name:      gitOpsObj.GetName(),
+		Status:    string(gitops.ChangeRequestStatusInCurrent),
+	}
+	infos := status.Status.GitOps.GitOpsInfo
+	if len(infos) > 0 {
+		report.Status = string(infos[len(infos)-1].ChangeRequestStatus)
+	}
+	ops := getGitOpsOpsRequests(kc, gvk.Kind, gitOpsObj.GetNamespace(), infos)
+
+	desired, _, _ := unstructured.NestedMap(gitOpsObj.Object, "spec")
+	current, _, _ := unstructured.NestedMap(live.Object, "spec")
+	for _, d := range diffFields("", desired, current) {
+		d.State = DriftUnsupported
+		for _, rule := range driftRules {
+			if rule.path.MatchString(d.Path) {
+				d.OpsType = rule.opsType
+				break
+			}
+		}
+		if d.OpsType != "" {
+			d.State = DriftNoOpsRequest
+			// ops is ordered newest first
+			for _, op := range ops {
+				if op.opsType != d.OpsType {
+					continue
+				}
+				d.OpsRequest = op.name
+				d.Message = op.message
+				switch op.phase {
+				case opsapi.OpsRequestPhaseFailed:
+					d.State = DriftFailed
+				case opsapi.OpsRequestPhaseSuccessful, opsapi.OpsRequestPhaseSkipped:
+					d.State = DriftUnreconciled
+				case opsapi.OpsRequestPhaseProgressing:
+					d.State = DriftProgressing
+				default:
+					d.State = DriftPending
+				}
+				break
+			}
+		}
+		d.Path = "spec." + d.Path
+		report.Drifts = append(report.Drifts, d)
+	}
+	return report, nil
+}
+
+// getGitOpsOpsRequests returns the OpsRequests recorded in the GitOps status,
+// newest first. OpsRequests that were already garbage collected are skipped.
+func getGitOpsOpsRequests(kc client.Client, kind, namespace string, infos []gitops.GitOpsInfo) []opsInfo {
+	var ops []opsInfo
+	for i := len(infos) - 1; i >= 0; i-- {
+		for j := len(infos[i].Operations) - 1; j >= 0; j-- {
+			var uns unstructured.Unstructured
+			uns.SetGroupVersionKind(opsapi.SchemeGroupVersion.WithKind(kind + "OpsRequest"))
+			err := kc.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: infos[i].Operations[j].Name}, &uns)
+			if err != nil {
+				continue
+			}
+			op := opsInfo{name: uns.GetName()}
+			op.opsType, _, _ = unstructured.NestedString(uns.Object, "spec", "type")
+			phase, _, _ := unstructured.NestedString(uns.Object, "status", "phase")
+			op.phase = opsapi.OpsRequestPhase(phase)
+			var o Ops
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(uns.Object, &o); err == nil && o.Status != nil {
+				for _, cond := range o.Status.Conditions {
+					if cond.Reason == opsapi.Failed {
+						op.message = cond.Message
+					}
+				}
+			}
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// diffFields walks the desired spec and reports every leaf that differs from
+// live. Fields only present in live are defaults filled in by the operator and
+// are not drift. Lists of named objects (e.g. containers) are matched by name.
+func diffFields(prefix string, desired, live any) []Drift {
+	switch d := desired.(type) {
+	case map[string]any:
+		l, _ := live.(map[string]any)
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var out []Drift
+		for _, k := range keys {
+			out = append(out, diffFields(joinPath(prefix, k), d[k], l[k])...)
+		}
+		return out
+	case []any:
+		l, _ := live.([]any)
+		if byName, ok := namedItems(d); ok {
+			liveByName, _ := namedItems(l)
+			var out []Drift
+			for _, name := range sortedKeys(byName) {
+				out = append(out, diffFields(fmt.Sprintf("%s[%s]", prefix, name), byName[name], liveByName[name])...)
+			}
+			return out
+		}
+	}
+	if equalValue(desired, live) {
+		return nil
+	}
+	return []Drift{{Path: prefix, Desired: renderValue(desired), Live: renderValue(live)}}
+}
+
+func namedItems(items []any) (map[string]any, bool) {
+	out := make(map[string]any, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		out[name] = m
+	}
+	return out, len(out) > 0
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// equalValue compares two leaves; quantities like 1Gi and 1024Mi are equal.
+func equalValue(a, b any) bool {
+	if fmt.Sprint(a) == fmt.Sprint(b) {
+		return true
+	}
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		aq, aerr := resource.ParseQuantity(as)
+		bq, berr := resource.ParseQuantity(bs)
+		return aerr == nil && berr == nil && aq.Cmp(bq) == 0
+	}
+	return false
+}
+
+func renderValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	s := fmt.Sprint(v)
+	if len(s) > 40 {
+		s = s[:37] + "..."
+	}
+	return s
+}
+
+func lookupDatabase(s string) (dbEntry, bool) {
+	s = strings.ToLower(s)
+	for _, entry := range databases {
+		if s == entry.use || s == strings.ToLower(entry.gvk.Kind) {
+			return entry, true
+		}
+		for _, a := range entry.aliases {
+			if s == a {
+				return entry, true
+			}
+		}
+	}
+	return dbEntry{}, false
+}
+
+// Summary returns one line per drift, in the style of the debug summary.
+func (r *DriftReport) Summary() []string {
+	lines := make([]string, 0, len(r.Drifts))
+	for _, d := range r.Drifts {
+		line := fmt.Sprintf("Drift %s: desired %s, live %s [%s]", d.Path, d.Desired, d.Live, d.State)
+		if d.OpsRequest != "" {
+			line += fmt.Sprintf(" fixed by %sOpsRequest %s", r.Kind, d.OpsRequest)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func PrintDriftReport(out io.Writer, r *DriftReport) error {
+	_, _ = fmt.Fprintf(out, "GitOps %s %s/%s: %s\n", r.Kind, r.Namespace, r.Name, r.Status)
+	if len(r.Drifts) == 0 {
+		_, _ = fmt.Fprintln(out, "No drift: the live spec matches the desired spec.")
+		return nil
+	}
+	_, _ = fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FIELD\tDESIRED\tLIVE\tSTATE\tOPSREQUEST")
+	for _, d := range r.Drifts {
+		opsRef := "-"
+		if d.OpsRequest != "" {
+			opsRef = fmt.Sprintf("%s/%s", strings.ToLower(r.Kind)+"opsrequest", d.OpsRequest)
+		} else if d.OpsType != "" {
+			opsRef = fmt.Sprintf("<%s>", d.OpsType)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Path, d.Desired, d.Live, d.State, opsRef)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, d := range r.Drifts {
+		switch d.State {
+		case DriftFailed:
+			_, _ = fmt.Fprintf(out, "\n%s: OpsRequest %s failed: %s\n", d.Path, d.OpsRequest, d.Message)
+		case DriftUnsupported:
+			_, _ = fmt.Fprintf(out, "\n%s: no OpsRequest can change this field; it will never be reconciled. Revert it in Git or recreate the database.\n", d.Path)
+		}
+	}
+	return nil
+}
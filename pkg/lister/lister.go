@@ -0,0 +1,269 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lister
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+var (
+	petSetGVR = schema.GroupVersionResource{Group: "apps.k8s.appscode.com", Version: "v1", Resource: "petsets"}
+	// KubeStash targets the database itself, Stash targets its AppBinding.
+	kubeStashBackupGVR = schema.GroupVersionResource{Group: "core.kubestash.com", Version: "v1alpha1", Resource: "backupconfigurations"}
+	stashBackupGVR     = schema.GroupVersionResource{Group: "stash.appscode.com", Version: "v1beta1", Resource: "backupconfigurations"}
+)
+
+// DBKind is one database kind served by the kubedb.com group, as reported by
+// discovery.
+type DBKind struct {
+	GVR        schema.GroupVersionResource
+	Kind       string
+	Singular   string
+	ShortNames []string
+}
+
+// Matches reports whether s names this kind by Kind, resource, singular or
+// short name, case-insensitively.
+func (k DBKind) Matches(s string) bool {
+	s = strings.ToLower(s)
+	if s == strings.ToLower(k.Kind) || s == k.GVR.Resource || s == k.Singular {
+		return true
+	}
+	for _, n := range k.ShortNames {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Options selects which databases List returns.
+type Options struct {
+	// Namespace is ignored when AllNamespaces is set.
+	Namespace     string
+	AllNamespaces bool
+	Kinds         []string
+	Selector      string
+}
+
+// Lister builds the fleet overview from a discovery client and a dynamic client.
+type Lister struct {
+	disco discovery.DiscoveryInterface
+	dyn   dynamic.Interface
+}
+
+func NewLister(config *rest.Config) (*Lister, error) {
+	disco, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Lister{disco: disco, dyn: dyn}, nil
+}
+
+// DiscoverKinds returns every listable kind the cluster serves under group. A
+// kind served at several versions is reported once, at the group's preferred
+// version when it is served there.
+func (l *Lister) DiscoverKinds(group string) ([]DBKind, error) {
+	groups, err := l.disco.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	var apiGroup *metav1.APIGroup
+	for i := range groups.Groups {
+		if groups.Groups[i].Name == group {
+			apiGroup = &groups.Groups[i]
+			break
+		}
+	}
+	if apiGroup == nil {
+		return nil, fmt.Errorf("the server does not serve the %s API group; is KubeDB installed?", group)
+	}
+
+	versions := []string{apiGroup.PreferredVersion.Version}
+	for _, v := range apiGroup.Versions {
+		if v.Version != apiGroup.PreferredVersion.Version {
+			versions = append(versions, v.Version)
+		}
+	}
+
+	seen := map[string]bool{}
+	var kinds []DBKind
+	for _, version := range versions {
+		resources, err := l.disco.ServerResourcesForGroupVersion(schema.GroupVersion{Group: group, Version: version}.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources.APIResources {
+			// skip subresources like postgreses/status
+			if strings.Contains(r.Name, "/") || seen[r.Kind] || !hasVerb(r.Verbs, "list") {
+				continue
+			}
+			seen[r.Kind] = true
+			kinds = append(kinds, DBKind{
+				GVR:        schema.GroupVersionResource{Group: group, Version: version, Resource: r.Name},
+				Kind:       r.Kind,
+				Singular:   r.SingularName,
+				ShortNames: r.ShortNames,
+			})
+		}
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Kind < kinds[j].Kind })
+	return kinds, nil
+}
+
+func hasVerb(verbs metav1.Verbs, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns one row per database matching opts, sorted by namespace, kind
+// and name.
+func (l *Lister) List(ctx context.Context, opts Options) ([]Row, error) {
+	kinds, err := l.DiscoverKinds(kubedb.GroupName)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Kinds) > 0 {
+		kinds, err = filterKinds(kinds, opts.Kinds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ns := opts.Namespace
+	if opts.AllNamespaces {
+		ns = metav1.NamespaceAll
+	}
+
+	// The OpsRequest group is optional; a cluster without the ops CRDs simply
+	// shows no last OpsRequest.
+	opsKinds, _ := l.DiscoverKinds(opsapi.SchemeGroupVersion.Group)
+
+	var side *sideObjects
+	var rows []Row
+	for _, k := range kinds {
+		list, err := l.dyn.Resource(k.GVR).Namespace(ns).List(ctx, metav1.ListOptions{LabelSelector: opts.Selector})
+		if err != nil {
+			if kerr.IsNotFound(err) || kerr.IsForbidden(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", k.GVR.Resource, err)
+		}
+		if len(list.Items) == 0 {
+			continue
+		}
+		if side == nil {
+			side = l.listSideObjects(ctx, ns)
+		}
+		lc := l.newKindCache(ctx, k, ns, opsKinds, side)
+		for i := range list.Items {
+			rows = append(rows, lc.row(&list.Items[i]))
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		if rows[i].Kind != rows[j].Kind {
+			return rows[i].Kind < rows[j].Kind
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+func filterKinds(kinds []DBKind, names []string) ([]DBKind, error) {
+	var out []DBKind
+	for _, name := range names {
+		found := false
+		for _, k := range kinds {
+			if k.Matches(name) {
+				out = append(out, k)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%q is not a database kind served by this cluster", name)
+		}
+	}
+	return out, nil
+}
+
+// sideObjects holds the side objects every kind shares (PetSets and backup
+// configurations), listed once per List call.
+type sideObjects struct {
+	petsets []unstructured.Unstructured
+	backups []unstructured.Unstructured
+}
+
+func (l *Lister) listSideObjects(ctx context.Context, ns string) *sideObjects {
+	side := &sideObjects{}
+	selector := meta_util.ManagedByLabelKey + "=" + kubedb.GroupName
+	if list, err := l.dyn.Resource(petSetGVR).Namespace(ns).List(ctx, metav1.ListOptions{LabelSelector: selector}); err == nil {
+		side.petsets = list.Items
+	}
+	for _, gvr := range []schema.GroupVersionResource{kubeStashBackupGVR, stashBackupGVR} {
+		if list, err := l.dyn.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{}); err == nil {
+			side.backups = append(side.backups, list.Items...)
+		}
+	}
+	return side
+}
+
+// kindCache holds the side objects of one kind: its OpsRequests, listed once
+// per kind rather than once per database, and the shared sideObjects.
+type kindCache struct {
+	kind DBKind
+	ops  []unstructured.Unstructured
+	side *sideObjects
+}
+
+func (l *Lister) newKindCache(ctx context.Context, k DBKind, ns string, opsKinds []DBKind, side *sideObjects) *kindCache {
+	lc := &kindCache{kind: k, side: side}
+	for _, ok := range opsKinds {
+		if ok.Kind == k.Kind+"OpsRequest" {
+			if list, err := l.dyn.Resource(ok.GVR).Namespace(ns).List(ctx, metav1.ListOptions{}); err == nil {
+				lc.ops = list.Items
+			}
+			break
+		}
+	}
+	return lc
+}
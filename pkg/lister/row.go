@@ -0,0 +1,254 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lister
+
+import (
+	"fmt"
+	"strings"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+// Row is the overview of a single database. The json tags are the stable
+// schema of `kubectl dba list -o json|yaml`.
+type Row struct {
+	Kind           string            `json:"kind"`
+	Namespace      string            `json:"namespace"`
+	Name           string            `json:"name"`
+	Version        string            `json:"version,omitempty"`
+	Mode           string            `json:"mode"`
+	ReadyReplicas  int64             `json:"readyReplicas"`
+	Replicas       int64             `json:"replicas"`
+	Phase          string            `json:"phase,omitempty"`
+	Paused         bool              `json:"paused"`
+	Halted         bool              `json:"halted"`
+	TLS            bool              `json:"tls"`
+	Monitoring     bool              `json:"monitoring"`
+	Backup         string            `json:"backup,omitempty"`
+	LastOpsRequest *OpsSummary       `json:"lastOpsRequest,omitempty"`
+	CreationTime   metav1.Time       `json:"creationTimestamp"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// OpsSummary is the most recent OpsRequest that targets a database.
+type OpsSummary struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Phase        string      `json:"phase,omitempty"`
+	CreationTime metav1.Time `json:"creationTimestamp"`
+}
+
+func (o *OpsSummary) String() string {
+	if o == nil {
+		return "<none>"
+	}
+	phase := o.Phase
+	if phase == "" {
+		phase = "Pending"
+	}
+	return fmt.Sprintf("%s(%s)", o.Type, phase)
+}
+
+// Ready renders the READY column, e.g. 2/3.
+func (r Row) Ready() string {
+	return fmt.Sprintf("%d/%d", r.ReadyReplicas, r.Replicas)
+}
+
+// State renders paused and halted together, since both mean the operator is
+// not acting on the database.
+func (r Row) State() string {
+	var s []string
+	if r.Halted {
+		s = append(s, "Halted")
+	}
+	if r.Paused {
+		s = append(s, "Paused")
+	}
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, ",")
+}
+
+func (lc *kindCache) row(db *unstructured.Unstructured) Row {
+	r := Row{
+		Kind:         lc.kind.Kind,
+		Namespace:    db.GetNamespace(),
+		Name:         db.GetName(),
+		Mode:         mode(db),
+		CreationTime: db.GetCreationTimestamp(),
+		Labels:       db.GetLabels(),
+	}
+	r.Version, _, _ = unstructured.NestedString(db.Object, "spec", "version")
+	r.Phase, _, _ = unstructured.NestedString(db.Object, "status", "phase")
+	r.Halted, _, _ = unstructured.NestedBool(db.Object, "spec", "halted")
+	if isConditionTrue(db, kubedb.DatabaseHalted) {
+		r.Halted = true
+	}
+	r.Paused = isConditionTrue(db, kubedb.DatabasePaused)
+	r.TLS = nonEmpty(db, "spec", "tls")
+	r.Monitoring = nonEmpty(db, "spec", "monitor")
+
+	r.Replicas, r.ReadyReplicas = lc.replicas(db)
+	r.Backup = lc.backup(db)
+	r.LastOpsRequest = lc.lastOps(db)
+	return r
+}
+
+// mode derives a short topology name from the fields the KubeDB CRDs use for
+// it, most specific first.
+func mode(db *unstructured.Unstructured) string {
+	if m, _, _ := unstructured.NestedString(db.Object, "spec", "mode"); m != "" {
+		return m
+	}
+	if m, _, _ := unstructured.NestedString(db.Object, "spec", "topology", "mode"); m != "" {
+		return m
+	}
+	switch {
+	case nonEmpty(db, "spec", "shardTopology"):
+		return "Sharded"
+	case nonEmpty(db, "spec", "replicaSet"):
+		return "ReplicaSet"
+	case nonEmpty(db, "spec", "topology"):
+		return "Topology"
+	}
+	if replicas, found, _ := unstructured.NestedInt64(db.Object, "spec", "replicas"); found && replicas > 1 {
+		return "Cluster"
+	}
+	return "Standalone"
+}
+
+// replicas sums desired and ready replicas over the PetSets owned by db, which
+// also covers topology databases split across several PetSets. It falls back to
+// spec.replicas when no PetSet exists yet.
+func (lc *kindCache) replicas(db *unstructured.Unstructured) (desired, ready int64) {
+	fqn := lc.kind.GVR.Resource + "." + kubedb.GroupName
+	found := false
+	for i := range lc.side.petsets {
+		ps := &lc.side.petsets[i]
+		l := ps.GetLabels()
+		if ps.GetNamespace() != db.GetNamespace() || l[meta_util.InstanceLabelKey] != db.GetName() || l[meta_util.NameLabelKey] != fqn {
+			continue
+		}
+		found = true
+		d, _, _ := unstructured.NestedInt64(ps.Object, "spec", "replicas")
+		r, _, _ := unstructured.NestedInt64(ps.Object, "status", "readyReplicas")
+		desired += d
+		ready += r
+	}
+	if !found {
+		desired, _, _ = unstructured.NestedInt64(db.Object, "spec", "replicas")
+	}
+	return desired, ready
+}
+
+// backup reports how the database is backed up: a continuous archiver, a
+// KubeStash BackupConfiguration targeting it, and/or a Stash one targeting its
+// AppBinding. A target without a namespace is in the BackupConfiguration's.
+func (lc *kindCache) backup(db *unstructured.Unstructured) string {
+	var kinds []string
+	if nonEmpty(db, "spec", "archiver") {
+		kinds = append(kinds, "Archiver")
+	}
+	var kubeStash, stash bool
+	for i := range lc.side.backups {
+		bc := &lc.side.backups[i]
+		var target []string
+		var kind string
+		switch bc.GroupVersionKind().Group {
+		case kubeStashBackupGVR.Group:
+			target, kind = []string{"spec", "target"}, lc.kind.Kind
+		case stashBackupGVR.Group:
+			target, kind = []string{"spec", "target", "ref"}, "AppBinding"
+		default:
+			continue
+		}
+		if k, _, _ := unstructured.NestedString(bc.Object, append(target, "kind")...); k != kind {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(bc.Object, append(target, "name")...)
+		ns, _, _ := unstructured.NestedString(bc.Object, append(target, "namespace")...)
+		if ns == "" {
+			ns = bc.GetNamespace()
+		}
+		if name != db.GetName() || ns != db.GetNamespace() {
+			continue
+		}
+		if kind == "AppBinding" {
+			stash = true
+		} else {
+			kubeStash = true
+		}
+	}
+	if kubeStash {
+		kinds = append(kinds, "KubeStash")
+	}
+	if stash {
+		kinds = append(kinds, "Stash")
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (lc *kindCache) lastOps(db *unstructured.Unstructured) *OpsSummary {
+	var last *OpsSummary
+	for i := range lc.ops {
+		o := &lc.ops[i]
+		if o.GetNamespace() != db.GetNamespace() {
+			continue
+		}
+		if ref, _, _ := unstructured.NestedString(o.Object, "spec", "databaseRef", "name"); ref != db.GetName() {
+			continue
+		}
+		created := o.GetCreationTimestamp()
+		if last != nil && !created.After(last.CreationTime.Time) {
+			continue
+		}
+		typ, _, _ := unstructured.NestedString(o.Object, "spec", "type")
+		phase, _, _ := unstructured.NestedString(o.Object, "status", "phase")
+		last = &OpsSummary{Name: o.GetName(), Type: typ, Phase: phase, CreationTime: created}
+	}
+	return last
+}
+
+func isConditionTrue(obj *unstructured.Unstructured, condType string) bool {
+	conds, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conds {
+		cm, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if cm["type"] == condType {
+			return cm["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+func nonEmpty(obj *unstructured.Unstructured, fields ...string) bool {
+	v, found, _ := unstructured.NestedFieldNoCopy(obj.Object, fields...)
+	if !found || v == nil {
+		return false
+	}
+	if m, ok := v.(map[string]any); ok {
+		return len(m) > 0
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitor
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// AlertmanagerSvc is the Alertmanager service the silence commands
+// port-forward to, like PromSvc is for Prometheus.
+type AlertmanagerSvc struct {
+	Name      string
+	Namespace string
+	Port      int
+}
+
+// AddAlertmanagerFlags registers the flags that locate the Alertmanager
+// service. The defaults match a kube-prometheus-stack installation.
+func AddAlertmanagerFlags(fs *pflag.FlagSet, am *AlertmanagerSvc) {
+	fs.StringVar(&am.Name, "alertmanager-svc-name", "alertmanager-operated", "name of the alertmanager service")
+	fs.StringVar(&am.Namespace, "alertmanager-svc-namespace", "monitoring", "namespace of the alertmanager service")
+	fs.IntVar(&am.Port, "alertmanager-svc-port", 9093, "port of the alertmanager service")
+}
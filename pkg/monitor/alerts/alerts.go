@@ -0,0 +1,295 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+const (
+	StateFiring   = "firing"
+	StatePending  = "pending"
+	StateAll      = "all"
+	StateResolved = "resolved"
+
+	severityCritical = "critical"
+
+	alertStateLabel model.LabelName = "alertstate"
+)
+
+// Alert is one alert instance of the database seen within the --since window.
+type Alert struct {
+	Name        string            `json:"name"`
+	State       string            `json:"state"`
+	Severity    string            `json:"severity,omitempty"`
+	Summary     string            `json:"summary,omitempty"`
+	Description string            `json:"description,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	EndedAt     *time.Time        `json:"endedAt,omitempty"`
+	Duration    string            `json:"duration"`
+	Labels      map[string]string `json:"labels"`
+}
+
+func (opts *dbOpts) work(promAPI promv1.API, alertOpts Options) ([]Alert, error) {
+	stateMatcher := StateFiring
+	switch alertOpts.State {
+	case StateFiring, "":
+	case StatePending:
+		stateMatcher = StatePending
+	case StateAll:
+		stateMatcher = StateFiring + "|" + StatePending
+	default:
+		return nil, fmt.Errorf("unknown state %q, must be one of: firing|pending|all", alertOpts.State)
+	}
+	since := alertOpts.Since
+	if since <= 0 {
+		since = time.Hour
+	}
+
+	alertQuery := fmt.Sprintf("ALERTS{alertstate=~\"%s\",k8s_group=\"kubedb.com\",k8s_resource=\"%s\",app=\"%s\",app_namespace=\"%s\"}",
+		stateMatcher, opts.resource, opts.db.GetName(), opts.db.GetNamespace())
+	now := time.Now()
+	result, warnings, err := promAPI.QueryRange(context.TODO(), alertQuery, promv1.Range{
+		Start: now.Add(-since),
+		End:   now,
+		Step:  queryStep(since),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Warnings:", warnings)
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s for alert query", result.Type())
+	}
+
+	rules, err := promAPI.Rules(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerting rules: %w", err)
+	}
+
+	// The rules API has the final word on an alert's state, so an instance the
+	// range query saw pending may be firing by now: filter after annotating.
+	// Resolved alerts stay, whatever state they were seen in.
+	merged := mergeSeries(matrix)
+	alerts := make([]Alert, 0, len(merged))
+	for _, a := range merged {
+		annotate(&a, rules, now)
+		if a.State == StateResolved || alertOpts.State == StateAll || a.State == stateMatcher {
+			alerts = append(alerts, a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].StartedAt.Equal(alerts[j].StartedAt) {
+			return alerts[i].Name < alerts[j].Name
+		}
+		return alerts[i].StartedAt.After(alerts[j].StartedAt)
+	})
+	return alerts, nil
+}
+
+// queryStep keeps the range query to about 240 samples per series.
+func queryStep(since time.Duration) time.Duration {
+	step := since / 240
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+	return step
+}
+
+// mergeSeries folds the pending and firing series of the same alert instance
+// into one Alert. Whether it is still active is decided later by annotate.
+func mergeSeries(matrix model.Matrix) []Alert {
+	byKey := map[string]*Alert{}
+	var keys []string
+	for _, series := range matrix {
+		if len(series.Values) == 0 {
+			continue
+		}
+		labels := map[string]string{}
+		for k, v := range series.Metric {
+			if k == model.MetricNameLabel || k == alertStateLabel {
+				continue
+			}
+			labels[string(k)] = string(v)
+		}
+		key := toLabelSet(labels).String()
+		start := series.Values[0].Timestamp.Time()
+		end := series.Values[len(series.Values)-1].Timestamp.Time()
+		state := string(series.Metric[alertStateLabel])
+
+		a, ok := byKey[key]
+		if !ok {
+			a = &Alert{
+				Name:      labels[model.AlertNameLabel],
+				Severity:  labels["severity"],
+				Labels:    labels,
+				StartedAt: start,
+				State:     state,
+			}
+			a.EndedAt = &end
+			byKey[key] = a
+			keys = append(keys, key)
+			continue
+		}
+		if start.Before(a.StartedAt) {
+			a.StartedAt = start
+		}
+		if end.After(*a.EndedAt) {
+			a.EndedAt = &end
+			a.State = state
+		}
+	}
+
+	alerts := make([]Alert, 0, len(keys))
+	for _, key := range keys {
+		alerts = append(alerts, *byKey[key])
+	}
+	return alerts
+}
+
+// annotate fills in the annotations and the current state from the rules API.
+// Active alerts carry their templated annotations and the exact time they
+// became active; resolved ones fall back to the rule's annotations.
+func annotate(a *Alert, rules promv1.RulesResult, now time.Time) {
+	var active *promv1.Alert
+	var rule *promv1.AlertingRule
+	for _, g := range rules.Groups {
+		for _, r := range g.Rules {
+			ar, ok := r.(promv1.AlertingRule)
+			if !ok || ar.Name != a.Name {
+				continue
+			}
+			for _, inst := range ar.Alerts {
+				if sameInstance(inst.Labels, a.Labels) {
+					rule, active = &ar, inst
+					break
+				}
+			}
+			if rule == nil {
+				rule = &ar
+			}
+		}
+	}
+
+	annotations := model.LabelSet{}
+	if rule != nil {
+		annotations = rule.Annotations
+	}
+	if active != nil {
+		annotations = active.Annotations
+		a.State = string(active.State)
+		a.StartedAt = active.ActiveAt
+		a.EndedAt = nil
+	} else {
+		a.State = StateResolved
+	}
+	a.Summary = string(annotations["summary"])
+	a.Description = string(annotations["description"])
+	if a.Severity == "" {
+		a.Severity = string(annotations["severity"])
+	}
+
+	end := now
+	if a.EndedAt != nil {
+		end = *a.EndedAt
+	}
+	a.Duration = duration.HumanDuration(end.Sub(a.StartedAt))
+}
+
+func sameInstance(ls model.LabelSet, labels map[string]string) bool {
+	for k, v := range ls {
+		if k == alertStateLabel {
+			continue
+		}
+		if labels[string(k)] != string(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLabelSet(labels map[string]string) model.LabelSet {
+	ls := make(model.LabelSet, len(labels))
+	for k, v := range labels {
+		ls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return ls
+}
+
+func countCriticalFiring(alerts []Alert) int {
+	n := 0
+	for _, a := range alerts {
+		if a.State == StateFiring && a.Severity == severityCritical {
+			n++
+		}
+	}
+	return n
+}
+
+func printJSON(out io.Writer, alerts []Alert) error {
+	if alerts == nil {
+		alerts = []Alert{}
+	}
+	b, err := json.MarshalIndent(alerts, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(b))
+	return err
+}
+
+func printTable(out io.Writer, alerts []Alert) error {
+	if len(alerts) == 0 {
+		_, err := fmt.Fprintln(out, "No alerts found.")
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ALERT\tSTATE\tSEVERITY\tSTARTED\tFOR\tSUMMARY")
+	for _, a := range alerts {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, a.State, orDash(a.Severity), a.StartedAt.Local().Format(time.RFC3339), a.Duration, orDash(a.Summary))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		if a.Description != "" {
+			_, _ = fmt.Fprintf(out, "\n%s: %s\n", a.Name, a.Description)
+		}
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -20,13 +20,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
 	"kubedb.dev/cli/pkg/monitor"
 
-	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/model"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
@@ -45,10 +44,25 @@ type dbOpts struct {
 	resource   string
 }
 
-func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc) {
+// Options controls which alerts get-alerts reports and how.
+type Options struct {
+	// Since is how far back to look for alerts.
+	Since time.Duration
+	// State is one of firing, pending or all.
+	State string
+	// Output is one of table or json.
+	Output string
+}
+
+func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc, alertOpts Options) {
 	if len(args) < 2 {
 		log.Fatal("Enter db object's name as an argument")
 	}
+	switch alertOpts.Output {
+	case "", "table", "json":
+	default:
+		log.Fatalf("unknown output format %q, must be one of: table|json", alertOpts.Output)
+	}
 	resource := args[0]
 	dbName := args[1]
 
@@ -62,13 +76,25 @@ func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc) {
 		log.Fatalln(err)
 	}
 
-	promClient, tunnel := monitor.GetPromClientAndTunnel(opts.config, prom)
-	defer tunnel.Close()
+	promClient, closeTunnel := monitor.GetPromClientAndTunnel(opts.config, prom)
+	defer closeTunnel()
 
-	err = opts.work(promClient)
+	alerts, err := opts.work(promClient, alertOpts)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	if alertOpts.Output == "json" {
+		err = printJSON(os.Stdout, alerts)
+	} else {
+		err = printTable(os.Stdout, alerts)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if n := countCriticalFiring(alerts); n > 0 {
+		log.Fatalf("%d critical alert(s) firing for %s %s/%s", n, opts.resource, opts.db.GetNamespace(), opts.db.GetName())
+	}
 }
 
 func newDBOpts(f cmdutil.Factory, dbName, namespace, resource string) (*dbOpts, error) {
@@ -118,30 +144,3 @@ func (opts *dbOpts) ForwardPort(resource string, prom monitor.PromSvc) (*portfor
 	}
 	return tunnel, nil
 }
-
-func (opts *dbOpts) work(promAPI promv1.API) error {
-	alertQuery := fmt.Sprintf("ALERTS{alertstate=\"firing\",k8s_group=\"kubedb.com\",k8s_resource=\"%s\",app=\"%s\",app_namespace=\"%s\"}",
-		opts.resource, opts.db.GetName(), opts.db.GetNamespace())
-	result, warnings, err := promAPI.QueryRange(context.TODO(), alertQuery, promv1.Range{
-		Start: time.Now().Add(-time.Hour),
-		End:   time.Now(),
-		Step:  time.Minute * 2,
-	})
-	if err != nil {
-		return err
-	}
-	if len(warnings) > 0 {
-		fmt.Println("Warnings:", warnings)
-	}
-
-	// Access the elements of the matrix using indexing.
-	matrix := result.(model.Matrix)
-	fmt.Printf("The number of firing alerts for %s %s/%s is %d \n", opts.resource, opts.db.GetNamespace(), opts.db.GetName(), matrix.Len())
-	for _, sample := range matrix {
-		alertName := sample.Metric["alertname"]
-		alertValue := sample.Values[0].Value
-		labels := sample.Metric
-		fmt.Printf("Alert Name: %s, Value: %s, Labels: %s\n", alertName, alertValue, labels)
-	}
-	return nil
-}
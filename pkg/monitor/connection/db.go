@@ -41,6 +41,11 @@ func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc) {
 	if len(args) < 2 {
 		log.Fatal("Enter database and specific database name as argument")
 	}
+	if prom.Name == "" && !prom.Direct() {
+		fmt.Println("No prometheus service or --prom-url given, checking the exporter directly.")
+		RunExporter(f, args)
+		return
+	}
 
 	database := monitor.ConvertedResourceToSingular(args[0])
 	databaseName := args[1]
@@ -54,8 +59,17 @@ func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc) {
 		log.Fatal(err)
 	}
 
-	promClient, tunnel := monitor.GetPromClientAndTunnel(config, prom)
-	defer tunnel.Close()
+	promClient, closeTunnel := monitor.GetPromClientAndTunnel(config, prom)
+	defer closeTunnel()
+
+	wiringOK := true
+	for _, s := range checkWiring(config, promClient, prom, monitor.ConvertedResourceToPlural(args[0]), databaseName, namespace) {
+		fmt.Println(s)
+		if !s.ok && !s.skipped {
+			wiringOK = false
+		}
+	}
+	fmt.Println()
 
 	queries := getIdenticalMetrics(database, databaseName)
 	var notFound []string
@@ -98,7 +112,7 @@ func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc) {
 		}
 	}
 
-	if len(notFound) == 0 {
+	if len(notFound) == 0 && wiringOK {
 		fmt.Printf("All monitoring connection established successfully for %s : %s/%s\n", database, namespace, databaseName)
 	} else {
 		for _, target := range notFound {
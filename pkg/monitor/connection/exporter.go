@@ -0,0 +1,67 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"fmt"
+	"log"
+
+	"kubedb.dev/cli/pkg/monitor"
+	"kubedb.dev/cli/pkg/monitor/scrape"
+
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// RunExporter checks the exporter of a database by scraping its stats service
+// directly. It is used when no Prometheus is installed, or with --scrape.
+func RunExporter(f cmdutil.Factory, args []string) {
+	if len(args) < 2 {
+		log.Fatal("Enter database and specific database name as argument")
+	}
+	database := monitor.ConvertedResourceToSingular(args[0])
+	databaseName := args[1]
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	metric, ok := DBUpMetric(database)
+	if !ok {
+		log.Fatal("database invalid!")
+	}
+	result, err := scrape.Exporter(config, databaseName, namespace)
+	if err != nil {
+		log.Fatalf("exporter of %s %s/%s is not reachable: %v", database, namespace, databaseName, err)
+	}
+	fmt.Printf("Scraped %s/%s:%d%s: %d metric families\n", namespace, result.Service, result.Port, result.Path, len(result.Families))
+
+	mf := result.Family(metric)
+	if mf == nil {
+		log.Fatalf("exporter of %s %s/%s does not export %s", database, namespace, databaseName, metric)
+	}
+	for _, m := range mf.GetMetric() {
+		if v := scrape.Value(m); v > 0 {
+			fmt.Printf("Exporter of %s %s/%s is healthy: %s = %v\n", database, namespace, databaseName, metric, v)
+			return
+		}
+	}
+	log.Fatalf("exporter of %s %s/%s is running but reports %s = 0, it cannot reach the database", database, namespace, databaseName, metric)
+}
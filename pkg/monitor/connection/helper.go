@@ -42,67 +42,37 @@ func getIdenticalMetrics(database, databaseName string) map[string]*metrics {
 	return queries
 }
 
+// dbUpMetrics are the exporter metrics that tell whether a database is
+// scraped, by singular resource name.
+var dbUpMetrics = map[string]string{
+	dbapi.ResourceSingularElasticsearch: "elasticsearch_clusterinfo_up",
+	dbapi.ResourceSingularKafka:         "kafka_controller_kafkacontroller_activebrokercount",
+	dbapi.ResourceSingularMariaDB:       "mysql_up",
+	dbapi.ResourceSingularMongoDB:       "mongodb_up",
+	dbapi.ResourceSingularMySQL:         "mysql_up",
+	dbapi.ResourceSingularPerconaXtraDB: "mysql_up",
+	dbapi.ResourceSingularPostgres:      "pg_up",
+	dbapi.ResourceSingularProxySQL:      "proxysql_uptime_seconds_total",
+	dbapi.ResourceSingularRedis:         "redis_up",
+}
+
+// DBUpMetric returns the exporter metric checked for the given database,
+// identified by its singular resource name.
+func DBUpMetric(database string) (string, bool) {
+	metric, ok := dbUpMetrics[database]
+	return metric, ok
+}
+
 func getDBMetrics(database, name string, queries map[string]*metrics) map[string]*metrics {
-	label := "service"
-	labelValue := fmt.Sprintf("%s-stats", name)
-	switch database {
-	case dbapi.ResourceSingularElasticsearch:
-		queries[database] = &metrics{
-			metric:     "elasticsearch_clusterinfo_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularKafka:
-		queries[database] = &metrics{
-			metric:     "kafka_controller_kafkacontroller_activebrokercount",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularMariaDB:
-		queries[database] = &metrics{
-			metric:     "mysql_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularMongoDB:
-		queries[database] = &metrics{
-			metric:     "mongodb_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularMySQL:
-		queries[database] = &metrics{
-			metric:     "mysql_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularPerconaXtraDB:
-		queries[database] = &metrics{
-			metric:     "mysql_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularPostgres:
-		queries[database] = &metrics{
-			metric:     "pg_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularProxySQL:
-		queries[database] = &metrics{
-			metric:     "proxysql_uptime_seconds_total",
-			label:      label,
-			labelValue: labelValue,
-		}
-	case dbapi.ResourceSingularRedis:
-		queries[database] = &metrics{
-			metric:     "redis_up",
-			label:      label,
-			labelValue: labelValue,
-		}
-	default:
+	metric, ok := DBUpMetric(database)
+	if !ok {
 		log.Fatal("database invalid!")
 	}
+	queries[database] = &metrics{
+		metric:     metric,
+		label:      "service",
+		labelValue: fmt.Sprintf("%s-stats", name),
+	}
 
 	// Panopticon
 	queries["panopticon"] = &metrics{
@@ -0,0 +1,373 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	olddbapi "kubedb.dev/apimachinery/apis/kubedb/v1alpha2"
+	"kubedb.dev/cli/pkg/monitor"
+
+	promoperator "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	mona "kmodules.xyz/monitoring-agent-api/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(promoperator.AddToScheme(scheme))
+}
+
+// step is one pass/fail check of the monitoring wiring.
+type step struct {
+	name    string
+	ok      bool
+	skipped bool
+	detail  string
+}
+
+func (s step) String() string {
+	mark := "PASS"
+	switch {
+	case s.skipped:
+		mark = "SKIP"
+	case !s.ok:
+		mark = "FAIL"
+	}
+	if s.detail == "" {
+		return fmt.Sprintf("[%s] %s", mark, s.name)
+	}
+	return fmt.Sprintf("[%s] %s: %s", mark, s.name, s.detail)
+}
+
+type wiringOpts struct {
+	kc        client.Client
+	dc        dynamic.Interface
+	prom      monitor.PromSvc
+	promAPI   promv1.API
+	resource  string
+	name      string
+	namespace string
+
+	agent       mona.AgentType
+	statsSvc    *corev1.Service
+	monitorName string
+	monitorKind string
+}
+
+// checkWiring follows the path from the database to a healthy scrape target
+// and stops at the first broken link, as the later steps depend on it.
+func checkWiring(config *rest.Config, promAPI promv1.API, prom monitor.PromSvc, resource, name, namespace string) []step {
+	kc, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return []step{{name: "create kubernetes client", detail: err.Error()}}
+	}
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return []step{{name: "create kubernetes client", detail: err.Error()}}
+	}
+	w := &wiringOpts{
+		kc:        kc,
+		dc:        dc,
+		prom:      prom,
+		promAPI:   promAPI,
+		resource:  resource,
+		name:      name,
+		namespace: namespace,
+	}
+
+	checks := []func() step{
+		w.checkMonitorSpec,
+		w.checkStatsService,
+		w.checkStatsEndpoints,
+		w.checkMonitorObject,
+		w.checkPrometheusSelector,
+		w.checkScrapeTarget,
+	}
+	var steps []step
+	for _, check := range checks {
+		s := check()
+		steps = append(steps, s)
+		if !s.ok && !s.skipped {
+			break
+		}
+	}
+	return steps
+}
+
+func (w *wiringOpts) checkMonitorSpec() step {
+	s := step{name: "spec.monitor is configured"}
+	gv := dbapi.SchemeGroupVersion
+	if monitor.IsOldAPI(w.resource) {
+		gv = olddbapi.SchemeGroupVersion
+	}
+	obj, err := w.dc.Resource(gv.WithResource(w.resource)).Namespace(w.namespace).Get(context.TODO(), w.name, metav1.GetOptions{})
+	if err != nil {
+		s.detail = err.Error()
+		return s
+	}
+	spec, found, _ := unstructured.NestedMap(obj.Object, "spec", "monitor")
+	if !found || len(spec) == 0 {
+		s.detail = "not set; the operator creates no stats service or ServiceMonitor without it"
+		return s
+	}
+	var agent mona.AgentSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &agent); err != nil {
+		s.detail = err.Error()
+		return s
+	}
+	w.agent = agent.Agent
+	switch agent.Agent {
+	case mona.AgentPrometheusOperator:
+		labels := "none"
+		if agent.Prometheus != nil && agent.Prometheus.ServiceMonitor != nil && len(agent.Prometheus.ServiceMonitor.Labels) > 0 {
+			labels = fmt.Sprint(agent.Prometheus.ServiceMonitor.Labels)
+		}
+		s.ok = true
+		s.detail = fmt.Sprintf("agent %s, serviceMonitor labels %s", agent.Agent, labels)
+	case mona.AgentPrometheusBuiltin:
+		s.ok = true
+		s.detail = fmt.Sprintf("agent %s", agent.Agent)
+	default:
+		s.detail = fmt.Sprintf("unsupported agent %q, use %s or %s", agent.Agent, mona.AgentPrometheusOperator, mona.AgentPrometheusBuiltin)
+	}
+	return s
+}
+
+func (w *wiringOpts) checkStatsService() step {
+	svcName := w.name + "-stats"
+	s := step{name: fmt.Sprintf("stats service %s/%s exists", w.namespace, svcName)}
+	var svc corev1.Service
+	if err := w.kc.Get(context.TODO(), types.NamespacedName{Namespace: w.namespace, Name: svcName}, &svc); err != nil {
+		s.detail = err.Error()
+		return s
+	}
+	w.statsSvc = &svc
+	s.ok = true
+	return s
+}
+
+func (w *wiringOpts) checkStatsEndpoints() step {
+	s := step{name: fmt.Sprintf("stats service %s has ready endpoints", w.statsSvc.Name)}
+	var slices discoveryv1.EndpointSliceList
+	err := w.kc.List(context.TODO(), &slices, client.InNamespace(w.namespace), client.MatchingLabels{discoveryv1.LabelServiceName: w.statsSvc.Name})
+	if err != nil {
+		s.detail = err.Error()
+		return s
+	}
+	ready := 0
+	for _, es := range slices.Items {
+		for _, ep := range es.Endpoints {
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	if ready == 0 {
+		s.detail = "no ready endpoints; check that the exporter container is running and its port matches the service"
+		return s
+	}
+	s.ok = true
+	s.detail = fmt.Sprintf("%d ready", ready)
+	return s
+}
+
+// checkMonitorObject finds the ServiceMonitor selecting the stats service, or
+// a PodMonitor selecting its pods. The builtin agent scrapes the service
+// annotations instead.
+func (w *wiringOpts) checkMonitorObject() step {
+	s := step{name: "ServiceMonitor or PodMonitor selects the stats service"}
+	if w.agent == mona.AgentPrometheusBuiltin {
+		s.name = "stats service has prometheus.io/scrape annotation"
+		if w.statsSvc.Annotations["prometheus.io/scrape"] != "true" {
+			s.detail = "missing; the builtin Prometheus will not discover this service"
+			return s
+		}
+		s.ok = true
+		return s
+	}
+
+	var sms promoperator.ServiceMonitorList
+	if err := w.kc.List(context.TODO(), &sms); err != nil {
+		s.detail = fmt.Sprintf("failed to list ServiceMonitors: %v", err)
+		return s
+	}
+	for _, sm := range sms.Items {
+		if !namespaceSelected(sm.Spec.NamespaceSelector, sm.Namespace, w.namespace) {
+			continue
+		}
+		if selectorMatches(&sm.Spec.Selector, w.statsSvc.Labels) {
+			w.monitorKind, w.monitorName = "serviceMonitor", sm.Namespace+"/"+sm.Name
+			s.ok = true
+			s.detail = "ServiceMonitor " + w.monitorName
+			return s
+		}
+	}
+
+	var pms promoperator.PodMonitorList
+	if err := w.kc.List(context.TODO(), &pms); err == nil {
+		for _, pm := range pms.Items {
+			if !namespaceSelected(pm.Spec.NamespaceSelector, pm.Namespace, w.namespace) {
+				continue
+			}
+			if selectorMatches(&pm.Spec.Selector, w.statsSvc.Spec.Selector) {
+				w.monitorKind, w.monitorName = "podMonitor", pm.Namespace+"/"+pm.Name
+				s.ok = true
+				s.detail = "PodMonitor " + w.monitorName
+				return s
+			}
+		}
+	}
+	s.detail = fmt.Sprintf("none found; no ServiceMonitor selects labels %v", w.statsSvc.Labels)
+	return s
+}
+
+// checkPrometheusSelector checks that the Prometheus behind --prom-svc-namespace
+// picks up the monitor object through its (service|pod)MonitorSelector.
+func (w *wiringOpts) checkPrometheusSelector() step {
+	s := step{name: "Prometheus selects the monitor object"}
+	if w.agent == mona.AgentPrometheusBuiltin {
+		s.skipped = true
+		s.detail = "builtin agent"
+		return s
+	}
+	if w.prom.Direct() && w.prom.Namespace == "" {
+		s.skipped = true
+		s.detail = "--prom-url given; set --prom-svc-namespace to check the Prometheus object"
+		return s
+	}
+	ns, name, _ := strings.Cut(w.monitorName, "/")
+	var monLabels map[string]string
+	if w.monitorKind == "serviceMonitor" {
+		var sm promoperator.ServiceMonitor
+		if err := w.kc.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, &sm); err != nil {
+			s.detail = err.Error()
+			return s
+		}
+		monLabels = sm.Labels
+	} else {
+		var pm promoperator.PodMonitor
+		if err := w.kc.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, &pm); err != nil {
+			s.detail = err.Error()
+			return s
+		}
+		monLabels = pm.Labels
+	}
+	var monNs corev1.Namespace
+	if err := w.kc.Get(context.TODO(), types.NamespacedName{Name: ns}, &monNs); err != nil {
+		s.detail = err.Error()
+		return s
+	}
+
+	var proms promoperator.PrometheusList
+	if err := w.kc.List(context.TODO(), &proms, client.InNamespace(w.prom.Namespace)); err != nil {
+		s.detail = fmt.Sprintf("failed to list Prometheus objects: %v", err)
+		return s
+	}
+	if len(proms.Items) == 0 {
+		s.detail = fmt.Sprintf("no Prometheus object found in namespace %q", w.prom.Namespace)
+		return s
+	}
+	var rejected []string
+	for _, p := range proms.Items {
+		sel, nsSel := p.Spec.ServiceMonitorSelector, p.Spec.ServiceMonitorNamespaceSelector
+		if w.monitorKind == "podMonitor" {
+			sel, nsSel = p.Spec.PodMonitorSelector, p.Spec.PodMonitorNamespaceSelector
+		}
+		// A nil namespace selector restricts discovery to the Prometheus' own namespace.
+		nsOK := (nsSel == nil && ns == p.Namespace) || (nsSel != nil && selectorMatches(nsSel, monNs.Labels))
+		if sel != nil && selectorMatches(sel, monLabels) && nsOK {
+			s.ok = true
+			s.detail = fmt.Sprintf("selected by Prometheus %s/%s", p.Namespace, p.Name)
+			return s
+		}
+		rejected = append(rejected, fmt.Sprintf("%s/%s (%sSelector %s)", p.Namespace, p.Name, w.monitorKind, metav1.FormatLabelSelector(sel)))
+	}
+	s.detail = fmt.Sprintf("monitor labels %v are not selected by %s; set spec.monitor.prometheus.serviceMonitor.labels accordingly", monLabels, strings.Join(rejected, ", "))
+	return s
+}
+
+func (w *wiringOpts) checkScrapeTarget() step {
+	s := step{name: "Prometheus scrape target is up"}
+	targets, err := w.promAPI.Targets(context.TODO())
+	if err != nil {
+		s.detail = fmt.Sprintf("failed to query targets API: %v", err)
+		return s
+	}
+	var found, down []string
+	for _, t := range targets.Active {
+		if string(t.Labels["namespace"]) != w.namespace || string(t.Labels["service"]) != w.statsSvc.Name {
+			if w.monitorName == "" || !strings.Contains(t.ScrapePool, w.monitorKind+"/"+w.monitorName+"/") {
+				continue
+			}
+		}
+		found = append(found, t.ScrapeURL)
+		if t.Health != promv1.HealthGood {
+			down = append(down, fmt.Sprintf("%s is %s: %s", t.ScrapeURL, t.Health, t.LastError))
+		}
+	}
+	switch {
+	case len(found) == 0:
+		s.detail = "no active target found for the stats service"
+	case len(down) > 0:
+		s.detail = strings.Join(down, "; ")
+	default:
+		s.ok = true
+		s.detail = fmt.Sprintf("%d target(s) healthy", len(found))
+	}
+	return s
+}
+
+func namespaceSelected(sel promoperator.NamespaceSelector, monitorNs, ns string) bool {
+	if sel.Any {
+		return true
+	}
+	if len(sel.MatchNames) == 0 {
+		return monitorNs == ns
+	}
+	for _, n := range sel.MatchNames {
+		if n == ns {
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(sel *metav1.LabelSelector, set map[string]string) bool {
+	selector, err := metav1.LabelSelectorAsSelector(sel)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(set))
+}
@@ -25,6 +25,7 @@ import (
 
 	"kubedb.dev/cli/pkg/monitor"
 
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -83,15 +84,16 @@ func Run(f cmdutil.Factory, args []string, branch, file, url string, prom monito
 		dashboardData = getDashboardFromFile(file)
 	}
 
-	queries := parseAllExpressions(dashboardData)
+	panelQueries := parseAllExpressions(dashboardData, templateVars(db))
+	queries := metricQueries(panelQueries)
 
 	config, err := f.ToRESTConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 	// Port forwarding cluster prometheus service for that grafana dashboard's prom datasource.
-	promClient, tunnel := monitor.GetPromClientAndTunnel(config, prom)
-	defer tunnel.Close()
+	promClient, closeTunnel := monitor.GetPromClientAndTunnel(config, prom)
+	defer closeTunnel()
 
 	// var unknown []missingOpts
 	unknown := make(map[string]*missingOpts)
@@ -136,8 +138,18 @@ func Run(f cmdutil.Factory, args []string, branch, file, url string, prom monito
 			unknown[metricName].panelTitle = uniqueAppend(unknown[metricName].panelTitle, query.panelTitle)
 		}
 	}
-	unknown = ignoreModeSpecificExpressions(unknown, database, db)
-	if len(unknown) > 0 {
+	filtered := ignoreModeSpecificExpressions(unknown, database, db)
+	ignored := map[string]bool{}
+	for metric := range unknown {
+		if _, ok := filtered[metric]; !ok {
+			ignored[metric] = true
+		}
+	}
+	unknown = filtered
+
+	noData := evaluatePanels(promClient, panelQueries, ignored)
+
+	if len(unknown) > 0 || len(noData) > 0 {
 		fmt.Println("Missing Information:")
 		for metric, opts := range unknown {
 			fmt.Println("---------------------------------------------------")
@@ -147,12 +159,70 @@ func Run(f cmdutil.Factory, args []string, branch, file, url string, prom monito
 			}
 			fmt.Printf("Effected Panel: %s \n", strings.Join(opts.panelTitle, ", "))
 		}
+		if len(noData) > 0 {
+			fmt.Println("---------------------------------------------------")
+			fmt.Println("Panels without data:")
+			for _, p := range noData {
+				fmt.Printf("- %s: %s\n", p.panelTitle, p.reason)
+				fmt.Printf("  query: %s\n", p.expr)
+			}
+		}
 		if isDB {
 			log.Fatalf("Information missing for database %s: %s/%s\n", database, namespace, dbName)
 		} else {
 			log.Fatalf("Information missing")
 		}
 	} else {
-		fmt.Println("All metrics found")
+		fmt.Println("All metrics found and all panels return data")
+	}
+}
+
+type panelResult struct {
+	panelTitle string
+	expr       string
+	reason     string
+}
+
+// evaluatePanels runs every full panel query and returns the ones that fail or
+// return no data. Panels that only use metrics of a topology the database does
+// not run are skipped.
+func evaluatePanels(promClient promv1.API, queries []panelQuery, ignored map[string]bool) []panelResult {
+	var out []panelResult
+	for _, q := range queries {
+		skip := false
+		for _, m := range q.metrics {
+			if ignored[m] {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		if q.err != nil {
+			out = append(out, panelResult{panelTitle: q.panelTitle, expr: q.expr, reason: q.err.Error()})
+			continue
+		}
+		result, _, err := promClient.Query(context.TODO(), q.expr, time.Now())
+		if err != nil {
+			out = append(out, panelResult{panelTitle: q.panelTitle, expr: q.expr, reason: "query failed: " + err.Error()})
+			continue
+		}
+		if isEmpty(result) {
+			out = append(out, panelResult{panelTitle: q.panelTitle, expr: q.expr, reason: "no data"})
+		}
+	}
+	return out
+}
+
+func isEmpty(v model.Value) bool {
+	switch r := v.(type) {
+	case model.Vector:
+		return len(r) == 0
+	case model.Matrix:
+		return len(r) == 0
+	case nil:
+		return true
 	}
+	return false
 }
@@ -17,115 +17,175 @@ limitations under the License.
 package dashboard
 
 import (
-	"log"
+	"fmt"
 	"regexp"
 	"strings"
-	"unicode"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-func parseAllExpressions(dashboardData map[string]any) []queryOpts {
-	var queries []queryOpts
-	if panels, ok := dashboardData["panels"].([]any); ok {
-		for _, panel := range panels {
-			if targets, ok := panel.(map[string]any)["targets"].([]any); ok {
-				title, ok := panel.(map[string]any)["title"].(string)
-				if !ok {
-					log.Fatal("panel's title found empty")
-				}
-				for _, target := range targets {
-					if expr, ok := target.(map[string]any)["expr"]; ok {
-						if expr != "" {
-							query := expr.(string)
-							queries = append(queries, parseSingleExpression(query, title)...)
-						}
-					}
-				}
-			}
+// panelQuery is one target expression of a panel, with the Grafana template
+// variables already substituted.
+type panelQuery struct {
+	panelTitle string
+	expr       string
+	metrics    []string
+	// err is set when the expression could not be substituted or parsed.
+	err error
+}
+
+const unknownVarSentinel = "__kubedb_cli_unknown_var__"
+
+var (
+	// Grafana variable names cannot start with a digit, so the $1 and ${1}
+	// back-references of label_replace are left alone.
+	templateVarRegex = regexp.MustCompile(`\$\{([A-Za-z_]\w*)(?::[\w]+)?\}|\[\[([A-Za-z_]\w*)\]\]|\$([A-Za-z_]\w*)`)
+	// matchers whose value contains an unknown variable are turned into regex
+	// matchers that accept any value.
+	unknownMatcherRegex = regexp.MustCompile(`(=~|!~|!=|=)\s*"([^"]*` + unknownVarSentinel + `[^"]*)"`)
+)
+
+// templateVars returns the values of the Grafana template variables used by
+// the KubeDB dashboards for db. db is nil when no database was given; then only
+// the interval variables are known.
+func templateVars(db *unstructured.Unstructured) map[string]string {
+	vars := map[string]string{
+		"__rate_interval": "5m",
+		"__interval":      "1m",
+		"interval":        "1m",
+		"__range":         "1h",
+		"__range_s":       "3600",
+	}
+	if db != nil {
+		vars["namespace"] = db.GetNamespace()
+		vars["app"] = db.GetName()
+		vars["db"] = db.GetName()
+		vars["service"] = db.GetName() + "-stats"
+	}
+	return vars
+}
+
+func substituteVars(expr string, vars map[string]string) (string, error) {
+	var unknown []string
+	out := templateVarRegex.ReplaceAllStringFunc(expr, func(m string) string {
+		sub := templateVarRegex.FindStringSubmatch(m)
+		name := sub[1] + sub[2] + sub[3]
+		if v, ok := vars[name]; ok {
+			return v
 		}
+		unknown = append(unknown, name)
+		return unknownVarSentinel
+	})
+	if len(unknown) == 0 {
+		return out, nil
 	}
-	return queries
+	out = unknownMatcherRegex.ReplaceAllStringFunc(out, func(m string) string {
+		sub := unknownMatcherRegex.FindStringSubmatch(m)
+		op := "=~"
+		if sub[1] == "!=" || sub[1] == "!~" {
+			op = "!~"
+		}
+		value := sub[2]
+		if sub[1] == "=" || sub[1] == "!=" {
+			value = regexp.QuoteMeta(strings.ReplaceAll(value, unknownVarSentinel, "\x00"))
+			value = strings.ReplaceAll(value, "\x00", unknownVarSentinel)
+		}
+		return fmt.Sprintf(`%s"%s"`, op, strings.ReplaceAll(value, unknownVarSentinel, ".*"))
+	})
+	if strings.Contains(out, unknownVarSentinel) {
+		return "", fmt.Errorf("unknown template variable(s) %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
 }
 
-// Steps:
-// - if current character is '{'
-//   - extract metric name by matching metric regex
-//   - get label selector substring inside { }
-//   - get label name from this substring by matching label regex
-//   - move i to its closing bracket position.
-func parseSingleExpression(query, title string) []queryOpts {
-	var queries []queryOpts
-	for i := 0; i < len(query); i++ {
-		if query[i] == '{' {
-			j := i
-			for j-1 >= 0 && (!matchMetricRegex(rune(query[j-1]))) {
-				j--
+// parseAllExpressions collects the target expressions of every panel,
+// including the panels of collapsed rows and of the old `rows` layout.
+func parseAllExpressions(dashboardData map[string]any, vars map[string]string) []panelQuery {
+	var queries []panelQuery
+	if panels, ok := dashboardData["panels"].([]any); ok {
+		queries = appendPanelQueries(queries, panels, vars)
+	}
+	if rows, ok := dashboardData["rows"].([]any); ok {
+		for _, row := range rows {
+			if r, ok := row.(map[string]any); ok {
+				if panels, ok := r["panels"].([]any); ok {
+					queries = appendPanelQueries(queries, panels, vars)
+				}
 			}
-			metric := query[j:i]
-			fullLabelString, closingPosition := getFullLabelString(query, i)
-			labelNames := parseLabelNames(fullLabelString)
-			queries = append(queries, queryOpts{
-				metric:     metric,
-				labelNames: labelNames,
-				panelTitle: title,
-			})
-			i = closingPosition
 		}
 	}
 	return queries
 }
 
-func matchMetricRegex(char rune) bool { // Must match the regex [a-zA-Z_:][a-zA-Z0-9_:]*
-	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_' || char == ':'
-}
-
-// Finding valid bracket sequence from startPosition
-func getFullLabelString(query string, startPosition int) (string, int) {
-	balance := 0
-	closingPosition := startPosition
-	for i := startPosition; i < len(query); i++ {
-		if query[i] == '{' {
-			balance++
+func appendPanelQueries(queries []panelQuery, panels []any, vars map[string]string) []panelQuery {
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
 		}
-		if query[i] == '}' {
-			balance--
+		title, _ := panel["title"].(string)
+		if title == "" {
+			title = fmt.Sprintf("<untitled panel %v>", panel["id"])
+		}
+		if targets, ok := panel["targets"].([]any); ok {
+			for _, target := range targets {
+				t, ok := target.(map[string]any)
+				if !ok {
+					continue
+				}
+				expr, _ := t["expr"].(string)
+				if strings.TrimSpace(expr) == "" || t["hide"] == true {
+					continue
+				}
+				queries = append(queries, parseSingleExpression(expr, title, vars))
+			}
 		}
-		if balance == 0 {
-			closingPosition = i
-			break
+		// collapsed rows keep their panels nested
+		if nested, ok := panel["panels"].([]any); ok {
+			queries = appendPanelQueries(queries, nested, vars)
 		}
 	}
-	return query[startPosition+1 : closingPosition], closingPosition
+	return queries
 }
 
-// Labels may contain ASCII letters, numbers, as well as underscores. They must match the regex [a-zA-Z_][a-zA-Z0-9_]*
-// So we need to split the selector string by comma. then extract label name with the help of the regex format
-// Ref: https://prometheus.io/docs/concepts/data_model/#metric-names-and-labels
-func parseLabelNames(fullLabelString string) []string {
-	// Define the regular expression pattern to match string inside double quotation
-	// Replace all quoted substring with an empty string
-	excludeQuotedSubstrings := func(input string) string {
-		re := regexp.MustCompile(`"[^"]*"`)
-		result := re.ReplaceAllString(input, "")
-		return result
+func parseSingleExpression(expr, title string, vars map[string]string) panelQuery {
+	q := panelQuery{panelTitle: title}
+	q.expr, q.err = substituteVars(expr, vars)
+	if q.err != nil {
+		return q
 	}
-
-	// Define the regular expression pattern to match non-alphanumeric characters except underscore
-	// Replace non-alphanumeric or underscore characters with an empty string
-	excludeNonAlphanumericUnderscore := func(input string) string {
-		pattern := `[^a-zA-Z0-9_]`
-		re := regexp.MustCompile(pattern)
-		result := re.ReplaceAllString(input, "")
-		return result
+	selectors, err := parsePromQL(q.expr)
+	if err != nil {
+		q.err = fmt.Errorf("invalid PromQL %q: %w", q.expr, err)
+		return q
+	}
+	for _, sel := range selectors {
+		if sel.metric != "" {
+			q.metrics = uniqueAppend(q.metrics, sel.metric)
+		}
 	}
+	return q
+}
 
-	var labelNames []string
-	unQuoted := excludeQuotedSubstrings(fullLabelString)
-	commaSeparated := strings.Split(unQuoted, ",")
-	for _, s := range commaSeparated {
-		labelName := excludeNonAlphanumericUnderscore(s)
-		if labelName != "" {
-			labelNames = append(labelNames, labelName)
+// metricQueries flattens the selectors of all panel queries into the per
+// metric checks, with the label names each panel filters on.
+func metricQueries(queries []panelQuery) []queryOpts {
+	var out []queryOpts
+	for _, q := range queries {
+		if q.err != nil {
+			continue
+		}
+		selectors, _ := parsePromQL(q.expr)
+		for _, sel := range selectors {
+			if sel.metric == "" {
+				continue
+			}
+			opts := queryOpts{metric: sel.metric, panelTitle: q.panelTitle}
+			for _, m := range sel.matchers {
+				opts.labelNames = uniqueAppend(opts.labelNames, m.name)
+			}
+			out = append(out, opts)
 		}
 	}
-	return labelNames
+	return out
 }
@@ -0,0 +1,464 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// This file holds a small recursive descent parser for PromQL. It validates
+// the expression against the PromQL grammar and collects every vector
+// selector with its label matchers, which is all the dashboard check needs.
+// Ref: https://prometheus.io/docs/prometheus/latest/querying/basics/
+
+type labelMatcher struct {
+	name  string
+	op    string // one of = != =~ !~
+	value string
+}
+
+type vectorSelector struct {
+	metric   string
+	matchers []labelMatcher
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp // operators and punctuation
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+var aggregators = map[string]bool{
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true,
+	"count": true, "count_values": true, "bottomk": true, "topk": true, "quantile": true,
+	"limitk": true, "limit_ratio": true,
+}
+
+// binary operators by precedence, lowest first. ^ is right associative.
+var binaryPrecedence = [][]string{
+	{"or"},
+	{"and", "unless"},
+	{"==", "!=", "<=", "<", ">=", ">"},
+	{"+", "-"},
+	{"*", "/", "%", "atan2"},
+	{"^"},
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#': // comment till end of line
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(input) && rune(input[j]) != c {
+				if input[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokString, val: unquote(input[i+1 : j]), pos: i})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(input) && unicode.IsDigit(rune(input[i+1]))):
+			j := i
+			for j < len(input) && (isAlnum(rune(input[j])) || input[j] == '.') {
+				j++
+			}
+			// exponents like 1e-3
+			if j < len(input) && (input[j] == '-' || input[j] == '+') && j > i && (input[j-1] == 'e' || input[j-1] == 'E') && !strings.HasPrefix(input[i:], "0x") {
+				j++
+				for j < len(input) && unicode.IsDigit(rune(input[j])) {
+					j++
+				}
+			}
+			kind := tokNumber
+			if isDuration(input[i:j]) {
+				kind = tokDuration
+			}
+			tokens = append(tokens, token{kind: kind, val: input[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(input) && (isAlnum(rune(input[j])) || input[j] == ':') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, val: input[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"=~", "!~", "!=", "==", "<=", ">=", "(", ")", "{", "}", "[", "]", ",", ":", "=", "<", ">", "+", "-", "*", "/", "%", "^", "@"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, val: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func isAlnum(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// isDuration reports whether s is a PromQL duration like 5m or 1h30m.
+func isDuration(s string) bool {
+	if s == "" || !unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	hasUnit := false
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && unicode.IsDigit(rune(s[j])) {
+			j++
+		}
+		if j == i {
+			return false
+		}
+		k := j
+		for k < len(s) && unicode.IsLetter(rune(s[k])) {
+			k++
+		}
+		switch s[j:k] {
+		case "ms", "s", "m", "h", "d", "w", "y":
+			hasUnit = true
+		default:
+			return false
+		}
+		i = k
+	}
+	return hasUnit
+}
+
+func unquote(s string) string {
+	r := strings.NewReplacer(`\"`, `"`, `\'`, `'`, `\\`, `\`)
+	return r.Replace(s)
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	selectors []vectorSelector
+}
+
+// parsePromQL returns every vector selector of expr, or an error when expr is
+// not valid PromQL.
+func parsePromQL(expr string) ([]vectorSelector, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if err := p.parseExpr(0); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+	}
+	return p.selectors, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(val string) bool {
+	t := p.peek()
+	return (t.kind == tokOp || t.kind == tokIdent) && t.val == val
+}
+
+func (p *parser) expect(val string) error {
+	t := p.next()
+	if (t.kind != tokOp && t.kind != tokIdent) || t.val != val {
+		return fmt.Errorf("expected %q but found %q at position %d", val, t.val, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) binaryOp(level int) (string, bool) {
+	for _, op := range binaryPrecedence[level] {
+		if p.is(op) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseExpr(level int) error {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	if err := p.parseExpr(level + 1); err != nil {
+		return err
+	}
+	for {
+		op, ok := p.binaryOp(level)
+		if !ok {
+			return nil
+		}
+		p.next()
+		if err := p.parseBinaryModifiers(); err != nil {
+			return err
+		}
+		next := level + 1
+		if op == "^" {
+			next = level // right associative
+		}
+		if err := p.parseExpr(next); err != nil {
+			return err
+		}
+	}
+}
+
+// parseBinaryModifiers skips bool, on/ignoring and group_left/group_right.
+func (p *parser) parseBinaryModifiers() error {
+	if p.is("bool") {
+		p.next()
+	}
+	if p.is("on") || p.is("ignoring") {
+		p.next()
+		if err := p.parseLabelList(); err != nil {
+			return err
+		}
+		if p.is("group_left") || p.is("group_right") {
+			p.next()
+			if p.is("(") {
+				if err := p.parseLabelList(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseLabelList() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		t := p.next()
+		if t.kind != tokIdent && t.kind != tokString {
+			return fmt.Errorf("expected label name but found %q at position %d", t.val, t.pos)
+		}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expect(")")
+}
+
+func (p *parser) parseUnary() error {
+	if p.is("-") || p.is("+") {
+		p.next()
+		return p.parseUnary()
+	}
+	if err := p.parsePrimary(); err != nil {
+		return err
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix handles range and subquery brackets, offset and @ modifiers.
+func (p *parser) parsePostfix() error {
+	for {
+		switch {
+		case p.is("["):
+			p.next()
+			if t := p.next(); t.kind != tokDuration {
+				return fmt.Errorf("expected duration but found %q at position %d", t.val, t.pos)
+			}
+			if p.is(":") {
+				p.next()
+				if p.peek().kind == tokDuration {
+					p.next()
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return err
+			}
+		case p.is("offset"):
+			p.next()
+			if p.is("-") {
+				p.next()
+			}
+			if t := p.next(); t.kind != tokDuration {
+				return fmt.Errorf("expected duration but found %q at position %d", t.val, t.pos)
+			}
+		case p.is("@"):
+			p.next()
+			if p.is("start") || p.is("end") {
+				p.next()
+				if err := p.expect("("); err != nil {
+					return err
+				}
+				if err := p.expect(")"); err != nil {
+					return err
+				}
+			} else if t := p.next(); t.kind != tokNumber {
+				return fmt.Errorf("expected timestamp but found %q at position %d", t.val, t.pos)
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() error {
+	t := p.peek()
+	switch {
+	case t.kind == tokNumber, t.kind == tokString, t.kind == tokDuration:
+		p.next()
+		return nil
+	case p.is("("):
+		p.next()
+		if err := p.parseExpr(0); err != nil {
+			return err
+		}
+		return p.expect(")")
+	case p.is("{"):
+		return p.parseSelector("")
+	case t.kind == tokIdent:
+		p.next()
+		name := t.val
+		switch {
+		case aggregators[name] && (p.is("(") || p.is("by") || p.is("without")):
+			return p.parseAggregation()
+		case p.is("("):
+			return p.parseCall()
+		case name == "Inf" || name == "NaN" || name == "inf" || name == "nan":
+			return nil
+		}
+		return p.parseSelector(name)
+	}
+	return fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+}
+
+func (p *parser) parseAggregation() error {
+	if p.is("by") || p.is("without") {
+		p.next()
+		if err := p.parseLabelList(); err != nil {
+			return err
+		}
+	}
+	if err := p.parseCall(); err != nil {
+		return err
+	}
+	if p.is("by") || p.is("without") {
+		p.next()
+		return p.parseLabelList()
+	}
+	return nil
+}
+
+func (p *parser) parseCall() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		if err := p.parseExpr(0); err != nil {
+			return err
+		}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expect(")")
+}
+
+func (p *parser) parseSelector(metric string) error {
+	sel := vectorSelector{metric: metric}
+	if p.is("{") {
+		p.next()
+		for !p.is("}") {
+			name := p.next()
+			if name.kind != tokIdent && name.kind != tokString {
+				return fmt.Errorf("expected label name but found %q at position %d", name.val, name.pos)
+			}
+			op := p.next()
+			switch op.val {
+			case "=", "!=", "=~", "!~":
+			default:
+				// a bare metric name in braces, e.g. {"metric_name"}
+				if name.kind == tokString && (op.val == "," || op.val == "}") {
+					sel.metric = name.val
+					p.pos--
+					if op.val == "," {
+						p.next()
+					}
+					continue
+				}
+				return fmt.Errorf("expected label matcher operator but found %q at position %d", op.val, op.pos)
+			}
+			value := p.next()
+			if value.kind != tokString {
+				return fmt.Errorf("expected quoted label value but found %q at position %d", value.val, value.pos)
+			}
+			if name.val == "__name__" && op.val == "=" {
+				sel.metric = value.val
+			} else {
+				sel.matchers = append(sel.matchers, labelMatcher{name: name.val, op: op.val, value: value.val})
+			}
+			if !p.is(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect("}"); err != nil {
+			return err
+		}
+	}
+	p.selectors = append(p.selectors, sel)
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboard
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"kubedb.dev/cli/pkg/monitor/generate"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParsePromQLSelectors(t *testing.T) {
+	cases := []struct {
+		expr string
+		want []vectorSelector
+	}{
+		{
+			expr: `up`,
+			want: []vectorSelector{{metric: "up"}},
+		},
+		{
+			expr: `rate(foo[5m])`,
+			want: []vectorSelector{{metric: "foo"}},
+		},
+		{
+			expr: `sum by (pod) (rate(pg_stat_database_xact_commit{job="pg-stats", datname=~"a|{b}"}[5m])) / on(pod) group_left max(pg_up) without (instance)`,
+			want: []vectorSelector{
+				{metric: "pg_stat_database_xact_commit", matchers: []labelMatcher{{name: "job", op: "=", value: "pg-stats"}, {name: "datname", op: "=~", value: "a|{b}"}}},
+				{metric: "pg_up"},
+			},
+		},
+		{
+			expr: `{__name__="mongodb_up", app!="x"} offset 5m > bool 0`,
+			want: []vectorSelector{{metric: "mongodb_up", matchers: []labelMatcher{{name: "app", op: "!=", value: "x"}}}},
+		},
+		{
+			expr: `topk(5, max_over_time(redis_memory_used_bytes[1h:5m])) * 2 ^ 3`,
+			want: []vectorSelector{{metric: "redis_memory_used_bytes"}},
+		},
+	}
+	for _, c := range cases {
+		got, err := parsePromQL(c.expr)
+		if err != nil {
+			t.Errorf("parsePromQL(%q): %v", c.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parsePromQL(%q) = %+v, want %+v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParsePromQLRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{`sum(foo`, `foo{bar}`, `rate(foo[5x])`, `foo{a="b"`} {
+		if _, err := parsePromQL(expr); err == nil {
+			t.Errorf("parsePromQL(%q): expected error, got nil", expr)
+		}
+	}
+}
+
+func TestSubstituteVars(t *testing.T) {
+	vars := map[string]string{"namespace": "demo", "app": "pg", "__rate_interval": "5m"}
+	cases := []struct {
+		expr, want string
+		wantErr    bool
+	}{
+		{expr: `rate(x{namespace="$namespace",app="${app}"}[$__rate_interval])`, want: `rate(x{namespace="demo",app="pg"}[5m])`},
+		{expr: `x{pod="[[app]]-0"}`, want: `x{pod="pg-0"}`},
+		{expr: `x{app="$app", pod="$pod"}`, want: `x{app="pg", pod=~".*"}`},
+		{expr: `x{pod!="$pod.0"}`, want: `x{pod!~".*\.0"}`},
+		{expr: `x[$unknown]`, wantErr: true},
+		{
+			expr: `label_replace(x{app="$app"}, "pod_short", "$1", "pod", "(.*)-${2}")`,
+			want: `label_replace(x{app="pg"}, "pod_short", "$1", "pod", "(.*)-${2}")`,
+		},
+	}
+	for _, c := range cases {
+		got, err := substituteVars(c.expr, vars)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("substituteVars(%q): expected error, got %q", c.expr, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("substituteVars(%q) = %q, %v; want %q", c.expr, got, err, c.want)
+		}
+	}
+}
+
+func TestParseAllExpressionsWalksRows(t *testing.T) {
+	dashboard := map[string]any{
+		"panels": []any{
+			map[string]any{"title": "Top", "targets": []any{map[string]any{"expr": "up"}}},
+			map[string]any{"title": "Row", "type": "row", "panels": []any{
+				map[string]any{"title": "Nested", "targets": []any{map[string]any{"expr": "rate(foo[5m])"}}},
+			}},
+		},
+	}
+	queries := parseAllExpressions(dashboard, templateVars(nil))
+	var titles []string
+	for _, q := range queries {
+		titles = append(titles, q.panelTitle)
+	}
+	if want := []string{"Top", "Nested"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("panel titles = %v, want %v", titles, want)
+	}
+}
+
+func TestGeneratedDashboardParses(t *testing.T) {
+	for _, database := range []string{"postgres", "mysql", "mongodb", "redis", "elasticsearch", "kafka"} {
+		dash := generate.Dashboard(generate.Target{Database: database, Resource: database + "s", Name: "demo", Namespace: "ns"})
+		data, err := json.Marshal(dash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		db := &unstructured.Unstructured{}
+		db.SetName("demo")
+		db.SetNamespace("ns")
+		for _, q := range parseAllExpressions(decoded, templateVars(db)) {
+			if q.err != nil {
+				t.Errorf("%s: panel %q: %v", database, q.panelTitle, q.err)
+			}
+		}
+	}
+}
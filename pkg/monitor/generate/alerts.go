@@ -0,0 +1,254 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+
+	promoperator "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+)
+
+// Target is the database the alerts and dashboards are generated for.
+type Target struct {
+	Database  string // singular resource name
+	Resource  string // plural resource name
+	Name      string
+	Namespace string
+}
+
+// ruleTemplate is a generated alert. expr is a format string taking
+//
+//	%[1]s the selector of the database's stats service series
+//	%[2]s the selector of the database's pod series
+//	%[3]s the selector of the database's volume series
+//	%[4]s the selector of the panopticon series of the database
+//	%[5]v the threshold
+type ruleTemplate struct {
+	suffix       string
+	expr         string
+	threshold    float64
+	hasThreshold bool
+	forDuration  string
+	severity     string
+	summary      string // $db is replaced by <namespace>/<name>
+}
+
+var commonRules = []ruleTemplate{
+	{suffix: "ExporterDown", expr: `max(up{%[1]s}) == 0`, forDuration: "1m", severity: severityCritical,
+		summary: "The exporter of $db is not scraped"},
+	{suffix: "PhaseNotReady", expr: `max(%[4]s) >= 1`, forDuration: "1m", severity: severityCritical,
+		summary: "$db is NotReady or Critical"},
+	{suffix: "HighCPU", expr: `sum(rate(container_cpu_usage_seconds_total{%[2]s}[5m])) / sum(kube_pod_container_resource_limits{%[2]s,resource="cpu"}) * 100 > %[5]v`,
+		threshold: 80, hasThreshold: true, forDuration: "10m", severity: severityWarning,
+		summary: "$db uses {{ $value | humanize }}% of its CPU limit"},
+	{suffix: "HighMemory", expr: `max(container_memory_working_set_bytes{%[2]s} / on (namespace, pod, container) kube_pod_container_resource_limits{%[2]s,resource="memory"}) * 100 > %[5]v`,
+		threshold: 85, hasThreshold: true, forDuration: "10m", severity: severityWarning,
+		summary: "A container of $db uses {{ $value | humanize }}% of its memory limit"},
+	{suffix: "DiskUsageHigh", expr: `max(kubelet_volume_stats_used_bytes{%[3]s} / kubelet_volume_stats_capacity_bytes{%[3]s}) * 100 > %[5]v`,
+		threshold: 80, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+		summary: "A volume of $db is {{ $value | humanize }}% full"},
+	{suffix: "PodRestarting", expr: `max(increase(kube_pod_container_status_restarts_total{%[2]s}[15m])) > %[5]v`,
+		threshold: 3, hasThreshold: true, forDuration: "1m", severity: severityWarning,
+		summary: "Pods of $db restarted {{ $value }} times in 15m"},
+}
+
+var mysqlRules = []ruleTemplate{
+	{suffix: "Down", expr: `max(mysql_up{%[1]s}) < 1`, forDuration: "1m", severity: severityCritical,
+		summary: "The exporter of $db cannot reach the database"},
+	{suffix: "TooManyConnections", expr: `max(mysql_global_status_threads_connected{%[1]s} / mysql_global_variables_max_connections{%[1]s}) * 100 > %[5]v`,
+		threshold: 80, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+		summary: "$db uses {{ $value | humanize }}% of max_connections"},
+	{suffix: "ReplicationLag", expr: `max(mysql_slave_status_seconds_behind_master{%[1]s}) > %[5]v`,
+		threshold: 30, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+		summary: "A replica of $db is {{ $value }}s behind"},
+}
+
+// engineRules are the engine specific alerts, by singular resource name.
+var engineRules = map[string][]ruleTemplate{
+	dbapi.ResourceSingularPostgres: {
+		{suffix: "Down", expr: `max(pg_up{%[1]s}) < 1`, forDuration: "1m", severity: severityCritical,
+			summary: "The exporter of $db cannot reach the database"},
+		{suffix: "TooManyConnections", expr: `sum(pg_stat_activity_count{%[1]s}) / max(pg_settings_max_connections{%[1]s}) * 100 > %[5]v`,
+			threshold: 80, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "$db uses {{ $value | humanize }}% of max_connections"},
+		{suffix: "ReplicationLag", expr: `max(pg_replication_lag{%[1]s}) > %[5]v`,
+			threshold: 30, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "A standby of $db is {{ $value }}s behind"},
+	},
+	dbapi.ResourceSingularMySQL:         mysqlRules,
+	dbapi.ResourceSingularMariaDB:       mysqlRules,
+	dbapi.ResourceSingularPerconaXtraDB: mysqlRules,
+	dbapi.ResourceSingularMongoDB: {
+		{suffix: "Down", expr: `max(mongodb_up{%[1]s}) < 1`, forDuration: "1m", severity: severityCritical,
+			summary: "The exporter of $db cannot reach the database"},
+		{suffix: "ReplicationLag", expr: `max(mongodb_mongod_replset_member_replication_lag{%[1]s}) > %[5]v`,
+			threshold: 10, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "A member of $db is {{ $value }}s behind"},
+		{suffix: "TooManyConnections", expr: `sum(mongodb_connections{%[1]s,state="current"}) / (sum(mongodb_connections{%[1]s,state="current"}) + sum(mongodb_connections{%[1]s,state="available"})) * 100 > %[5]v`,
+			threshold: 80, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "$db uses {{ $value | humanize }}% of its connections"},
+	},
+	dbapi.ResourceSingularRedis: {
+		{suffix: "Down", expr: `max(redis_up{%[1]s}) < 1`, forDuration: "1m", severity: severityCritical,
+			summary: "The exporter of $db cannot reach the database"},
+		{suffix: "MemoryHigh", expr: `max(redis_memory_used_bytes{%[1]s} / (redis_memory_max_bytes{%[1]s} > 0)) * 100 > %[5]v`,
+			threshold: 90, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "$db uses {{ $value | humanize }}% of maxmemory"},
+		{suffix: "KeysEvicted", expr: `sum(rate(redis_evicted_keys_total{%[1]s}[5m])) > %[5]v`,
+			threshold: 0, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "$db evicts {{ $value | humanize }} keys/s"},
+	},
+	dbapi.ResourceSingularElasticsearch: {
+		{suffix: "ClusterRed", expr: `max(elasticsearch_cluster_health_status{%[1]s,color="red"}) == 1`, forDuration: "1m", severity: severityCritical,
+			summary: "The cluster health of $db is red"},
+		{suffix: "UnassignedShards", expr: `max(elasticsearch_cluster_health_unassigned_shards{%[1]s}) > %[5]v`,
+			threshold: 0, hasThreshold: true, forDuration: "10m", severity: severityWarning,
+			summary: "$db has {{ $value }} unassigned shards"},
+		{suffix: "HeapHigh", expr: `max(elasticsearch_jvm_memory_used_bytes{%[1]s,area="heap"} / elasticsearch_jvm_memory_max_bytes{%[1]s,area="heap"}) * 100 > %[5]v`,
+			threshold: 90, hasThreshold: true, forDuration: "5m", severity: severityWarning,
+			summary: "A node of $db uses {{ $value | humanize }}% of its heap"},
+	},
+}
+
+// AlertPrefix is the CamelCase kind prefixed to the generated alert names.
+func AlertPrefix(database string) string {
+	switch database {
+	case dbapi.ResourceSingularMongoDB:
+		return "MongoDB"
+	case dbapi.ResourceSingularMySQL:
+		return "MySQL"
+	case dbapi.ResourceSingularMariaDB:
+		return "MariaDB"
+	case dbapi.ResourceSingularPerconaXtraDB:
+		return "PerconaXtraDB"
+	case dbapi.ResourceSingularProxySQL:
+		return "ProxySQL"
+	}
+	return strings.ToUpper(database[:1]) + database[1:]
+}
+
+func (t Target) statsSelector() string {
+	return fmt.Sprintf(`namespace=%q,service="%s-stats"`, t.Namespace, t.Name)
+}
+
+func (t Target) podSelector() string {
+	return fmt.Sprintf(`namespace=%q,pod=~"%s-[0-9]+",container!="",container!="POD"`, t.Namespace, t.Name)
+}
+
+func (t Target) volumeSelector() string {
+	return fmt.Sprintf(`namespace=%q,persistentvolumeclaim=~"data-%s-[0-9]+"`, t.Namespace, t.Name)
+}
+
+func (t Target) panopticonSeries() string {
+	return fmt.Sprintf(`kubedb_com_%s_status_phase{namespace=%q,%s=%q,phase=~"NotReady|Critical"}`, t.Database, t.Namespace, t.Database, t.Name)
+}
+
+// PrometheusRule builds the PrometheusRule of the target database. The alerts
+// carry the app, app_namespace and k8s_resource labels `monitor get-alerts`
+// and `monitor silence` match on.
+func PrometheusRule(t Target, values *Values, labels map[string]string) (*promoperator.PrometheusRule, error) {
+	prefix := AlertPrefix(t.Database)
+	templates := append(append([]ruleTemplate{}, commonRules...), engineRules[t.Database]...)
+
+	known := map[string]bool{}
+	var rules []promoperator.Rule
+	for _, tpl := range templates {
+		alert := prefix + tpl.suffix
+		known[alert] = true
+		rv := values.Rules[alert]
+		if rv.Enabled != nil && !*rv.Enabled {
+			continue
+		}
+		threshold := tpl.threshold
+		if rv.Threshold != nil {
+			if !tpl.hasThreshold {
+				return nil, fmt.Errorf("alert %s has no threshold", alert)
+			}
+			threshold = *rv.Threshold
+		}
+		forDuration := tpl.forDuration
+		if rv.For != "" {
+			if _, err := model.ParseDuration(rv.For); err != nil {
+				return nil, fmt.Errorf("invalid for of alert %s: %w", alert, err)
+			}
+			forDuration = rv.For
+		}
+		severity := tpl.severity
+		if rv.Severity != "" {
+			severity = rv.Severity
+		}
+		d := promoperator.Duration(forDuration)
+		rules = append(rules, promoperator.Rule{
+			Alert: alert,
+			Expr:  intstr.FromString(fmt.Sprintf(tpl.expr, t.statsSelector(), t.podSelector(), t.volumeSelector(), t.panopticonSeries(), strconv.FormatFloat(threshold, 'f', -1, 64))),
+			For:   &d,
+			Labels: map[string]string{
+				"severity":      severity,
+				"app":           t.Name,
+				"app_namespace": t.Namespace,
+				"k8s_resource":  t.Resource,
+				"k8s_group":     kubedb.GroupName,
+			},
+			Annotations: map[string]string{
+				"summary": strings.ReplaceAll(tpl.summary, "$db", t.Namespace+"/"+t.Name),
+			},
+		})
+	}
+
+	var unknown []string
+	for alert := range values.Rules {
+		if !known[alert] {
+			unknown = append(unknown, alert)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown alerts %s for %s", strings.Join(unknown, ", "), t.Database)
+	}
+
+	return &promoperator.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: promoperator.SchemeGroupVersion.String(),
+			Kind:       promoperator.PrometheusRuleKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      t.Name + "-alerts",
+			Namespace: t.Namespace,
+			Labels:    labels,
+		},
+		Spec: promoperator.PrometheusRuleSpec{
+			Groups: []promoperator.RuleGroup{{
+				Name:  fmt.Sprintf("%s.%s.%s", t.Database, t.Namespace, t.Name),
+				Rules: rules,
+			}},
+		},
+	}, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"kubedb.dev/cli/pkg/monitor/top"
+)
+
+// Selectors of the generated dashboard use the namespace, app and service
+// template variables, which `monitor dashboard` fills in when validating it.
+const (
+	dashboardStatsSelector  = `namespace="$namespace",service="$service"`
+	dashboardPodSelector    = `namespace="$namespace",pod=~"$app-[0-9]+",container!="",container!="POD"`
+	dashboardVolumeSelector = `namespace="$namespace",persistentvolumeclaim=~"data-$app-[0-9]+"`
+)
+
+type panelSpec struct {
+	title  string
+	expr   string
+	legend string
+	unit   string
+	kind   string
+}
+
+// Dashboard builds a Grafana dashboard model of the target database, in the
+// format `monitor dashboard --file` reads.
+func Dashboard(t Target) map[string]any {
+	specs := []panelSpec{
+		{title: "Exporter up", expr: fmt.Sprintf(`max(up{%s})`, dashboardStatsSelector), unit: "short", kind: "stat"},
+		{title: "CPU usage", expr: fmt.Sprintf(`sum by (pod) (rate(container_cpu_usage_seconds_total{%s}[$__rate_interval]))`, dashboardPodSelector), legend: "{{pod}}", unit: "short"},
+		{title: "CPU limit", expr: fmt.Sprintf(`sum by (pod) (kube_pod_container_resource_limits{%s,resource="cpu"})`, dashboardPodSelector), legend: "{{pod}}", unit: "short"},
+		{title: "Memory working set", expr: fmt.Sprintf(`sum by (pod) (container_memory_working_set_bytes{%s})`, dashboardPodSelector), legend: "{{pod}}", unit: "bytes"},
+		{title: "Memory limit", expr: fmt.Sprintf(`sum by (pod) (kube_pod_container_resource_limits{%s,resource="memory"})`, dashboardPodSelector), legend: "{{pod}}", unit: "bytes"},
+		{title: "Volume usage", expr: fmt.Sprintf(`max by (persistentvolumeclaim) (kubelet_volume_stats_used_bytes{%[1]s} / kubelet_volume_stats_capacity_bytes{%[1]s})`, dashboardVolumeSelector), legend: "{{persistentvolumeclaim}}", unit: "percentunit"},
+	}
+	for _, k := range top.KPIs(t.Database, dashboardStatsSelector) {
+		spec := panelSpec{title: k.Name, expr: k.Query, unit: grafanaUnit(k.Unit)}
+		if k.By != "" {
+			spec.legend = "{{" + k.By + "}}"
+		}
+		specs = append(specs, spec)
+	}
+
+	panels := make([]any, 0, len(specs))
+	for i, s := range specs {
+		kind := s.kind
+		if kind == "" {
+			kind = "timeseries"
+		}
+		panels = append(panels, map[string]any{
+			"id":    i + 1,
+			"type":  kind,
+			"title": s.title,
+			"datasource": map[string]any{
+				"type": "prometheus",
+				"uid":  "${datasource}",
+			},
+			"gridPos": map[string]any{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			"fieldConfig": map[string]any{
+				"defaults":  map[string]any{"unit": s.unit},
+				"overrides": []any{},
+			},
+			"targets": []any{
+				map[string]any{"refId": "A", "expr": s.expr, "legendFormat": s.legend},
+			},
+		})
+	}
+
+	return map[string]any{
+		"uid":           dashboardUID(t),
+		"title":         fmt.Sprintf("KubeDB / %s / %s / %s", AlertPrefix(t.Database), t.Namespace, t.Name),
+		"tags":          []any{"kubedb", t.Database},
+		"editable":      true,
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]any{"from": "now-1h", "to": "now"},
+		"templating": map[string]any{
+			"list": []any{
+				map[string]any{"name": "datasource", "type": "datasource", "query": "prometheus", "label": "Data source"},
+				constantVar("namespace", t.Namespace),
+				constantVar("app", t.Name),
+				constantVar("service", t.Name+"-stats"),
+			},
+		},
+		"panels": panels,
+	}
+}
+
+func constantVar(name, value string) map[string]any {
+	return map[string]any{
+		"name":    name,
+		"type":    "constant",
+		"hide":    2,
+		"query":   value,
+		"current": map[string]any{"text": value, "value": value},
+	}
+}
+
+// dashboardUID stays within the 40 characters Grafana allows.
+func dashboardUID(t Target) string {
+	sum := sha256.Sum256([]byte(t.Database + "/" + t.Namespace + "/" + t.Name))
+	return "kubedb-" + hex.EncodeToString(sum[:])[:16]
+}
+
+func grafanaUnit(unit string) string {
+	switch unit {
+	case "/s":
+		return "ops"
+	case "bytes":
+		return "bytes"
+	case "s":
+		return "s"
+	case "%":
+		return "percentunit"
+	}
+	return "short"
+}
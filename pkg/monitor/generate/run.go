@@ -0,0 +1,78 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"kubedb.dev/cli/pkg/monitor"
+
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+// AlertOptions holds the flags of `monitor generate alerts`.
+type AlertOptions struct {
+	ValuesFile string
+	Set        []string
+	Labels     map[string]string
+}
+
+func RunAlerts(f cmdutil.Factory, args []string, opts AlertOptions) {
+	t := target(f, args)
+	values, err := LoadValues(opts.ValuesFile, opts.Set)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	rule, err := PrometheusRule(t, values, opts.Labels)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	data, err := yaml.Marshal(rule)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	_, _ = os.Stdout.Write(data)
+}
+
+func RunDashboard(f cmdutil.Factory, args []string) {
+	t := target(f, args)
+	data, err := json.MarshalIndent(Dashboard(t), "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(string(data))
+}
+
+func target(f cmdutil.Factory, args []string) Target {
+	if len(args) < 2 {
+		log.Fatal("Enter database and specific database name as argument")
+	}
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return Target{
+		Database:  monitor.ConvertedResourceToSingular(args[0]),
+		Resource:  monitor.ConvertedResourceToPlural(args[0]),
+		Name:      args[1],
+		Namespace: namespace,
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// RuleValues tunes a generated alert rule. Unset fields keep the defaults.
+type RuleValues struct {
+	Enabled   *bool    `json:"enabled,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	For       string   `json:"for,omitempty"`
+	Severity  string   `json:"severity,omitempty"`
+}
+
+// Values is the values file of `monitor generate alerts`, keyed by alert name:
+//
+//	rules:
+//	  PostgresReplicationLag:
+//	    threshold: 60
+//	    for: 10m
+//	  PostgresHighCPU:
+//	    enabled: false
+type Values struct {
+	Rules map[string]RuleValues `json:"rules,omitempty"`
+}
+
+// LoadValues reads the values file, if any, and applies the --set overrides
+// of the form <Alert>.<field>=<value> on top of it.
+func LoadValues(file string, sets []string) (*Values, error) {
+	values := &Values{}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, values); err != nil {
+			return nil, fmt.Errorf("failed to parse values file %s: %w", file, err)
+		}
+	}
+	if values.Rules == nil {
+		values.Rules = map[string]RuleValues{}
+	}
+	for _, set := range sets {
+		key, val, ok := strings.Cut(set, "=")
+		alert, field, ok2 := strings.Cut(key, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid --set %q, expected <Alert>.<field>=<value>", set)
+		}
+		rv := values.Rules[alert]
+		switch field {
+		case "enabled":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --set %q: %w", set, err)
+			}
+			rv.Enabled = &b
+		case "threshold":
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --set %q: %w", set, err)
+			}
+			rv.Threshold = &f
+		case "for":
+			rv.For = val
+		case "severity":
+			rv.Severity = val
+		default:
+			return nil, fmt.Errorf("invalid --set %q, field must be one of enabled|threshold|for|severity", set)
+		}
+		values.Rules[alert] = rv
+	}
+	return values, nil
+}
@@ -19,41 +19,237 @@ package monitor
 import (
 	"fmt"
 	"log"
-	"strconv"
+	"os"
 
 	"kubedb.dev/cli/pkg/lib"
 
 	"github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/spf13/pflag"
 	"k8s.io/client-go/rest"
-	"kmodules.xyz/client-go/tools/portforward"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/yaml"
 )
 
+// PromConfigEnv names the context file used when --prom-config is not set.
+const PromConfigEnv = "KUBEDB_PROM_CONFIG"
+
+// PromSvc locates the Prometheus server the monitor commands query. Either
+// URL is set and Prometheus is reached directly, or the Name/Namespace/Port
+// service is port-forwarded to.
 type PromSvc struct {
 	Name      string
 	Namespace string
 	Port      int
+
+	PromAuth `json:",inline"`
+
+	// ConfigFile and Context select a context from a kubeconfig-style file.
+	ConfigFile string `json:"-"`
+	Context    string `json:"-"`
+}
+
+// PromAuth holds the address and credentials of a directly reachable
+// Prometheus compatible API, e.g. behind kube-rbac-proxy or Thanos Query.
+type PromAuth struct {
+	URL                   string `json:"url,omitempty"`
+	BearerToken           string `json:"bearer-token,omitempty"`
+	BearerTokenFile       string `json:"bearer-token-file,omitempty"`
+	Username              string `json:"username,omitempty"`
+	Password              string `json:"password,omitempty"`
+	CAFile                string `json:"ca-file,omitempty"`
+	CertFile              string `json:"client-cert-file,omitempty"`
+	KeyFile               string `json:"client-key-file,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecure-skip-tls-verify,omitempty"`
+}
+
+// PromConfig is the context file. It follows the kubeconfig layout:
+//
+//	current-context: prod
+//	contexts:
+//	- name: prod
+//	  context:
+//	    url: https://thanos-query.example.com
+//	    bearer-token-file: /var/run/secrets/prom/token
+//	    ca-file: /etc/prom/ca.crt
+//	- name: dev
+//	  context:
+//	    service: {name: prometheus-operated, namespace: monitoring, port: 9090}
+type PromConfig struct {
+	CurrentContext string             `json:"current-context,omitempty"`
+	Contexts       []NamedPromContext `json:"contexts"`
+}
+
+type NamedPromContext struct {
+	Name    string      `json:"name"`
+	Context PromContext `json:"context"`
+}
+
+type PromContext struct {
+	PromAuth `json:",inline"`
+	Service  *PromContextService `json:"service,omitempty"`
+}
+
+type PromContextService struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Port      int    `json:"port,omitempty"`
 }
 
-func GetPromClientAndTunnel(config *rest.Config, prom PromSvc) (promv1.API, *portforward.Tunnel) {
+// AddPromFlags registers the flags that locate and authenticate to Prometheus.
+func AddPromFlags(fs *pflag.FlagSet, prom *PromSvc) {
+	fs.StringVar(&prom.Name, "prom-svc-name", "", "name of the prometheus service")
+	fs.StringVar(&prom.Namespace, "prom-svc-namespace", "", "namespace of the prometheus service")
+	fs.IntVar(&prom.Port, "prom-svc-port", 9090, "port of the prometheus service")
+	fs.StringVar(&prom.URL, "prom-url", "", "address of a directly reachable prometheus compatible API; no port-forward is made when set")
+	fs.StringVar(&prom.BearerToken, "prom-bearer-token", "", "bearer token to authenticate to prometheus")
+	fs.StringVar(&prom.BearerTokenFile, "prom-bearer-token-file", "", "file holding the bearer token to authenticate to prometheus")
+	fs.StringVar(&prom.Username, "prom-username", "", "username for basic authentication to prometheus")
+	fs.StringVar(&prom.Password, "prom-password", "", "password for basic authentication to prometheus")
+	fs.StringVar(&prom.CAFile, "prom-ca-file", "", "CA bundle to verify the prometheus server certificate")
+	fs.StringVar(&prom.CertFile, "prom-client-cert-file", "", "client certificate for TLS authentication to prometheus")
+	fs.StringVar(&prom.KeyFile, "prom-client-key-file", "", "client key for TLS authentication to prometheus")
+	fs.BoolVar(&prom.InsecureSkipTLSVerify, "prom-insecure-skip-tls-verify", false, "skip verification of the prometheus server certificate")
+	fs.StringVar(&prom.ConfigFile, "prom-config", "", "kubeconfig-style file holding prometheus contexts, defaults to $"+PromConfigEnv)
+	fs.StringVar(&prom.Context, "prom-context", "", "context of --prom-config to use, defaults to its current-context")
+}
+
+// Complete fills the settings not given as flags from the selected context of
+// the context file. Flags always take precedence over the file.
+func (prom *PromSvc) Complete(fs *pflag.FlagSet) error {
+	file := prom.ConfigFile
+	if file == "" {
+		file = os.Getenv(PromConfigEnv)
+	}
+	if file == "" {
+		return nil
+	}
+	pc, err := loadPromConfig(file)
+	if err != nil {
+		return err
+	}
+	name := prom.Context
+	if name == "" {
+		name = pc.CurrentContext
+	}
+	if name == "" {
+		return fmt.Errorf("no context selected in %s; set current-context or --prom-context", file)
+	}
+	var ctx *PromContext
+	for i := range pc.Contexts {
+		if pc.Contexts[i].Name == name {
+			ctx = &pc.Contexts[i].Context
+			break
+		}
+	}
+	if ctx == nil {
+		return fmt.Errorf("context %q not found in %s", name, file)
+	}
+
+	setString := func(flag string, dst *string, val string) {
+		if !fs.Changed(flag) && val != "" {
+			*dst = val
+		}
+	}
+	setString("prom-url", &prom.URL, ctx.URL)
+	setString("prom-bearer-token", &prom.BearerToken, ctx.BearerToken)
+	setString("prom-bearer-token-file", &prom.BearerTokenFile, ctx.BearerTokenFile)
+	setString("prom-username", &prom.Username, ctx.Username)
+	setString("prom-password", &prom.Password, ctx.Password)
+	setString("prom-ca-file", &prom.CAFile, ctx.CAFile)
+	setString("prom-client-cert-file", &prom.CertFile, ctx.CertFile)
+	setString("prom-client-key-file", &prom.KeyFile, ctx.KeyFile)
+	if !fs.Changed("prom-insecure-skip-tls-verify") && ctx.InsecureSkipTLSVerify {
+		prom.InsecureSkipTLSVerify = true
+	}
+	if svc := ctx.Service; svc != nil {
+		setString("prom-svc-name", &prom.Name, svc.Name)
+		setString("prom-svc-namespace", &prom.Namespace, svc.Namespace)
+		if !fs.Changed("prom-svc-port") && svc.Port != 0 {
+			prom.Port = svc.Port
+		}
+	}
+	return nil
+}
+
+func loadPromConfig(file string) (*PromConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus config: %w", err)
+	}
+	var pc PromConfig
+	if err := yaml.UnmarshalStrict(data, &pc); err != nil {
+		return nil, fmt.Errorf("failed to parse prometheus config %s: %w", file, err)
+	}
+	return &pc, nil
+}
+
+// Direct reports whether Prometheus is reached through --prom-url.
+func (prom PromSvc) Direct() bool {
+	return prom.URL != ""
+}
+
+// GetPromClientAndTunnel returns a client for Prometheus and a func that
+// closes the port-forward made for it, if any.
+func GetPromClientAndTunnel(config *rest.Config, prom PromSvc) (promv1.API, func()) {
+	if prom.Direct() {
+		promClient, err := newPromClient(prom.URL, "", prom.PromAuth)
+		if err != nil {
+			log.Fatal("Error creating Prometheus client:", err)
+		}
+		return promClient, func() {}
+	}
+
 	tunnel, err := lib.TunnelToDBService(config, prom.Name, prom.Namespace, prom.Port)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	promClient := getPromClient(strconv.Itoa(tunnel.Local))
-	return promClient, tunnel
+	// credentials still apply, e.g. for kube-rbac-proxy in front of the service
+	scheme := "http"
+	if prom.CAFile != "" || prom.CertFile != "" || prom.InsecureSkipTLSVerify {
+		scheme = "https"
+	}
+	serverName := fmt.Sprintf("%s.%s.svc", prom.Name, prom.Namespace)
+	promClient, err := newPromClient(fmt.Sprintf("%s://localhost:%d/", scheme, tunnel.Local), serverName, prom.PromAuth)
+	if err != nil {
+		tunnel.Close()
+		log.Fatal("Error creating Prometheus client:", err)
+	}
+	return promClient, tunnel.Close
 }
 
-func getPromClient(localPort string) promv1.API {
-	prometheusURL := fmt.Sprintf("http://localhost:%s/", localPort)
+func newPromClient(address, serverName string, auth PromAuth) (promv1.API, error) {
+	tc := &transport.Config{
+		BearerToken:     auth.BearerToken,
+		BearerTokenFile: auth.BearerTokenFile,
+		Username:        auth.Username,
+		Password:        auth.Password,
+		TLS: transport.TLSConfig{
+			CAFile:     auth.CAFile,
+			CertFile:   auth.CertFile,
+			KeyFile:    auth.KeyFile,
+			Insecure:   auth.InsecureSkipTLSVerify,
+			ServerName: serverName,
+		},
+	}
+	if tc.HasBasicAuth() && tc.HasTokenAuth() {
+		return nil, fmt.Errorf("bearer token and basic auth are mutually exclusive")
+	}
+	if (auth.CertFile == "") != (auth.KeyFile == "") {
+		return nil, fmt.Errorf("--prom-client-cert-file and --prom-client-key-file must be set together")
+	}
+	rt, err := transport.New(tc)
+	if err != nil {
+		return nil, err
+	}
 
 	client, err := api.NewClient(api.Config{
-		Address: prometheusURL,
+		Address:      address,
+		RoundTripper: rt,
 	})
 	if err != nil {
-		log.Fatal("Error creating Prometheus client:", err)
+		return nil, err
 	}
-
-	return promv1.NewAPI(client)
+	return promv1.NewAPI(client), nil
 }
@@ -0,0 +1,231 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"kubedb.dev/cli/pkg/monitor"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// seriesPerMetric caps the rows printed for a metric in the summary.
+const seriesPerMetric = 5
+
+// Options holds the flags of `monitor scrape`.
+type Options struct {
+	Raw    bool
+	Filter string
+}
+
+var mysqlMetrics = []string{
+	"mysql_up",
+	"mysql_global_status_uptime",
+	"mysql_global_status_queries",
+	"mysql_global_status_threads_connected",
+	"mysql_global_status_threads_running",
+	"mysql_global_status_innodb_buffer_pool_reads",
+	"mysql_global_status_innodb_buffer_pool_read_requests",
+	"mysql_slave_status_seconds_behind_master",
+}
+
+// keyMetrics are summarized by default, by singular resource name.
+var keyMetrics = map[string][]string{
+	dbapi.ResourceSingularPostgres: {
+		"pg_up",
+		"pg_postmaster_start_time_seconds",
+		"pg_stat_activity_count",
+		"pg_stat_database_xact_commit",
+		"pg_stat_database_xact_rollback",
+		"pg_stat_database_blks_hit",
+		"pg_stat_database_blks_read",
+		"pg_replication_lag",
+		"pg_database_size_bytes",
+	},
+	dbapi.ResourceSingularMySQL:         mysqlMetrics,
+	dbapi.ResourceSingularMariaDB:       mysqlMetrics,
+	dbapi.ResourceSingularPerconaXtraDB: mysqlMetrics,
+	dbapi.ResourceSingularMongoDB: {
+		"mongodb_up",
+		"mongodb_op_counters_total",
+		"mongodb_connections",
+		"mongodb_mongod_replset_member_replication_lag",
+		"mongodb_mongod_wiredtiger_cache_bytes",
+	},
+	dbapi.ResourceSingularRedis: {
+		"redis_up",
+		"redis_uptime_in_seconds",
+		"redis_commands_processed_total",
+		"redis_connected_clients",
+		"redis_memory_used_bytes",
+		"redis_evicted_keys_total",
+	},
+	dbapi.ResourceSingularElasticsearch: {
+		"elasticsearch_clusterinfo_up",
+		"elasticsearch_cluster_health_status",
+		"elasticsearch_cluster_health_number_of_nodes",
+		"elasticsearch_cluster_health_unassigned_shards",
+		"elasticsearch_jvm_memory_used_bytes",
+		"elasticsearch_indices_docs",
+	},
+	dbapi.ResourceSingularKafka: {
+		"kafka_controller_kafkacontroller_activebrokercount",
+		"kafka_server_replicamanager_underreplicatedpartitions",
+		"kafka_controller_kafkacontroller_offlinepartitionscount",
+	},
+	dbapi.ResourceSingularProxySQL: {
+		"proxysql_uptime_seconds_total",
+		"proxysql_client_connections_connected",
+		"proxysql_questions_total",
+	},
+}
+
+func Run(f cmdutil.Factory, args []string, opts Options) {
+	if len(args) < 2 {
+		log.Fatal("Enter database and specific database name as argument")
+	}
+	database := monitor.ConvertedResourceToSingular(args[0])
+	name := args[1]
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var filter *regexp.Regexp
+	if opts.Filter != "" {
+		if filter, err = regexp.Compile(opts.Filter); err != nil {
+			log.Fatalf("invalid --filter: %v", err)
+		}
+	}
+
+	result, err := Exporter(config, name, namespace)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if opts.Raw {
+		for _, mf := range result.Families {
+			if filter != nil && !filter.MatchString(mf.GetName()) {
+				continue
+			}
+			if _, err := expfmt.MetricFamilyToText(os.Stdout, mf); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		return
+	}
+
+	fmt.Printf("Scraped %s/%s:%d%s in %s: %d metric families, %d series\n\n",
+		namespace, result.Service, result.Port, result.Path, result.Duration.Round(time.Millisecond), len(result.Families), countSeries(result.Families))
+
+	var names []string
+	switch {
+	case filter != nil:
+		for _, mf := range result.Families {
+			if filter.MatchString(mf.GetName()) {
+				names = append(names, mf.GetName())
+			}
+		}
+	default:
+		var ok bool
+		if names, ok = keyMetrics[database]; !ok {
+			fmt.Printf("No key metrics are known for %s, use --filter or --raw to inspect the exporter output.\n", database)
+			return
+		}
+	}
+	if err := printSummary(os.Stdout, result, names); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func printSummary(out io.Writer, result *Result, names []string) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METRIC\tLABELS\tVALUE")
+	for _, name := range names {
+		mf := result.Family(name)
+		if mf == nil {
+			_, _ = fmt.Fprintf(w, "%s\t\tnot exported\n", name)
+			continue
+		}
+		metrics := mf.GetMetric()
+		for i, m := range metrics {
+			if i == seriesPerMetric {
+				_, _ = fmt.Fprintf(w, "%s\t(%d more series)\t\n", name, len(metrics)-seriesPerMetric)
+				break
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, renderLabels(m), formatValue(Value(m)))
+		}
+	}
+	return w.Flush()
+}
+
+// Value returns the sample value of a counter, gauge or untyped metric, and
+// the sample count of a summary or histogram.
+func Value(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	case m.Summary != nil:
+		return float64(m.Summary.GetSampleCount())
+	case m.Histogram != nil:
+		return float64(m.Histogram.GetSampleCount())
+	}
+	return math.NaN()
+}
+
+func countSeries(families []*dto.MetricFamily) int {
+	n := 0
+	for _, mf := range families {
+		n += len(mf.GetMetric())
+	}
+	return n
+}
+
+func renderLabels(m *dto.Metric) string {
+	parts := make([]string, 0, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		parts = append(parts, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"kubedb.dev/cli/pkg/lib"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	mona "kmodules.xyz/monitoring-agent-api/api/v1"
+)
+
+// Result is a single scrape of a database exporter.
+type Result struct {
+	Service  string
+	Port     int32
+	Path     string
+	Duration time.Duration
+	Families []*dto.MetricFamily // sorted by name
+}
+
+// Family returns the metric family with the given name, or nil.
+func (r *Result) Family(name string) *dto.MetricFamily {
+	i := sort.Search(len(r.Families), func(i int) bool { return r.Families[i].GetName() >= name })
+	if i < len(r.Families) && r.Families[i].GetName() == name {
+		return r.Families[i]
+	}
+	return nil
+}
+
+// Exporter port-forwards to the <db>-stats service of a database and scrapes
+// its exporter, without going through Prometheus.
+func Exporter(config *rest.Config, name, namespace string) (*Result, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	svcName := name + "-stats"
+	svc, err := kubeClient.CoreV1().Services(namespace).Get(context.TODO(), svcName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats service %s/%s, is spec.monitor set? %w", namespace, svcName, err)
+	}
+	port, err := metricsPort(svc)
+	if err != nil {
+		return nil, err
+	}
+	path := svc.Annotations["prometheus.io/path"]
+	if path == "" {
+		path = "/metrics"
+	}
+
+	tunnel, err := lib.TunnelToDBService(config, svcName, namespace, int(port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to port-forward to stats service %s/%s: %w", namespace, svcName, err)
+	}
+	defer tunnel.Close()
+
+	start := time.Now()
+	families, err := fetch(fmt.Sprintf("http://localhost:%d%s", tunnel.Local, path))
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Service:  svcName,
+		Port:     port,
+		Path:     path,
+		Duration: time.Since(start),
+		Families: families,
+	}, nil
+}
+
+func metricsPort(svc *core.Service) (int32, error) {
+	for _, p := range svc.Spec.Ports {
+		if p.Name == mona.PrometheusExporterPortName {
+			return p.Port, nil
+		}
+	}
+	if len(svc.Spec.Ports) > 0 {
+		return svc.Spec.Ports[0].Port, nil
+	}
+	return 0, fmt.Errorf("stats service %s/%s has no ports", svc.Namespace, svc.Name)
+}
+
+func fetch(url string) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// ask for the text format, the parser does not support protobuf or OpenMetrics
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape exporter: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("exporter returned %s: %s", resp.Status, body)
+	}
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	byName, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exporter response: %w", err)
+	}
+	families := make([]*dto.MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package silence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"kubedb.dev/cli/pkg/lib"
+	"kubedb.dev/cli/pkg/monitor"
+
+	"k8s.io/client-go/rest"
+	"kmodules.xyz/client-go/tools/portforward"
+)
+
+// Matcher, Silence and Status mirror the Alertmanager v2 API objects.
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type Status struct {
+	State string `json:"state"`
+}
+
+type Silence struct {
+	ID        string    `json:"id,omitempty"`
+	Status    *Status   `json:"status,omitempty"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+const (
+	StateActive  = "active"
+	StatePending = "pending"
+	StateExpired = "expired"
+)
+
+// Client talks to the Alertmanager v2 API.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClientAndTunnel port-forwards to the Alertmanager service and returns a
+// client for the local end of the tunnel.
+func NewClientAndTunnel(config *rest.Config, am monitor.AlertmanagerSvc) (*Client, *portforward.Tunnel, error) {
+	tunnel, err := lib.TunnelToDBService(config, am.Name, am.Namespace, am.Port)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to port-forward to alertmanager service %s/%s: %w", am.Namespace, am.Name, err)
+	}
+	return &Client{
+		baseURL: fmt.Sprintf("http://localhost:%d/api/v2", tunnel.Local),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}, tunnel, nil
+}
+
+func (c *Client) Create(s Silence) (string, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.do(http.MethodPost, "/silences", bytes.NewReader(body), &resp); err != nil {
+		return "", err
+	}
+	return resp.SilenceID, nil
+}
+
+// List returns the silences whose matchers contain all of the given ones.
+func (c *Client) List(matchers []Matcher) ([]Silence, error) {
+	q := url.Values{}
+	for _, m := range matchers {
+		q.Add("filter", fmt.Sprintf("%s=%q", m.Name, m.Value))
+	}
+	path := "/silences"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var silences []Silence
+	if err := c.do(http.MethodGet, path, nil, &silences); err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+func (c *Client) Expire(id string) error {
+	return c.do(http.MethodDelete, "/silence/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *Client) do(method, path string, body io.Reader, into any) error {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("alertmanager returned %s for %s %s: %s", resp.Status, method, path, bytes.TrimSpace(data))
+	}
+	if into == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, into)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package silence
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kubedb.dev/cli/pkg/monitor"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// Options holds the flags of `monitor silence`.
+type Options struct {
+	For     time.Duration
+	Comment string
+	Author  string
+}
+
+// DatabaseMatchers matches the alerts of a single database, using the same
+// labels `monitor get-alerts` queries. resource is the plural resource name.
+func DatabaseMatchers(resource, name, namespace string) []Matcher {
+	return []Matcher{
+		{Name: "k8s_resource", Value: resource, IsEqual: true},
+		{Name: "app", Value: name, IsEqual: true},
+		{Name: "app_namespace", Value: namespace, IsEqual: true},
+	}
+}
+
+// DefaultAuthor is recorded as createdBy when --author is not given.
+func DefaultAuthor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "kubectl-dba"
+}
+
+// ForDatabase silences the alerts of a database for opts.For and returns the
+// silence ID. It is used by the maintenance commands, e.g. pause --silence.
+func ForDatabase(config *rest.Config, am monitor.AlertmanagerSvc, resource, name, namespace string, opts Options) (string, error) {
+	c, tunnel, err := NewClientAndTunnel(config, am)
+	if err != nil {
+		return "", err
+	}
+	defer tunnel.Close()
+	return create(c, resource, name, namespace, opts)
+}
+
+func create(c *Client, resource, name, namespace string, opts Options) (string, error) {
+	if opts.For <= 0 {
+		return "", fmt.Errorf("silence duration must be positive, got %s", opts.For)
+	}
+	if opts.Author == "" {
+		opts.Author = DefaultAuthor()
+	}
+	if opts.Comment == "" {
+		opts.Comment = fmt.Sprintf("Maintenance of %s %s/%s", resource, namespace, name)
+	}
+	now := time.Now()
+	return c.Create(Silence{
+		Matchers:  DatabaseMatchers(resource, name, namespace),
+		StartsAt:  now,
+		EndsAt:    now.Add(opts.For),
+		CreatedBy: opts.Author,
+		Comment:   opts.Comment,
+	})
+}
+
+func Run(f cmdutil.Factory, args []string, am monitor.AlertmanagerSvc, opts Options) {
+	if len(args) < 2 {
+		log.Fatal("Enter db object's kind and name as arguments")
+	}
+	resource := monitor.ConvertedResourceToPlural(args[0])
+	namespace, config := namespaceAndConfig(f)
+
+	c, tunnel, err := NewClientAndTunnel(config, am)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer tunnel.Close()
+
+	id, err := create(c, resource, args[1], namespace, opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("Silenced alerts of %s %s/%s until %s (silence %s)\n", resource, namespace, args[1], time.Now().Add(opts.For).Format(time.RFC3339), id)
+}
+
+// RunList lists the active and pending silences, of one database when kind
+// and name are given.
+func RunList(f cmdutil.Factory, args []string, am monitor.AlertmanagerSvc) {
+	namespace, config := namespaceAndConfig(f)
+	var matchers []Matcher
+	if len(args) >= 2 {
+		matchers = DatabaseMatchers(monitor.ConvertedResourceToPlural(args[0]), args[1], namespace)
+	}
+
+	c, tunnel, err := NewClientAndTunnel(config, am)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer tunnel.Close()
+
+	silences, err := c.List(matchers)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tSTATE\tMATCHERS\tENDS IN\tCREATED BY\tCOMMENT")
+	now := time.Now()
+	n := 0
+	for _, s := range silences {
+		if s.Status != nil && s.Status.State == StateExpired {
+			continue
+		}
+		n++
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, state(s), renderMatchers(s.Matchers), duration.HumanDuration(s.EndsAt.Sub(now)), s.CreatedBy, s.Comment)
+	}
+	if n == 0 {
+		fmt.Println("No active silences found.")
+		return
+	}
+	_ = w.Flush()
+}
+
+// RunExpire expires the given silence IDs, or every active silence of a
+// database when kind and name are given.
+func RunExpire(f cmdutil.Factory, args []string, am monitor.AlertmanagerSvc, ids []string) {
+	namespace, config := namespaceAndConfig(f)
+	if len(ids) == 0 && len(args) < 2 {
+		log.Fatal("Enter db object's kind and name as arguments, or the silence IDs with --id")
+	}
+
+	c, tunnel, err := NewClientAndTunnel(config, am)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer tunnel.Close()
+
+	if len(ids) == 0 {
+		silences, err := c.List(DatabaseMatchers(monitor.ConvertedResourceToPlural(args[0]), args[1], namespace))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, s := range silences {
+			if s.Status == nil || s.Status.State != StateExpired {
+				ids = append(ids, s.ID)
+			}
+		}
+		if len(ids) == 0 {
+			fmt.Printf("No active silences found for %s/%s\n", namespace, args[1])
+			return
+		}
+	}
+	for _, id := range ids {
+		if err := c.Expire(id); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Expired silence %s\n", id)
+	}
+}
+
+func namespaceAndConfig(f cmdutil.Factory) (string, *rest.Config) {
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return namespace, config
+}
+
+func state(s Silence) string {
+	if s.Status == nil {
+		return "-"
+	}
+	return s.Status.State
+}
+
+func renderMatchers(matchers []Matcher) string {
+	parts := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		op := "="
+		switch {
+		case m.IsRegex && !m.IsEqual:
+			op = "!~"
+		case m.IsRegex:
+			op = "=~"
+		case !m.IsEqual:
+			op = "!="
+		}
+		parts = append(parts, m.Name+op+m.Value)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,106 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"fmt"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+)
+
+type unit string
+
+const (
+	unitRate    unit = "/s"
+	unitCount   unit = ""
+	unitBytes   unit = "bytes"
+	unitSeconds unit = "s"
+	unitRatio   unit = "%"
+)
+
+// kpi is a key performance indicator of a database engine. query is a format
+// string taking the label selector of the database's stats service; when by is
+// set the result is shown broken down by that label.
+type kpi struct {
+	name  string
+	query string
+	unit  unit
+	by    string
+}
+
+var mysqlKPIs = []kpi{
+	{name: "QPS", query: `sum(rate(mysql_global_status_queries{%[1]s}[1m]))`, unit: unitRate},
+	{name: "Threads connected", query: `sum(mysql_global_status_threads_connected{%[1]s})`, unit: unitCount},
+	{name: "Threads running", query: `sum(mysql_global_status_threads_running{%[1]s})`, unit: unitCount},
+	{name: "InnoDB buffer pool hit", query: `1 - sum(rate(mysql_global_status_innodb_buffer_pool_reads{%[1]s}[1m])) / sum(rate(mysql_global_status_innodb_buffer_pool_read_requests{%[1]s}[1m]))`, unit: unitRatio},
+	{name: "Replica lag", query: `max(mysql_slave_status_seconds_behind_master{%[1]s})`, unit: unitSeconds},
+}
+
+// engineKPIs holds the KPIs shown by `top`, by singular resource name.
+var engineKPIs = map[string][]kpi{
+	dbapi.ResourceSingularPostgres: {
+		{name: "TPS", query: `sum(rate(pg_stat_database_xact_commit{%[1]s}[1m])) + sum(rate(pg_stat_database_xact_rollback{%[1]s}[1m]))`, unit: unitRate},
+		{name: "Connections", query: `sum(pg_stat_activity_count{%[1]s})`, unit: unitCount},
+		{name: "Replication lag", query: `max(pg_replication_lag{%[1]s})`, unit: unitSeconds},
+		{name: "Cache hit ratio", query: `sum(rate(pg_stat_database_blks_hit{%[1]s}[1m])) / (sum(rate(pg_stat_database_blks_hit{%[1]s}[1m])) + sum(rate(pg_stat_database_blks_read{%[1]s}[1m])))`, unit: unitRatio},
+	},
+	dbapi.ResourceSingularMySQL:         mysqlKPIs,
+	dbapi.ResourceSingularMariaDB:       mysqlKPIs,
+	dbapi.ResourceSingularPerconaXtraDB: mysqlKPIs,
+	dbapi.ResourceSingularMongoDB: {
+		{name: "Opcounters", query: `sum by (type) (rate(mongodb_op_counters_total{%[1]s}[1m]))`, unit: unitRate, by: "type"},
+		{name: "Replication lag", query: `max(mongodb_mongod_replset_member_replication_lag{%[1]s})`, unit: unitSeconds},
+		{name: "WiredTiger cache used", query: `sum(mongodb_mongod_wiredtiger_cache_bytes{%[1]s,type="total"}) / sum(mongodb_mongod_wiredtiger_cache_bytes_total{%[1]s})`, unit: unitRatio},
+		{name: "Connections", query: `sum(mongodb_connections{%[1]s,state="current"})`, unit: unitCount},
+	},
+	dbapi.ResourceSingularRedis: {
+		{name: "Ops", query: `sum(rate(redis_commands_processed_total{%[1]s}[1m]))`, unit: unitRate},
+		{name: "Memory used", query: `sum(redis_memory_used_bytes{%[1]s})`, unit: unitBytes},
+		{name: "Evictions", query: `sum(rate(redis_evicted_keys_total{%[1]s}[1m]))`, unit: unitRate},
+		{name: "Connected clients", query: `sum(redis_connected_clients{%[1]s})`, unit: unitCount},
+	},
+	dbapi.ResourceSingularElasticsearch: {
+		{name: "Indexing rate", query: `sum(rate(elasticsearch_indices_indexing_index_total{%[1]s}[1m]))`, unit: unitRate},
+		{name: "Search rate", query: `sum(rate(elasticsearch_indices_search_query_total{%[1]s}[1m]))`, unit: unitRate},
+		{name: "JVM heap used", query: `sum(elasticsearch_jvm_memory_used_bytes{%[1]s,area="heap"}) / sum(elasticsearch_jvm_memory_max_bytes{%[1]s,area="heap"})`, unit: unitRatio},
+		{name: "Unassigned shards", query: `max(elasticsearch_cluster_health_unassigned_shards{%[1]s})`, unit: unitCount},
+	},
+}
+
+// KPI is a key performance indicator with its query rendered for a selector.
+// Unit is one of "/s", "bytes", "s", "%" or empty for plain counts.
+type KPI struct {
+	Name  string
+	Query string
+	Unit  string
+	By    string
+}
+
+// KPIs returns the key performance indicators of a database engine, by
+// singular resource name, with their queries scoped to selector.
+func KPIs(database, selector string) []KPI {
+	var out []KPI
+	for _, k := range engineKPIs[database] {
+		out = append(out, KPI{
+			Name:  k.name,
+			Query: fmt.Sprintf(k.query, selector),
+			Unit:  string(k.unit),
+			By:    k.by,
+		})
+	}
+	return out
+}
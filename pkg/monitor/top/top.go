@@ -0,0 +1,451 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	"kubedb.dev/cli/pkg/monitor"
+	"kubedb.dev/cli/pkg/monitor/connection"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// Options holds the flags of `top`.
+type Options struct {
+	Interval time.Duration
+	Once     bool
+}
+
+type topOpts struct {
+	database  string // singular resource name
+	resource  string // plural resource name
+	name      string
+	namespace string
+
+	kubeClient kubernetes.Interface
+	promAPI    promv1.API
+}
+
+func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc, opts Options) {
+	if len(args) < 2 {
+		log.Fatal("Enter database and specific database name as argument")
+	}
+	if opts.Interval <= 0 {
+		log.Fatal("--interval must be positive")
+	}
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	t := &topOpts{
+		database:  monitor.ConvertedResourceToSingular(args[0]),
+		resource:  monitor.ConvertedResourceToPlural(args[0]),
+		name:      args[1],
+		namespace: namespace,
+	}
+	if err := t.checkDatabase(f); err != nil {
+		log.Fatalln(err)
+	}
+	t.kubeClient, err = kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	promClient, closeTunnel := monitor.GetPromClientAndTunnel(config, prom)
+	defer closeTunnel()
+	t.promAPI = promClient
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		var buf bytes.Buffer
+		if err := t.render(ctx, &buf, opts); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Fatalln(err)
+		}
+		if !opts.Once {
+			fmt.Print(clearScreen)
+		}
+		_, _ = os.Stdout.Write(buf.Bytes())
+		if opts.Once {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+func (t *topOpts) checkDatabase(f cmdutil.Factory) error {
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Group: kubedb.GroupName, Resource: t.resource})
+	if err != nil {
+		return err
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	_, err = dc.Resource(gvr).Namespace(t.namespace).Get(context.TODO(), t.name, metav1.GetOptions{})
+	return err
+}
+
+type podStat struct {
+	name   string
+	role   string
+	phase  core.PodPhase
+	pvcs   []string
+	cpu    usage // cores
+	memory usage // bytes
+	disk   usage // bytes, req is the volume capacity
+}
+
+type usage struct {
+	used, req, lim float64
+}
+
+func (t *topOpts) render(ctx context.Context, out *bytes.Buffer, opts Options) error {
+	pods, err := t.pods(ctx)
+	if err != nil {
+		return err
+	}
+	if err := t.fillUsage(ctx, pods); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(out, "%s %s/%s   exporter: %s   %s", t.database, t.namespace, t.name, t.exporterState(ctx), time.Now().Format(time.TimeOnly))
+	if !opts.Once {
+		_, _ = fmt.Fprintf(out, "   every %s, Ctrl+C to quit", opts.Interval)
+	}
+	_, _ = fmt.Fprint(out, "\n\n")
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "POD\tROLE\tSTATUS\tCPU USED/REQ/LIM\tMEMORY USED/REQ/LIM\tDISK USED/SIZE")
+	for _, p := range pods {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.name, orDash(p.role), p.phase,
+			p.cpu.render(formatCores), p.memory.render(formatBytes), renderDisk(p.disk))
+	}
+	if len(pods) == 0 {
+		_, _ = fmt.Fprintln(w, "no pods found\t\t\t\t\t")
+	}
+	_ = w.Flush()
+
+	kpis, ok := engineKPIs[t.database]
+	if !ok {
+		_, _ = fmt.Fprintf(out, "\nNo KPIs are known for %s\n", t.database)
+		return nil
+	}
+	_, _ = fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KPI\tVALUE")
+	for _, k := range kpis {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", k.name, t.evalKPI(ctx, k))
+	}
+	return w.Flush()
+}
+
+func (t *topOpts) pods(ctx context.Context) ([]*podStat, error) {
+	selector := labels.SelectorFromSet(map[string]string{
+		meta_util.InstanceLabelKey: t.name,
+		meta_util.NameLabelKey:     t.resource + "." + kubedb.GroupName,
+	})
+	list, err := t.kubeClient.CoreV1().Pods(t.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*podStat, 0, len(list.Items))
+	for _, pod := range list.Items {
+		p := &podStat{
+			name:  pod.Name,
+			role:  pod.Labels[kubedb.LabelRole],
+			phase: pod.Status.Phase,
+		}
+		for _, c := range pod.Spec.Containers {
+			p.cpu.req += float64(c.Resources.Requests.Cpu().MilliValue()) / 1000
+			p.cpu.lim += float64(c.Resources.Limits.Cpu().MilliValue()) / 1000
+			p.memory.req += float64(c.Resources.Requests.Memory().Value())
+			p.memory.lim += float64(c.Resources.Limits.Memory().Value())
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				p.pvcs = append(p.pvcs, v.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		pods = append(pods, p)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].name < pods[j].name })
+	return pods, nil
+}
+
+// fillUsage queries cAdvisor and kubelet volume metrics for the pods.
+func (t *topOpts) fillUsage(ctx context.Context, pods []*podStat) error {
+	if len(pods) == 0 {
+		return nil
+	}
+	var podNames, pvcNames []string
+	for _, p := range pods {
+		podNames = append(podNames, regexp.QuoteMeta(p.name))
+		for _, c := range p.pvcs {
+			pvcNames = append(pvcNames, regexp.QuoteMeta(c))
+		}
+	}
+	podSel := fmt.Sprintf(`namespace=%q,pod=~%q,container!="",container!="POD"`, t.namespace, strings.Join(podNames, "|"))
+	cpu, err := t.byLabel(ctx, fmt.Sprintf(`sum by (pod) (rate(container_cpu_usage_seconds_total{%s}[1m]))`, podSel), "pod")
+	if err != nil {
+		return err
+	}
+	mem, err := t.byLabel(ctx, fmt.Sprintf(`sum by (pod) (container_memory_working_set_bytes{%s})`, podSel), "pod")
+	if err != nil {
+		return err
+	}
+	var diskUsed, diskSize map[string]float64
+	if len(pvcNames) > 0 {
+		pvcSel := fmt.Sprintf(`namespace=%q,persistentvolumeclaim=~%q`, t.namespace, strings.Join(pvcNames, "|"))
+		diskUsed, err = t.byLabel(ctx, fmt.Sprintf(`max by (persistentvolumeclaim) (kubelet_volume_stats_used_bytes{%s})`, pvcSel), "persistentvolumeclaim")
+		if err != nil {
+			return err
+		}
+		diskSize, err = t.byLabel(ctx, fmt.Sprintf(`max by (persistentvolumeclaim) (kubelet_volume_stats_capacity_bytes{%s})`, pvcSel), "persistentvolumeclaim")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range pods {
+		p.cpu.used = valueOrNaN(cpu, p.name)
+		p.memory.used = valueOrNaN(mem, p.name)
+		p.disk = usage{used: math.NaN(), req: math.NaN()}
+		for _, c := range p.pvcs {
+			if v, ok := diskUsed[c]; ok {
+				p.disk.used = addKnown(p.disk.used, v)
+			}
+			if v, ok := diskSize[c]; ok {
+				p.disk.req = addKnown(p.disk.req, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *topOpts) exporterState(ctx context.Context) string {
+	metric, ok := connection.DBUpMetric(t.database)
+	if !ok {
+		return "unknown"
+	}
+	v, err := t.scalar(ctx, fmt.Sprintf(`max(%s{%s})`, metric, t.statsSelector()))
+	switch {
+	case err != nil:
+		return "error: " + err.Error()
+	case math.IsNaN(v):
+		return "no data"
+	case v > 0:
+		return "up"
+	}
+	return "down"
+}
+
+// statsSelector selects the series scraped from the database's stats service.
+func (t *topOpts) statsSelector() string {
+	return fmt.Sprintf(`service="%s-stats",namespace=%q`, t.name, t.namespace)
+}
+
+func (t *topOpts) evalKPI(ctx context.Context, k kpi) string {
+	query := fmt.Sprintf(k.query, t.statsSelector())
+	if k.by == "" {
+		v, err := t.scalar(ctx, query)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return formatValue(v, k.unit)
+	}
+	values, err := t.byLabel(ctx, query, k.by)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if len(values) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+formatValue(values[key], k.unit))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (t *topOpts) query(ctx context.Context, query string) (model.Vector, error) {
+	result, _, err := t.promAPI.Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", query, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s for query %s", result.Type(), query)
+	}
+	return vector, nil
+}
+
+// scalar returns the value of a single-sample query, NaN when it has no data.
+func (t *topOpts) scalar(ctx context.Context, query string) (float64, error) {
+	vector, err := t.query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(vector) == 0 {
+		return math.NaN(), nil
+	}
+	return float64(vector[0].Value), nil
+}
+
+func (t *topOpts) byLabel(ctx context.Context, query, label string) (map[string]float64, error) {
+	vector, err := t.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(vector))
+	for _, s := range vector {
+		values[string(s.Metric[model.LabelName(label)])] = float64(s.Value)
+	}
+	return values, nil
+}
+
+func valueOrNaN(values map[string]float64, key string) float64 {
+	if v, ok := values[key]; ok {
+		return v
+	}
+	return math.NaN()
+}
+
+func addKnown(sum, v float64) float64 {
+	if math.IsNaN(sum) {
+		return v
+	}
+	return sum + v
+}
+
+func (u usage) render(format func(float64) string) string {
+	return fmt.Sprintf("%s/%s/%s", format(u.used), formatLimit(u.req, format), formatLimit(u.lim, format))
+}
+
+func renderDisk(u usage) string {
+	s := fmt.Sprintf("%s/%s", formatBytes(u.used), formatBytes(u.req))
+	if !math.IsNaN(u.used) && !math.IsNaN(u.req) && u.req > 0 {
+		s += fmt.Sprintf(" (%.0f%%)", u.used/u.req*100)
+	}
+	return s
+}
+
+// formatLimit renders an unset request or limit as "-".
+func formatLimit(v float64, format func(float64) string) string {
+	if v == 0 {
+		return "-"
+	}
+	return format(v)
+}
+
+func formatCores(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return resource.NewMilliQuantity(int64(math.Round(v*1000)), resource.DecimalSI).String()
+}
+
+func formatBytes(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "-"
+	}
+	const unit = 1024
+	if v < unit {
+		return fmt.Sprintf("%.0fB", v)
+	}
+	exp := 0
+	for n := v / unit; n >= unit && exp < 4; n /= unit {
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", v/math.Pow(unit, float64(exp+1)), "KMGTP"[exp])
+}
+
+func formatValue(v float64, u unit) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "-"
+	}
+	switch u {
+	case unitRate:
+		return fmt.Sprintf("%.1f/s", v)
+	case unitBytes:
+		return formatBytes(v)
+	case unitSeconds:
+		return fmt.Sprintf("%.1fs", v)
+	case unitRatio:
+		return fmt.Sprintf("%.1f%%", v*100)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommend
+
+import (
+	"fmt"
+	"strings"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// opsNode returns the field of spec.verticalScaling and spec.volumeExpansion
+// that holds the database node of a non-topology database.
+func (r *recommender) opsNode() (string, error) {
+	for _, field := range []string{"topology", "shardTopology"} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(r.db.Object, "spec", field); found {
+			return "", fmt.Errorf("%s uses spec.%s; create the OpsRequest per node type", r.db.GetName(), field)
+		}
+	}
+	switch r.database {
+	case dbapi.ResourceSingularMongoDB:
+		if _, found, _ := unstructured.NestedFieldNoCopy(r.db.Object, "spec", "replicaSet"); found {
+			return "replicaSet", nil
+		}
+		return "standalone", nil
+	case dbapi.ResourceSingularElasticsearch, dbapi.ResourceSingularKafka:
+		return "node", nil
+	}
+	return r.database, nil
+}
+
+func (r *recommender) verticalScalingOps(containers []*containerRec) (*unstructured.Unstructured, error) {
+	node, err := r.opsNode()
+	if err != nil {
+		return nil, err
+	}
+	main := r.pods[0].Spec.Containers[0].Name
+
+	spec := map[string]any{}
+	for _, c := range containers {
+		if c.cpu == nil || c.memory == nil {
+			continue
+		}
+		field := ""
+		switch {
+		case c.name == main:
+			field = node
+		case strings.Contains(c.name, "coordinator"):
+			field = "coordinator"
+		case strings.Contains(c.name, "exporter"):
+			field = "exporter"
+		default:
+			continue
+		}
+		res, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&opsapi.ContainerResources{Resources: recommendedResources(c)})
+		if err != nil {
+			return nil, err
+		}
+		spec[field] = res
+	}
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("no usage data for the containers")
+	}
+	return r.opsRequest("vscale", "VerticalScaling", "verticalScaling", spec), nil
+}
+
+// recommendedResources keeps the memory limit equal to the request, as KubeDB
+// does, and never lowers an existing CPU limit below the new request.
+func recommendedResources(c *containerRec) core.ResourceRequirements {
+	res := core.ResourceRequirements{
+		Requests: core.ResourceList{
+			core.ResourceCPU:    *c.cpu,
+			core.ResourceMemory: *c.memory,
+		},
+		Limits: core.ResourceList{
+			core.ResourceMemory: *c.memory,
+		},
+	}
+	if limit, ok := c.current.Limits[core.ResourceCPU]; ok {
+		if limit.Cmp(*c.cpu) < 0 {
+			limit = *c.cpu
+		}
+		res.Limits[core.ResourceCPU] = limit
+	}
+	return res
+}
+
+func (r *recommender) volumeExpansionOps(size *resource.Quantity) (*unstructured.Unstructured, error) {
+	node, err := r.opsNode()
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]any{
+		"mode": r.opts.ExpansionMode,
+		node:   size.String(),
+	}
+	return r.opsRequest("expand", string(opsapi.VolumeExpansion), "volumeExpansion", spec), nil
+}
+
+func (r *recommender) opsRequest(suffix, opsType, field string, spec map[string]any) *unstructured.Unstructured {
+	ops := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"type":        opsType,
+			"databaseRef": map[string]any{"name": r.db.GetName()},
+			field:         spec,
+		},
+	}}
+	ops.SetAPIVersion(opsapi.SchemeGroupVersion.String())
+	ops.SetKind(r.kind + "OpsRequest")
+	ops.SetName(fmt.Sprintf("%s-%s-%s", r.db.GetName(), suffix, r.generated.Format("20060102-1504")))
+	ops.SetNamespace(r.db.GetNamespace())
+	return ops
+}
@@ -0,0 +1,483 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	opsapi "kubedb.dev/apimachinery/apis/ops/v1alpha1"
+	"kubedb.dev/cli/pkg/debug"
+	"kubedb.dev/cli/pkg/monitor"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	meta_util "kmodules.xyz/client-go/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	cpuStep     = 50   // millicores
+	memoryStep  = 64   // MiB
+	storageStep = 1024 // MiB
+	mi          = 1024 * 1024
+)
+
+// Options holds the flags of `recommend`.
+type Options struct {
+	Window           string
+	CPUPercentile    float64
+	MemoryPercentile float64
+	Headroom         float64
+	StorageHorizon   string
+	EmitOps          bool
+	ExpansionMode    string
+}
+
+type recommender struct {
+	opts      Options
+	window    model.Duration
+	horizon   time.Duration
+	kind      string
+	database  string // singular resource name
+	db        *unstructured.Unstructured
+	pods      []core.Pod
+	promAPI   promv1.API
+	kc        client.Client
+	out       io.Writer
+	generated time.Time
+}
+
+// containerRec is the recommendation for one container, merged over all pods.
+type containerRec struct {
+	name      string
+	current   core.ResourceRequirements
+	cpuPct    float64 // cores at the CPU percentile
+	cpuMax    float64
+	memPct    float64 // bytes at the memory percentile
+	memMax    float64
+	cpu       *resource.Quantity
+	memory    *resource.Quantity
+	rationale []string
+}
+
+type storageRec struct {
+	current     *resource.Quantity
+	used        float64
+	capacity    float64
+	growthDay   float64
+	recommended *resource.Quantity
+	rationale   string
+}
+
+func Run(f cmdutil.Factory, args []string, prom monitor.PromSvc, opts Options) {
+	if len(args) < 2 {
+		log.Fatal("Enter database and specific database name as argument")
+	}
+	r, err := newRecommender(f, args[0], args[1], opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	promClient, closeTunnel := monitor.GetPromClientAndTunnel(config, prom)
+	defer closeTunnel()
+	r.promAPI = promClient
+
+	// with --emit-ops the manifests go to stdout so they can be piped to kubectl apply
+	r.out = os.Stdout
+	if opts.EmitOps {
+		r.out = os.Stderr
+	}
+	if err := r.run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func newRecommender(f cmdutil.Factory, kind, name string, opts Options) (*recommender, error) {
+	window, err := model.ParseDuration(opts.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --window: %w", err)
+	}
+	horizon, err := model.ParseDuration(opts.StorageHorizon)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --storage-horizon: %w", err)
+	}
+	if opts.CPUPercentile <= 0 || opts.CPUPercentile > 1 || opts.MemoryPercentile <= 0 || opts.MemoryPercentile > 1 {
+		return nil, fmt.Errorf("percentiles must be in (0, 1]")
+	}
+	if opts.Headroom < 1 {
+		return nil, fmt.Errorf("--headroom must be at least 1")
+	}
+	if opts.ExpansionMode != string(opsapi.VolumeExpansionModeOnline) && opts.ExpansionMode != string(opsapi.VolumeExpansionModeOffline) {
+		return nil, fmt.Errorf("--expansion-mode must be Online or Offline")
+	}
+
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	plural := monitor.ConvertedResourceToPlural(kind)
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Group: kubedb.GroupName, Resource: plural})
+	if err != nil {
+		return nil, err
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	db, err := dc.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(map[string]string{
+		meta_util.InstanceLabelKey: name,
+		meta_util.NameLabelKey:     plural + "." + kubedb.GroupName,
+	})
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no pods found for %s %s/%s", gvk.Kind, namespace, name)
+	}
+
+	kc, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &recommender{
+		opts:      opts,
+		window:    window,
+		horizon:   time.Duration(horizon),
+		kind:      gvk.Kind,
+		database:  monitor.ConvertedResourceToSingular(kind),
+		db:        db,
+		pods:      pods.Items,
+		kc:        kc,
+		generated: time.Now(),
+	}, nil
+}
+
+func (r *recommender) run() error {
+	containers, err := r.recommendContainers()
+	if err != nil {
+		return err
+	}
+	storage, err := r.recommendStorage()
+	if err != nil {
+		return err
+	}
+	computeScaler, storageScaler, err := r.autoscalers()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(r.out, "%s %s/%s, usage over the last %s\n\n", r.kind, r.db.GetNamespace(), r.db.GetName(), r.window)
+	w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CONTAINER\tRESOURCE\tREQUEST\tLIMIT\tOBSERVED\tRECOMMENDED\tRATIONALE")
+	for _, c := range containers {
+		_, _ = fmt.Fprintf(w, "%s\tcpu\t%s\t%s\tp%.0f %s, max %s\t%s\t%s\n", c.name,
+			quantity(c.current.Requests, core.ResourceCPU), quantity(c.current.Limits, core.ResourceCPU),
+			r.opts.CPUPercentile*100, cores(c.cpuPct), cores(c.cpuMax), orDash(c.cpu), c.rationale[0])
+		_, _ = fmt.Fprintf(w, "%s\tmemory\t%s\t%s\tp%.0f %s, max %s\t%s\t%s\n", c.name,
+			quantity(c.current.Requests, core.ResourceMemory), quantity(c.current.Limits, core.ResourceMemory),
+			r.opts.MemoryPercentile*100, bytes(c.memPct), bytes(c.memMax), orDash(c.memory), c.rationale[1])
+	}
+	if storage != nil {
+		_, _ = fmt.Fprintf(w, "-\tstorage\t%s\t-\t%s used, %s/day\t%s\t%s\n",
+			orDash(storage.current), bytes(storage.used), bytes(storage.growthDay), orDash(storage.recommended), storage.rationale)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if computeScaler != "" {
+		_, _ = fmt.Fprintf(r.out, "\n%sAutoscaler %s scales compute resources; apply the recommendation through it instead of a VerticalScaling OpsRequest.\n", r.kind, computeScaler)
+	}
+	if storageScaler != "" {
+		_, _ = fmt.Fprintf(r.out, "\n%sAutoscaler %s expands storage; apply the recommendation through it instead of a VolumeExpansion OpsRequest.\n", r.kind, storageScaler)
+	}
+	if !r.opts.EmitOps {
+		return nil
+	}
+
+	var manifests []*unstructured.Unstructured
+	if computeScaler == "" {
+		ops, err := r.verticalScalingOps(containers)
+		if err != nil {
+			_, _ = fmt.Fprintf(r.out, "\nNo VerticalScaling OpsRequest generated: %v\n", err)
+		} else if ops != nil {
+			manifests = append(manifests, ops)
+		}
+	}
+	if storageScaler == "" && storage != nil && storage.recommended != nil {
+		ops, err := r.volumeExpansionOps(storage.recommended)
+		if err != nil {
+			_, _ = fmt.Fprintf(r.out, "\nNo VolumeExpansion OpsRequest generated: %v\n", err)
+		} else {
+			manifests = append(manifests, ops)
+		}
+	}
+	for i, m := range manifests {
+		data, err := yaml.Marshal(m.Object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+func (r *recommender) podSelector(container string) string {
+	names := make([]string, 0, len(r.pods))
+	for _, p := range r.pods {
+		names = append(names, regexp.QuoteMeta(p.Name))
+	}
+	return fmt.Sprintf(`namespace=%q,pod=~%q,container=%q`, r.db.GetNamespace(), strings.Join(names, "|"), container)
+}
+
+func (r *recommender) recommendContainers() ([]*containerRec, error) {
+	var recs []*containerRec
+	seen := map[string]*containerRec{}
+	for _, pod := range r.pods {
+		for _, c := range pod.Spec.Containers {
+			if _, ok := seen[c.Name]; !ok {
+				rec := &containerRec{name: c.Name, current: c.Resources}
+				seen[c.Name] = rec
+				recs = append(recs, rec)
+			}
+		}
+	}
+
+	for _, c := range recs {
+		sel := r.podSelector(c.name)
+		var err error
+		if c.cpuPct, err = r.scalar(fmt.Sprintf(`max(quantile_over_time(%v, rate(container_cpu_usage_seconds_total{%s}[5m])[%s:5m]))`, r.opts.CPUPercentile, sel, r.window)); err != nil {
+			return nil, err
+		}
+		if c.cpuMax, err = r.scalar(fmt.Sprintf(`max(max_over_time(rate(container_cpu_usage_seconds_total{%s}[5m])[%s:5m]))`, sel, r.window)); err != nil {
+			return nil, err
+		}
+		if c.memPct, err = r.scalar(fmt.Sprintf(`max(quantile_over_time(%v, container_memory_working_set_bytes{%s}[%s]))`, r.opts.MemoryPercentile, sel, r.window)); err != nil {
+			return nil, err
+		}
+		if c.memMax, err = r.scalar(fmt.Sprintf(`max(max_over_time(container_memory_working_set_bytes{%s}[%s]))`, sel, r.window)); err != nil {
+			return nil, err
+		}
+
+		if math.IsNaN(c.cpuPct) {
+			c.rationale = append(c.rationale, "no cpu data in window")
+		} else {
+			milli := roundUp(c.cpuPct*r.opts.Headroom*1000, cpuStep)
+			c.cpu = resource.NewMilliQuantity(int64(milli), resource.DecimalSI)
+			c.rationale = append(c.rationale, compare(c.current.Requests.Cpu(), c.cpu, fmt.Sprintf("p%.0f x %.2f headroom", r.opts.CPUPercentile*100, r.opts.Headroom)))
+		}
+		if math.IsNaN(c.memPct) {
+			c.rationale = append(c.rationale, "no memory data in window")
+		} else {
+			// memory is not compressible, never go below the observed peak
+			need := math.Max(c.memPct*r.opts.Headroom, c.memMax)
+			c.memory = resource.NewQuantity(int64(roundUp(need/mi, memoryStep))*mi, resource.BinarySI)
+			c.rationale = append(c.rationale, compare(c.current.Requests.Memory(), c.memory, fmt.Sprintf("max(p%.0f x %.2f headroom, peak)", r.opts.MemoryPercentile*100, r.opts.Headroom)))
+		}
+	}
+	return recs, nil
+}
+
+func (r *recommender) recommendStorage() (*storageRec, error) {
+	var pvcs []string
+	for _, pod := range r.pods {
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				pvcs = append(pvcs, regexp.QuoteMeta(v.PersistentVolumeClaim.ClaimName))
+			}
+		}
+	}
+	if len(pvcs) == 0 {
+		return nil, nil
+	}
+	sel := fmt.Sprintf(`namespace=%q,persistentvolumeclaim=~%q`, r.db.GetNamespace(), strings.Join(pvcs, "|"))
+
+	s := &storageRec{}
+	if q, found, _ := unstructured.NestedString(r.db.Object, "spec", "storage", "resources", "requests", "storage"); found {
+		if v, err := resource.ParseQuantity(q); err == nil {
+			s.current = &v
+		}
+	}
+	var err error
+	if s.used, err = r.scalar(fmt.Sprintf(`max(kubelet_volume_stats_used_bytes{%s})`, sel)); err != nil {
+		return nil, err
+	}
+	if s.capacity, err = r.scalar(fmt.Sprintf(`max(kubelet_volume_stats_capacity_bytes{%s})`, sel)); err != nil {
+		return nil, err
+	}
+	if s.growthDay, err = r.scalar(fmt.Sprintf(`max(deriv(kubelet_volume_stats_used_bytes{%s}[%s])) * 86400`, sel, r.window)); err != nil {
+		return nil, err
+	}
+	if math.IsNaN(s.used) || math.IsNaN(s.capacity) {
+		s.rationale = "no volume data in window"
+		return s, nil
+	}
+
+	growth := math.Max(s.growthDay, 0)
+	need := (s.used + growth*r.horizon.Hours()/24) * r.opts.Headroom
+	if need <= s.capacity {
+		if growth > 0 {
+			s.rationale = fmt.Sprintf("keep; full in %.0f days at the current growth", (s.capacity-s.used)/growth)
+		} else {
+			s.rationale = "keep; usage is not growing"
+		}
+		return s, nil
+	}
+	s.recommended = resource.NewQuantity(int64(roundUp(need/mi, storageStep))*mi, resource.BinarySI)
+	full := "now"
+	if growth > 0 {
+		full = fmt.Sprintf("in %.0f days", math.Max((s.capacity-s.used)/growth, 0))
+	}
+	s.rationale = fmt.Sprintf("expand; full %s, sized for %s of growth x %.2f headroom", full, model.Duration(r.horizon), r.opts.Headroom)
+	return s, nil
+}
+
+// autoscalers returns the names of the <Kind>Autoscalers that scale compute
+// resources and storage of the database.
+func (r *recommender) autoscalers() (compute, storage string, err error) {
+	scalers, err := debug.ListAutoscalers(r.kc, r.kind, metav1.ObjectMeta{Name: r.db.GetName(), Namespace: r.db.GetNamespace()})
+	if err != nil {
+		// the autoscaler CRDs are optional
+		return "", "", nil
+	}
+	for _, sc := range scalers {
+		if compute == "" && triggered(sc, "compute") {
+			compute = sc.GetName()
+		}
+		if storage == "" && triggered(sc, "storage") {
+			storage = sc.GetName()
+		}
+	}
+	return compute, storage, nil
+}
+
+// triggered reports whether any node of spec.<field> of an autoscaler is on.
+func triggered(sc unstructured.Unstructured, field string) bool {
+	nodes, _, _ := unstructured.NestedMap(sc.Object, "spec", field)
+	for _, v := range nodes {
+		if node, ok := v.(map[string]any); ok && node["trigger"] == "On" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *recommender) scalar(query string) (float64, error) {
+	result, _, err := r.promAPI.Query(context.TODO(), query, r.generated)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", query, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok || len(vector) == 0 {
+		return math.NaN(), nil
+	}
+	return float64(vector[0].Value), nil
+}
+
+func compare(current, recommended *resource.Quantity, how string) string {
+	if current == nil || current.IsZero() {
+		return how + "; no request set"
+	}
+	ratio := float64(recommended.MilliValue()) / float64(current.MilliValue())
+	switch {
+	case ratio < 0.8:
+		return fmt.Sprintf("%s; over-provisioned, %.0f%% of the request", how, ratio*100)
+	case ratio > 1:
+		return fmt.Sprintf("%s; under-provisioned, %.0f%% of the request", how, ratio*100)
+	}
+	return how + "; request fits"
+}
+
+func roundUp(v, step float64) float64 {
+	return math.Max(math.Ceil(v/step), 1) * step
+}
+
+func quantity(list core.ResourceList, name core.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return "-"
+}
+
+func orDash(q *resource.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return q.String()
+}
+
+func cores(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return resource.NewMilliQuantity(int64(math.Ceil(v*1000)), resource.DecimalSI).String()
+}
+
+func bytes(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.0fMi", math.Ceil(v/mi))
+}
@@ -28,8 +28,6 @@ import (
 	"os"
 	"time"
 
-	"kubedb.dev/cli/pkg/common"
-
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
@@ -138,7 +136,7 @@ func issueClientCertFromCA(userName string, dnsSANs []string, caCertPEM, caKeyPE
 // files given on the command line — no cert-manager involved — and packages it as
 // the kubernetes.io/tls Secret the remote replica will mount (ca.crt, tls.crt,
 // tls.key; the operator remaps tls.* to client.* at mount time).
-func generateTlsSecretFromLocalCA(userName, ns, caCertPath, caKeyPath string, dnsSANs []string, opts *common.PostgresOpts) ([]byte, string, error) {
+func generateTlsSecretFromLocalCA(userName, ns, caCertPath, caKeyPath string, dnsSANs []string, dbName string) ([]byte, string, error) {
 	caCertPEM, err := os.ReadFile(caCertPath)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to read --ca-cert: %v", err)
@@ -158,7 +156,7 @@ func generateTlsSecretFromLocalCA(userName, ns, caCertPath, caKeyPath string, dn
 			APIVersion: ApiversionV1,
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-remote-replica-client-cert", opts.DB.Name),
+			Name:      fmt.Sprintf("%s-remote-replica-client-cert", dbName),
 			Namespace: ns,
 		},
 		Type: core.SecretTypeTLS,
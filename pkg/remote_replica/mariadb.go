@@ -0,0 +1,239 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"kubedb.dev/cli/pkg/common"
+
+	cm_api "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	cm_util "kmodules.xyz/cert-manager-util/certmanager/v1"
+	kutil "kmodules.xyz/client-go"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	core_util "kmodules.xyz/client-go/core/v1"
+	appApi "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// mariadbReplicationGrants lets the replica stream the binlog (REPLICATION
+// SLAVE/CLIENT) and take the initial consistent dump of every schema.
+const mariadbReplicationGrants = "REPLICATION SLAVE, REPLICATION CLIENT, SELECT, RELOAD, LOCK TABLES, SHOW VIEW, EVENT, TRIGGER"
+
+func MariaDBAPP(f cmdutil.Factory) *cobra.Command {
+	var o remoteConfigFlags
+	cmd := cobra.Command{
+		Use:     "mariadb",
+		Short:   desLong,
+		Long:    desLong,
+		Example: "kubectl dba remote-config mariadb -n <ns> -u <user_name> -p$<password> -d<dns_name> [--replica-name <name>] --remote-kubeconfig <remote.yaml> <db_name>",
+		Args:    nil,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.validate(cmd, args)
+			buffer, err := generateMariaDBConfig(f, &o, args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			cfg := emitRemoteConfig(&o.remote, buffer)
+			if cfg != nil && o.replicaName != "" {
+				replica := dbapi.MariaDB{ObjectMeta: metav1.ObjectMeta{Name: o.replicaName, Namespace: o.ns}}
+				if err := waitForRemoteReplica(cfg, dbapi.ResourcePluralMariaDB, o.ns, o.replicaName, replica.OffshootSelectors(), o.timeout, mariadbStreaming); err != nil {
+					log.Fatal(err)
+				}
+			}
+		},
+		DisableAutoGenTag:     false,
+		DisableFlagsInUseLine: false,
+	}
+	addRemoteConfigFlags(&cmd, &o, "repl", 3306)
+	return &cmd
+}
+
+// mariadbStreaming reports whether both replication threads of the replica are
+// running.
+func mariadbStreaming(config *rest.Config, pod *core.Pod) (bool, error) {
+	out, err := mysqlQuery(config, pod, "mariadb", "root", "", true, "SHOW SLAVE STATUS")
+	if err != nil {
+		return false, err
+	}
+	st := parseVerticalStatus(out)
+	return st["Slave_IO_Running"] == "Yes" && st["Slave_SQL_Running"] == "Yes", nil
+}
+
+func generateMariaDBConfig(f cmdutil.Factory, o *remoteConfigFlags, dbname string) ([]byte, error) {
+	opts, err := common.NewMariaDBOpts(f, dbname, o.ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get db %s, err:%v", dbname, err)
+	}
+	if o.replicaName != "" {
+		if err := requireRemoteReplicaField(f, dbapi.ResourcePluralMariaDB); err != nil {
+			return nil, err
+		}
+	}
+
+	apb, err := opts.AppcatClient.AppcatalogV1alpha1().AppBindings(o.ns).Get(context.TODO(), dbname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appbinding %v", err)
+	}
+
+	password := o.password
+	if o.userName != opts.Username {
+		if err := generateMariaDBUser(opts, o.userName, o.password); err != nil {
+			return nil, fmt.Errorf("failed to generate user err:%v", err)
+		}
+	} else {
+		password = opts.Pass
+	}
+	buffer, err := authSecretYaml(o.authSecretName, o.ns, o.userName, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate auth secret ,%v", err)
+	}
+
+	var tlsSecretName string
+	switch {
+	case o.caCertPath != "":
+		var tlsBuff []byte
+		tlsBuff, tlsSecretName, err = generateTlsSecretFromLocalCA(o.userName, o.ns, o.caCertPath, o.caKeyPath, o.clientSANs, dbname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tls secret from --ca-cert: %v", err)
+		}
+		buffer = append(buffer, tlsBuff...)
+	case apb.Spec.TLSSecret != nil && opts.DB.Spec.TLS != nil:
+		if _, err := ensureMariaDBClientCert(opts, apb, opts.DB, dbapi.MariaDBClientCert, o.userName, o.clientSANs); err != nil {
+			return nil, fmt.Errorf("failed to ensure client cert %v", err)
+		}
+		tlsSecretName = opts.DB.GetCertSecretName(dbapi.MariaDBClientCert) + fmt.Sprintf("-%s", o.userName)
+		tlsBuff, err := clientCertSecretYaml(opts.Client, o.ns, tlsSecretName)
+		if err != nil {
+			return nil, err
+		}
+		buffer = append(buffer, tlsBuff...)
+	}
+
+	appbindingYaml, err := yaml.Marshal(remoteAppBinding(apb, o.ns, o.dns, o.port, o.authSecretName, tlsSecretName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal appbind yaml %v", err)
+	}
+	buffer = append(buffer, appbindingYaml...)
+
+	if o.replicaName != "" {
+		replicaYaml, err := generateMariaDBReplicaSpec(opts.DB, o.replicaName, o.ns, apb.Name, o.authSecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate replica spec %v", err)
+		}
+		buffer = append(buffer, []byte("---\n")...)
+		buffer = append(buffer, replicaYaml...)
+	}
+	return buffer, nil
+}
+
+// generateMariaDBReplicaSpec emits a remote replica MariaDB manifest sized from
+// the source: version, replicas, storage and the mariadb container's resources.
+// spec.tls, monitoring and the Galera/replication topology are not copied; the
+// replica follows the source through the AppBinding and needs its own issuer.
+func generateMariaDBReplicaSpec(src *dbapi.MariaDB, name, ns, sourceRefName, authSecretName string) ([]byte, error) {
+	spec := map[string]any{
+		"version":     src.Spec.Version,
+		"storageType": src.Spec.StorageType,
+	}
+	if src.Spec.Replicas != nil {
+		spec["replicas"] = *src.Spec.Replicas
+	}
+	if src.Spec.Storage != nil {
+		spec["storage"] = src.Spec.Storage
+	}
+	if pt := containerResources(src.Spec.PodTemplate.Spec.Containers, "mariadb"); pt != nil {
+		spec["podTemplate"] = pt
+	}
+	return remoteReplicaManifest(dbapi.ResourceKindMariaDB, name, ns, sourceRefName, authSecretName, spec)
+}
+
+func generateMariaDBUser(opts *common.MariaDBOpts, name string, password string) error {
+	pod, err := primaryPod(opts.Client, opts.DB.Namespace, opts.DB.OffshootLabels())
+	if err != nil {
+		return err
+	}
+	account := sqlLiteral(name) + "@'%'"
+	query := "CREATE USER IF NOT EXISTS " + account + "; ALTER USER " + account + " IDENTIFIED BY " + sqlLiteral(password) + "; " +
+		"GRANT " + mariadbReplicationGrants + " ON *.* TO " + account + "; FLUSH PRIVILEGES;"
+	_, err = mysqlQuery(opts.Config, pod, "mariadb", opts.Username, opts.Pass, false, query)
+	return err
+}
+
+func ensureMariaDBClientCert(opts *common.MariaDBOpts, apb *appApi.AppBinding, mariadb *dbapi.MariaDB, alias dbapi.MariaDBCertificateAlias, username string, extraSANs []string) (kutil.VerbType, error) {
+	var duration, renewBefore *metav1.Duration
+	var subject *cm_api.X509Subject
+	var dnsNames, ipAddresses, uriSANs, emailSANs []string
+	if _, cert := kmapi.GetCertificate(mariadb.Spec.TLS.Certificates, string(alias)); cert != nil {
+		dnsNames = cert.DNSNames
+		ipAddresses = cert.IPAddresses
+		duration = cert.Duration
+		renewBefore = cert.RenewBefore
+		subject = x509Subject(cert.Subject)
+		uriSANs = cert.URIs
+		emailSANs = cert.EmailAddresses
+	}
+
+	ref := metav1.NewControllerRef(apb, appApi.SchemeGroupVersion.WithKind(appApi.ResourceKindApp))
+
+	_, vt, err := cm_util.CreateOrPatchCertificate(
+		context.TODO(),
+		opts.CertManagerClient.CertmanagerV1(),
+		metav1.ObjectMeta{
+			Name:      mariadb.CertificateName(alias) + fmt.Sprintf("-%s", username),
+			Namespace: mariadb.GetNamespace(),
+		},
+		func(in *cm_api.Certificate) *cm_api.Certificate {
+			in.Labels = mariadb.OffshootLabels()
+			core_util.EnsureOwnerReference(in, ref)
+
+			in.Spec.CommonName = username
+			in.Spec.Subject = subject
+			in.Spec.Duration = duration
+			in.Spec.RenewBefore = renewBefore
+			in.Spec.DNSNames = sets.NewString(append(dnsNames, extraSANs...)...).List()
+			in.Spec.IPAddresses = sets.NewString(ipAddresses...).List()
+			in.Spec.URIs = sets.NewString(uriSANs...).List()
+			in.Spec.EmailAddresses = sets.NewString(emailSANs...).List()
+			in.Spec.SecretName = mariadb.GetCertSecretName(alias) + fmt.Sprintf("-%s", username)
+			in.Spec.IssuerRef = GetIssuerObjectRef(mariadb.Spec.TLS, string(alias))
+			in.Spec.Usages = []cm_api.KeyUsage{
+				cm_api.UsageDigitalSignature,
+				cm_api.UsageKeyEncipherment,
+				cm_api.UsageClientAuth,
+			}
+			if isCertMangerAdditionalOutputEnabled(opts.CertManagerClient) {
+				in.Spec.AdditionalOutputFormats = []cm_api.CertificateAdditionalOutputFormat{
+					{
+						Type: cm_api.CertificateOutputFormatCombinedPEM,
+					},
+				}
+			}
+			return in
+		}, metav1.PatchOptions{},
+	)
+	return vt, err
+}
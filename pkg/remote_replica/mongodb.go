@@ -0,0 +1,282 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"kubedb.dev/cli/pkg/common"
+
+	cm_api "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	cm_util "kmodules.xyz/cert-manager-util/certmanager/v1"
+	kutil "kmodules.xyz/client-go"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	core_util "kmodules.xyz/client-go/core/v1"
+	exec_util "kmodules.xyz/client-go/tools/exec"
+	appApi "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// mongodbReplicationRoles lets the replica read the oplog (read on local),
+// the replica set state (clusterMonitor) and every database for the initial
+// sync (readAnyDatabase).
+const mongodbReplicationRoles = `[{role: "clusterMonitor", db: "admin"}, {role: "readAnyDatabase", db: "admin"}, {role: "read", db: "local"}]`
+
+// mongodbShell runs the mongo shell with the arguments that follow on the script
+// read from stdin: the binary is mongosh on recent MongoDB versions and mongo on
+// older ones. The script goes through a file rather than --eval so that the
+// credentials in it never show up in the pod's process list.
+const mongodbShell = `F=$(mktemp) && trap 'rm -f "$F"' EXIT && cat > "$F" && M=$(command -v mongosh || command -v mongo) && "$M" "$@" "$F"`
+
+func MongoDBAPP(f cmdutil.Factory) *cobra.Command {
+	var o remoteConfigFlags
+	cmd := cobra.Command{
+		Use:     "mongodb",
+		Short:   desLong,
+		Long:    desLong,
+		Example: "kubectl dba remote-config mongodb -n <ns> -u <user_name> -p$<password> -d<dns_name> [--replica-name <name>] --remote-kubeconfig <remote.yaml> <db_name>",
+		Args:    nil,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.validate(cmd, args)
+			buffer, err := generateMongoDBConfig(f, &o, args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			cfg := emitRemoteConfig(&o.remote, buffer)
+			if cfg != nil && o.replicaName != "" {
+				replica := dbapi.MongoDB{ObjectMeta: metav1.ObjectMeta{Name: o.replicaName, Namespace: o.ns}}
+				if err := waitForRemoteReplica(cfg, dbapi.ResourcePluralMongoDB, o.ns, o.replicaName, replica.OffshootSelectors(), o.timeout, nil); err != nil {
+					log.Fatal(err)
+				}
+			}
+		},
+		DisableAutoGenTag:     false,
+		DisableFlagsInUseLine: false,
+	}
+	addRemoteConfigFlags(&cmd, &o, "repl", 27017)
+	return &cmd
+}
+
+func generateMongoDBConfig(f cmdutil.Factory, o *remoteConfigFlags, dbname string) ([]byte, error) {
+	opts, err := common.NewMongoDBOpts(f, dbname, o.ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get db %s, err:%v", dbname, err)
+	}
+	if opts.DB.Spec.ShardTopology != nil {
+		return nil, fmt.Errorf("MongoDB %s/%s is sharded; a remote replica can only follow a standalone or replica set MongoDB", o.ns, dbname)
+	}
+	if o.replicaName != "" {
+		if err := requireRemoteReplicaField(f, dbapi.ResourcePluralMongoDB); err != nil {
+			return nil, err
+		}
+	}
+
+	apb, err := opts.AppcatClient.AppcatalogV1alpha1().AppBindings(o.ns).Get(context.TODO(), dbname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appbinding %v", err)
+	}
+
+	password := o.password
+	if o.userName != opts.Username {
+		if err := generateMongoDBUser(opts, o.userName, o.password); err != nil {
+			return nil, fmt.Errorf("failed to generate user err:%v", err)
+		}
+	} else {
+		password = opts.Pass
+	}
+	buffer, err := authSecretYaml(o.authSecretName, o.ns, o.userName, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate auth secret ,%v", err)
+	}
+
+	var tlsSecretName string
+	switch {
+	case o.caCertPath != "":
+		var tlsBuff []byte
+		tlsBuff, tlsSecretName, err = generateTlsSecretFromLocalCA(o.userName, o.ns, o.caCertPath, o.caKeyPath, o.clientSANs, dbname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tls secret from --ca-cert: %v", err)
+		}
+		buffer = append(buffer, tlsBuff...)
+	case apb.Spec.TLSSecret != nil && opts.DB.Spec.TLS != nil:
+		if _, err := ensureMongoDBClientCert(opts, apb, opts.DB, dbapi.MongoDBClientCert, o.userName, o.clientSANs); err != nil {
+			return nil, fmt.Errorf("failed to ensure client cert %v", err)
+		}
+		tlsSecretName = opts.DB.GetCertSecretName(dbapi.MongoDBClientCert, "") + fmt.Sprintf("-%s", o.userName)
+		tlsBuff, err := clientCertSecretYaml(opts.Client, o.ns, tlsSecretName)
+		if err != nil {
+			return nil, err
+		}
+		buffer = append(buffer, tlsBuff...)
+	}
+
+	appbindingYaml, err := yaml.Marshal(remoteAppBinding(apb, o.ns, o.dns, o.port, o.authSecretName, tlsSecretName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal appbind yaml %v", err)
+	}
+	buffer = append(buffer, appbindingYaml...)
+
+	if o.replicaName != "" {
+		replicaYaml, err := generateMongoDBReplicaSpec(opts.DB, o.replicaName, o.ns, apb.Name, o.authSecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate replica spec %v", err)
+		}
+		buffer = append(buffer, []byte("---\n")...)
+		buffer = append(buffer, replicaYaml...)
+	}
+	return buffer, nil
+}
+
+// generateMongoDBReplicaSpec emits a remote replica MongoDB manifest sized from
+// the source: version, replicas, the replica set, storage engine, storage and
+// the mongodb container's resources. spec.tls and monitoring are not copied;
+// the remote cluster has its own issuer.
+func generateMongoDBReplicaSpec(src *dbapi.MongoDB, name, ns, sourceRefName, authSecretName string) ([]byte, error) {
+	spec := map[string]any{
+		"version":     src.Spec.Version,
+		"storageType": src.Spec.StorageType,
+	}
+	if src.Spec.Replicas != nil {
+		spec["replicas"] = *src.Spec.Replicas
+	}
+	if src.Spec.ReplicaSet != nil {
+		spec["replicaSet"] = src.Spec.ReplicaSet
+	}
+	if src.Spec.StorageEngine != "" {
+		spec["storageEngine"] = src.Spec.StorageEngine
+	}
+	if src.Spec.Storage != nil {
+		spec["storage"] = src.Spec.Storage
+	}
+	if src.Spec.PodTemplate != nil {
+		if pt := containerResources(src.Spec.PodTemplate.Spec.Containers, "mongodb"); pt != nil {
+			spec["podTemplate"] = pt
+		}
+	}
+	return remoteReplicaManifest(dbapi.ResourceKindMongoDB, name, ns, sourceRefName, authSecretName, spec)
+}
+
+func generateMongoDBUser(opts *common.MongoDBOpts, name string, password string) error {
+	pod, err := primaryPod(opts.Client, opts.DB.Namespace, opts.DB.OffshootLabels())
+	if err != nil {
+		return err
+	}
+	// JSON string literals are valid JavaScript, and the script reaches the shell
+	// as a single argument, so neither value needs any further quoting.
+	user, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	pwd, err := json.Marshal(password)
+	if err != nil {
+		return err
+	}
+	eval := fmt.Sprintf("var roles = %s; if (db.getUser(%s)) { db.updateUser(%s, {pwd: %s, roles: roles}); } else { db.createUser({user: %s, pwd: %s, roles: roles}); }",
+		mongodbReplicationRoles, user, user, pwd, user, pwd)
+	_, err = mongoEval(opts.Config, pod, opts.Username, opts.Pass, opts.DB.Spec.TLS != nil, eval)
+	return err
+}
+
+// mongoEval evaluates a script in the admin database as the given user. The
+// user authenticates from within the script, which is passed on stdin.
+func mongoEval(config *rest.Config, pod *core.Pod, user, password string, tls bool, eval string) (string, error) {
+	u, err := json.Marshal(user)
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(password)
+	if err != nil {
+		return "", err
+	}
+	// mongosh throws on a failed auth; the legacy shell returns 0.
+	script := fmt.Sprintf("if (!db.auth(%s, %s)) { quit(1); }\n%s\n", u, p, eval)
+	shellArgs := []string{"admin", "--quiet"}
+	if tls {
+		shellArgs = append(shellArgs, "--tls", "--tlsCAFile", "/var/run/mongodb/tls/ca.crt", "--tlsCertificateKeyFile", "/var/run/mongodb/tls/client.pem")
+	}
+	options := []func(options *exec_util.Options){
+		exec_util.Command(append([]string{"bash", "-c", mongodbShell, "mongodb-remote-config"}, shellArgs...)...),
+		exec_util.Container("mongodb"),
+		exec_util.Input(script),
+	}
+	return exec_util.ExecIntoPod(config, pod, options...)
+}
+
+func ensureMongoDBClientCert(opts *common.MongoDBOpts, apb *appApi.AppBinding, mongodb *dbapi.MongoDB, alias dbapi.MongoDBCertificateAlias, username string, extraSANs []string) (kutil.VerbType, error) {
+	var duration, renewBefore *metav1.Duration
+	var subject *cm_api.X509Subject
+	var dnsNames, ipAddresses, uriSANs, emailSANs []string
+	if _, cert := kmapi.GetCertificate(mongodb.Spec.TLS.Certificates, string(alias)); cert != nil {
+		dnsNames = cert.DNSNames
+		ipAddresses = cert.IPAddresses
+		duration = cert.Duration
+		renewBefore = cert.RenewBefore
+		subject = x509Subject(cert.Subject)
+		uriSANs = cert.URIs
+		emailSANs = cert.EmailAddresses
+	}
+
+	ref := metav1.NewControllerRef(apb, appApi.SchemeGroupVersion.WithKind(appApi.ResourceKindApp))
+
+	_, vt, err := cm_util.CreateOrPatchCertificate(
+		context.TODO(),
+		opts.CertManagerClient.CertmanagerV1(),
+		metav1.ObjectMeta{
+			Name:      mongodb.CertificateName(alias, "") + fmt.Sprintf("-%s", username),
+			Namespace: mongodb.GetNamespace(),
+		},
+		func(in *cm_api.Certificate) *cm_api.Certificate {
+			in.Labels = mongodb.OffshootLabels()
+			core_util.EnsureOwnerReference(in, ref)
+
+			in.Spec.CommonName = username
+			in.Spec.Subject = subject
+			in.Spec.Duration = duration
+			in.Spec.RenewBefore = renewBefore
+			in.Spec.DNSNames = sets.NewString(append(dnsNames, extraSANs...)...).List()
+			in.Spec.IPAddresses = sets.NewString(ipAddresses...).List()
+			in.Spec.URIs = sets.NewString(uriSANs...).List()
+			in.Spec.EmailAddresses = sets.NewString(emailSANs...).List()
+			in.Spec.SecretName = mongodb.GetCertSecretName(alias, "") + fmt.Sprintf("-%s", username)
+			in.Spec.IssuerRef = GetIssuerObjectRef(mongodb.Spec.TLS, string(alias))
+			in.Spec.Usages = []cm_api.KeyUsage{
+				cm_api.UsageDigitalSignature,
+				cm_api.UsageKeyEncipherment,
+				cm_api.UsageClientAuth,
+			}
+			if isCertMangerAdditionalOutputEnabled(opts.CertManagerClient) {
+				in.Spec.AdditionalOutputFormats = []cm_api.CertificateAdditionalOutputFormat{
+					{
+						Type: cm_api.CertificateOutputFormatCombinedPEM,
+					},
+				}
+			}
+			return in
+		}, metav1.PatchOptions{},
+	)
+	return vt, err
+}
@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
@@ -46,8 +45,8 @@ import (
 
 var (
 	desLong = "generate appbinding , secrets for remote replica"
-	example = "kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name>  <db_name>\n " +
-		"kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name>  <db_name> \n"
+	example = "kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name> --remote-kubeconfig <remote.yaml> <db_name>\n " +
+		"kubectl dba remote-config mysql -n <ns> -u <user_name> -p$<password> -d<dns_name> --output-file <file.yaml> <db_name> \n"
 )
 
 const (
@@ -60,6 +59,7 @@ const (
 func MysqlAPP(f cmdutil.Factory) *cobra.Command {
 	var userName, password, dns, ns string
 	var yes bool
+	var remote common.RemoteClusterFlags
 	cmd := cobra.Command{
 		Use:     "mysql",
 		Short:   desLong,
@@ -71,25 +71,19 @@ func MysqlAPP(f cmdutil.Factory) *cobra.Command {
 			if len(args) == 0 {
 				log.Fatal("no database name given")
 			}
-			if err := userPrompt(yes); err != nil {
+			if err := remote.Validate(); err != nil {
 				log.Fatal(err)
 			}
-			var buffer []byte
-
-			buffer, err := generateMySQLConfig(f, userName, password, dns, ns, args[0])
-			if err != nil {
-				log.Fatal(err)
-			}
-			directory, err := os.Getwd()
-			if err != nil {
+			if err := userPrompt(alterPasswordPrompt, yes); err != nil {
 				log.Fatal(err)
 			}
+			var buffer []byte
 
-			err = os.WriteFile(fmt.Sprintf(directory+"/%s-remote-config.yaml", args[0]), buffer, 0o644)
+			buffer, err := generateMySQLConfig(f, userName, password, dns, ns, args[0])
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Printf("kubectl apply -f  %s/%s-remote-config.yaml\n", directory, args[0])
+			emitRemoteConfig(&remote, buffer)
 		},
 		DisableAutoGenTag:     false,
 		DisableFlagsInUseLine: false,
@@ -111,6 +105,7 @@ func MysqlAPP(f cmdutil.Factory) *cobra.Command {
 		log.Fatal(err)
 	}
 	cmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "permission for alter password  for the remote replica")
+	common.AddRemoteClusterFlags(cmd.PersistentFlags(), &remote)
 	return &cmd
 }
 
@@ -22,8 +22,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -41,6 +39,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	cm_util "kmodules.xyz/cert-manager-util/certmanager/v1"
@@ -54,90 +53,45 @@ import (
 )
 
 func PostgreSQlAPP(f cmdutil.Factory) *cobra.Command {
-	var userName, password, dns, ns, authSecretName, replicaName string
-	var caCertPath, caKeyPath string
-	var clientSANs []string
-	var port int32
-	var yes bool
-
+	var o remoteConfigFlags
 	cmd := cobra.Command{
 		Use:     "postgres",
 		Short:   desLong,
 		Long:    desLong,
-		Example: example,
+		Example: "kubectl dba remote-config postgres -n <ns> -u <user_name> -p$<password> -d<dns_name> [--replica-name <name>] --remote-kubeconfig <remote.yaml> <db_name>",
 		Args:    nil,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) == 0 {
-				log.Fatal("no database name given")
-			}
-			if err := userPrompt(yes); err != nil {
-				log.Fatal(err)
-			}
-			// Issuing a certificate needs the CA's PRIVATE key; ca.crt alone cannot
-			// sign anything, so the pair travels together.
-			if (caCertPath == "") != (caKeyPath == "") {
-				log.Fatal("--ca-cert and --ca-key must be provided together")
-			}
-
-			// Accept -d host:port as a convenience. An explicit --port always wins.
-			if host, p, found := strings.Cut(dns, ":"); found && strings.Count(dns, ":") == 1 {
-				if v, convErr := strconv.Atoi(p); convErr == nil && v > 0 && v < 65536 {
-					if !cmd.Flags().Changed("port") {
-						port = int32(v)
-					}
-					dns = host
-				}
-			}
-
-			var buffer []byte
-			buffer, err := generateConfig(f, userName, password, dns, ns, authSecretName, replicaName, port, args[0], tlsIssueOptions{
-				CACertPath: caCertPath,
-				CAKeyPath:  caKeyPath,
-				DNSSANs:    clientSANs,
-			})
+			o.validate(cmd, args)
+			buffer, err := generateConfig(f, o.userName, o.password, o.dns, o.ns, o.authSecretName, o.replicaName, o.port, args[0], o.tlsOptions())
 			if err != nil {
 				log.Fatal(err)
 			}
-
-			directory, err := os.Getwd()
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			err = os.WriteFile(fmt.Sprintf(directory+"/%s-remote-config.yaml", args[0]), buffer, 0o644)
-			if err != nil {
-				log.Fatal(err)
+			cfg := emitRemoteConfig(&o.remote, buffer)
+			if cfg != nil && o.replicaName != "" {
+				replica := dbapi.Postgres{ObjectMeta: metav1.ObjectMeta{Name: o.replicaName, Namespace: o.ns}}
+				if err := waitForRemoteReplica(cfg, dbapi.ResourcePluralPostgres, o.ns, o.replicaName, replica.OffshootSelectors(), o.timeout, postgresStreaming); err != nil {
+					log.Fatal(err)
+				}
 			}
-			fmt.Printf("kubectl apply -f  %s/%s-remote-config.yaml\n", directory, args[0])
 		},
 		DisableAutoGenTag:     false,
 		DisableFlagsInUseLine: false,
 	}
+	addRemoteConfigFlags(&cmd, &o, "postgres", 5432)
+	return &cmd
+}
 
-	cmd.PersistentFlags().StringVarP(&userName, "user", "u", "postgres", "user name for the remote replica")
-	if err := cmd.MarkPersistentFlagRequired("user"); err != nil {
-		log.Fatal(err)
-	}
-	cmd.PersistentFlags().StringVarP(&password, "pass", "p", "password", "password name for the remote replica")
-	if err := cmd.MarkPersistentFlagRequired("pass"); err != nil {
-		log.Fatal(err)
-	}
-	cmd.PersistentFlags().StringVarP(&dns, "dns", "d", "localhost", "dns name for the remote replica")
-	if err := cmd.MarkPersistentFlagRequired("dns"); err != nil {
-		log.Fatal(err)
-	}
-	cmd.PersistentFlags().StringVarP(&ns, "namespace", "n", "default", "host namespace for the remote replica")
-	if err := cmd.MarkPersistentFlagRequired("namespace"); err != nil {
-		log.Fatal(err)
+// postgresStreaming reports whether the replica's WAL receiver is streaming
+// from the source.
+func postgresStreaming(config *rest.Config, pod *core.Pod) (bool, error) {
+	out, err := exec_util.ExecIntoPod(config, pod,
+		exec_util.Command("psql", "-tAc", "SELECT status FROM pg_stat_wal_receiver"),
+		exec_util.Container("postgres"),
+	)
+	if err != nil {
+		return false, err
 	}
-	cmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "permission for alter password  for the remote replica")
-	cmd.PersistentFlags().StringVar(&authSecretName, "auth-secret", "", "name for the auth secret on the remote cluster (default: <dbname>-remote-replica-auth)")
-	cmd.PersistentFlags().Int32Var(&port, "port", 5432, "port the source is reachable on from the remote cluster; written into the generated AppBinding (also accepted as -d host:port)")
-	cmd.PersistentFlags().StringVar(&replicaName, "replica-name", "", "when set, also emit a ready-to-apply remote replica Postgres manifest with this name, sized from the source spec")
-	cmd.PersistentFlags().StringVar(&caCertPath, "ca-cert", "", "path to a CA certificate PEM; when set (together with --ca-key) the client certificate is issued locally from this CA instead of through cert-manager")
-	cmd.PersistentFlags().StringVar(&caKeyPath, "ca-key", "", "path to the CA private key PEM matching --ca-cert; required to sign the client certificate")
-	cmd.PersistentFlags().StringSliceVar(&clientSANs, "client-sans", nil, "comma separated DNS names to set as SANs on the generated client certificate")
-	return &cmd
+	return strings.TrimSpace(out) == "streaming", nil
 }
 
 // tlsIssueOptions carries how the client certificate for the remote replica is
@@ -175,7 +129,7 @@ func generateConfig(f cmdutil.Factory, userName string, password string, dns str
 		// cert-manager entirely. This also covers sources whose TLS was configured
 		// outside cert-manager (hardened/bring-your-own-CA setups).
 		var tlsBuff []byte
-		tlsBuff, tlsSecretName, err = generateTlsSecretFromLocalCA(userName, ns, tlsOpt.CACertPath, tlsOpt.CAKeyPath, tlsOpt.DNSSANs, opts)
+		tlsBuff, tlsSecretName, err = generateTlsSecretFromLocalCA(userName, ns, tlsOpt.CACertPath, tlsOpt.CAKeyPath, tlsOpt.DNSSANs, opts.DB.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate tls secret from --ca-cert: %v", err)
 		}
@@ -189,41 +143,10 @@ func generateConfig(f cmdutil.Factory, userName string, password string, dns str
 		buffer = append(buffer, tlsBuff...)
 	}
 
-	// Deep-copy the source AppBinding and replace only the ObjectMeta with a
-	// clean one. This preserves all spec fields (appRef, parameters, type,
-	// version, clientConfig, etc.) so nothing is silently dropped, while
-	// ensuring server-managed metadata (resourceVersion, uid, generation,
-	// labels, annotations) never leaks into the generated YAML. A clean
-	// ObjectMeta means the last-applied annotation stays minimal, so
-	// repeated kubectl apply is idempotent and the 3-way merge never tries
-	// to remove metadata.resourceVersion.
-	remoteApb := apb.DeepCopy()
-	remoteApb.TypeMeta = metav1.TypeMeta{
-		APIVersion: AppcatApiVersion,
-		Kind:       AppcatKind,
-	}
-	remoteApb.ObjectMeta = metav1.ObjectMeta{
-		Name:      apb.Name,
-		Namespace: ns,
-	}
-	if remoteApb.Spec.ClientConfig.Service == nil {
-		remoteApb.Spec.ClientConfig.Service = &appApi.ServiceReference{}
-	}
-	remoteApb.Spec.ClientConfig.Service.Name = dns
 	// The port the source is reachable on FROM THE REMOTE CLUSTER (a load balancer
 	// frontend, not necessarily 5432). The operator injects it as PRIMARY_PORT into
 	// the remote replica containers.
-	remoteApb.Spec.ClientConfig.Service.Port = port
-	if remoteApb.Spec.Secret == nil {
-		remoteApb.Spec.Secret = &appApi.TypedLocalObjectReference{}
-	}
-	remoteApb.Spec.Secret.Name = authSecretName
-	if tlsSecretName != "" {
-		if remoteApb.Spec.TLSSecret == nil {
-			remoteApb.Spec.TLSSecret = &appApi.TypedLocalObjectReference{}
-		}
-		remoteApb.Spec.TLSSecret.Name = tlsSecretName
-	}
+	remoteApb := remoteAppBinding(apb, ns, dns, port, authSecretName, tlsSecretName)
 
 	appbindingYaml, err := yaml.Marshal(remoteApb)
 	if err != nil {
@@ -561,8 +484,11 @@ func isCertMangerAdditionalOutputEnabled(certManagerClient cm.Interface) bool {
 	return true
 }
 
-func userPrompt(yes bool) error {
-	fmt.Println("password will be altered with the given password if provided user  exist you want to continue/Y/N?")
+// alterPasswordPrompt is what remote-config asks before it touches the user.
+const alterPasswordPrompt = "password will be altered with the given password if provided user  exist you want to continue/Y/N?"
+
+func userPrompt(msg string, yes bool) error {
+	fmt.Println(msg)
 	if yes {
 		return nil
 	}
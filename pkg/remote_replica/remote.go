@@ -0,0 +1,389 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	"kubedb.dev/cli/pkg/common"
+
+	cm_api "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	kmapi "kmodules.xyz/client-go/api/v1"
+	appApi "kmodules.xyz/custom-resources/apis/appcatalog/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// The helpers below are shared by the engines added after postgres and mysql:
+// they differ only in how the replication user is created and which fields
+// describe the replica's capacity.
+
+// remoteConfigFlags are the flags of the remote-config subcommands.
+type remoteConfigFlags struct {
+	userName, password, dns, ns string
+	authSecretName, replicaName string
+	caCertPath, caKeyPath       string
+	clientSANs                  []string
+	port                        int32
+	yes                         bool
+	remote                      common.RemoteClusterFlags
+	timeout                     time.Duration
+}
+
+func addRemoteConfigFlags(cmd *cobra.Command, o *remoteConfigFlags, defaultUser string, defaultPort int32) {
+	cmd.PersistentFlags().StringVarP(&o.userName, "user", "u", defaultUser, "user name for the remote replica")
+	if err := cmd.MarkPersistentFlagRequired("user"); err != nil {
+		log.Fatal(err)
+	}
+	cmd.PersistentFlags().StringVarP(&o.password, "pass", "p", "password", "password name for the remote replica")
+	if err := cmd.MarkPersistentFlagRequired("pass"); err != nil {
+		log.Fatal(err)
+	}
+	cmd.PersistentFlags().StringVarP(&o.dns, "dns", "d", "localhost", "dns name for the remote replica")
+	if err := cmd.MarkPersistentFlagRequired("dns"); err != nil {
+		log.Fatal(err)
+	}
+	cmd.PersistentFlags().StringVarP(&o.ns, "namespace", "n", "default", "host namespace for the remote replica")
+	if err := cmd.MarkPersistentFlagRequired("namespace"); err != nil {
+		log.Fatal(err)
+	}
+	cmd.PersistentFlags().BoolVarP(&o.yes, "yes", "y", false, "permission for alter password  for the remote replica")
+	cmd.PersistentFlags().StringVar(&o.authSecretName, "auth-secret", "", "name for the auth secret on the remote cluster (default: <dbname>-remote-replica-auth)")
+	cmd.PersistentFlags().Int32Var(&o.port, "port", defaultPort, "port the source is reachable on from the remote cluster; written into the generated AppBinding (also accepted as -d host:port)")
+	cmd.PersistentFlags().StringVar(&o.replicaName, "replica-name", "", "when set, also emit a ready-to-apply remote replica manifest with this name, sized from the source spec")
+	cmd.PersistentFlags().StringVar(&o.caCertPath, "ca-cert", "", "path to a CA certificate PEM; when set (together with --ca-key) the client certificate is issued locally from this CA instead of through cert-manager")
+	cmd.PersistentFlags().StringVar(&o.caKeyPath, "ca-key", "", "path to the CA private key PEM matching --ca-cert; required to sign the client certificate")
+	cmd.PersistentFlags().StringSliceVar(&o.clientSANs, "client-sans", nil, "comma separated DNS names to set as SANs on the generated client certificate")
+	common.AddRemoteClusterFlags(cmd.PersistentFlags(), &o.remote)
+	cmd.PersistentFlags().DurationVar(&o.timeout, "timeout", 10*time.Minute, "with --remote-kubeconfig and --replica-name, how long to wait for the replica to become Ready (and, for Postgres and MariaDB, to replicate)")
+}
+
+// validate checks the flags and accepts -d host:port; an explicit --port
+// always wins.
+func (o *remoteConfigFlags) validate(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		log.Fatal("no database name given")
+	}
+	if err := userPrompt(alterPasswordPrompt, o.yes); err != nil {
+		log.Fatal(err)
+	}
+	if err := o.remote.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	// Issuing a certificate needs the CA's PRIVATE key; ca.crt alone cannot
+	// sign anything, so the pair travels together.
+	if (o.caCertPath == "") != (o.caKeyPath == "") {
+		log.Fatal("--ca-cert and --ca-key must be provided together")
+	}
+	if host, p, found := strings.Cut(o.dns, ":"); found && strings.Count(o.dns, ":") == 1 {
+		if v, convErr := strconv.Atoi(p); convErr == nil && v > 0 && v < 65536 {
+			if !cmd.Flags().Changed("port") {
+				o.port = int32(v)
+			}
+			o.dns = host
+		}
+	}
+	if o.authSecretName == "" {
+		o.authSecretName = fmt.Sprintf("%s-remote-replica-auth", args[0])
+	}
+}
+
+func (o *remoteConfigFlags) tlsOptions() tlsIssueOptions {
+	return tlsIssueOptions{CACertPath: o.caCertPath, CAKeyPath: o.caKeyPath, DNSSANs: o.clientSANs}
+}
+
+// emitRemoteConfig applies the generated objects to the remote cluster and/or
+// writes them to --output-file. It returns the remote cluster's config, or nil
+// when nothing was applied there.
+func emitRemoteConfig(r *common.RemoteClusterFlags, buffer []byte) *rest.Config {
+	cfg, err := r.Emit(context.Background(), os.Stdout, buffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg == nil {
+		fmt.Printf("Apply it on the remote cluster with: kubectl apply -f %s\n", r.OutputFile)
+	}
+	return cfg
+}
+
+// streamingCheck reports whether a remote replica pod is replicating.
+type streamingCheck func(config *rest.Config, pod *core.Pod) (bool, error)
+
+// waitForRemoteReplica waits until the replica applied on the remote cluster
+// reports Ready and, when streaming is given, replicates from the source. Without
+// a streaming check (MongoDB) only the Ready phase is verified.
+func waitForRemoteReplica(config *rest.Config, resource, ns, name string, selector map[string]string, timeout time.Duration, streaming streamingCheck) error {
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	kc, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	gvr := schema.GroupVersionResource{Group: kubedb.GroupName, Version: "v1", Resource: resource}
+	fmt.Printf("Waiting up to %s for %s %s/%s to become Ready on the remote cluster...\n", timeout, resource, ns, name)
+	phase := ""
+	err = wait.PollUntilContextTimeout(context.Background(), 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		obj, err := dc.Resource(gvr).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		if p, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); p != phase {
+			phase = p
+			fmt.Printf("  phase: %s\n", orDash(phase))
+		}
+		if phase != "Ready" {
+			return false, nil
+		}
+		if streaming == nil {
+			return true, nil
+		}
+		pod, err := primaryPod(kc, ns, selector)
+		if err != nil {
+			return false, nil
+		}
+		ok, err := streaming(config, pod)
+		return err == nil && ok, nil
+	})
+	if err != nil {
+		if streaming == nil {
+			return fmt.Errorf("%s %s/%s did not become Ready within %s (last phase %q): %w", resource, ns, name, timeout, phase, err)
+		}
+		return fmt.Errorf("%s %s/%s did not become Ready and replicating within %s (last phase %q): %w", resource, ns, name, timeout, phase, err)
+	}
+	if streaming == nil {
+		fmt.Printf("%s %s/%s is Ready; replication from the source is not verified, check it with \"kubectl dba remote-replica status\".\n", resource, ns, name)
+		return nil
+	}
+	fmt.Printf("%s %s/%s is Ready and replicating.\n", resource, ns, name)
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// primaryPod returns the pod to create the replication user on: the one
+// labeled primary, or the first pod of a standalone or multi-primary database.
+func primaryPod(client kubernetes.Interface, ns string, selector map[string]string) (*core.Pod, error) {
+	pods, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.Set.String(selector),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no pods found with labels %s", labels.Set.String(selector))
+	}
+	for i := range pods.Items {
+		if strings.EqualFold(pods.Items[i].Labels[kubedb.LabelRole], kubedb.DatabasePodPrimary) {
+			return &pods.Items[i], nil
+		}
+	}
+	return &pods.Items[0], nil
+}
+
+// authSecretYaml renders the basic-auth Secret the remote side connects with.
+func authSecretYaml(name, ns, userName, password string) ([]byte, error) {
+	secret := core.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindSecret,
+			APIVersion: ApiversionV1,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		StringData: map[string]string{
+			"username": userName,
+			"password": password,
+		},
+		Type: core.SecretTypeBasicAuth,
+	}
+	out, err := yaml.Marshal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal authsecret yaml %v", err)
+	}
+	return append(out, []byte("---\n")...), nil
+}
+
+// clientCertSecretYaml waits for cert-manager to issue the client certificate
+// Secret and renders it without the server-managed metadata.
+func clientCertSecretYaml(client kubernetes.Interface, ns, name string) ([]byte, error) {
+	var tlsSecret *core.Secret
+	err := wait.PollUntilContextTimeout(context.Background(), 300*time.Millisecond, 60*time.Minute, true, func(ctx context.Context) (done bool, err error) {
+		tlsSecret, err = client.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tls secret %v", err)
+	}
+	clean := core.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: ApiversionV1, Kind: KindSecret},
+		ObjectMeta: metav1.ObjectMeta{Name: tlsSecret.Name, Namespace: ns},
+		Type:       tlsSecret.Type,
+		Data:       tlsSecret.Data,
+	}
+	out, err := yaml.Marshal(clean)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tls secret yaml %v", err)
+	}
+	return append(out, []byte("---\n")...), nil
+}
+
+// remoteAppBinding deep-copies the source AppBinding and replaces only the
+// ObjectMeta with a clean one. This preserves all spec fields (appRef,
+// parameters, type, version, clientConfig, etc.) so nothing is silently
+// dropped, while ensuring server-managed metadata (resourceVersion, uid,
+// generation, labels, annotations) never leaks into the generated YAML. A
+// clean ObjectMeta means the last-applied annotation stays minimal, so
+// repeated kubectl apply is idempotent and the 3-way merge never tries to
+// remove metadata.resourceVersion. The service is pointed at the address and
+// port the source is reachable on from the remote cluster.
+func remoteAppBinding(apb *appApi.AppBinding, ns, dns string, port int32, authSecretName, tlsSecretName string) *appApi.AppBinding {
+	remoteApb := apb.DeepCopy()
+	remoteApb.TypeMeta = metav1.TypeMeta{
+		APIVersion: AppcatApiVersion,
+		Kind:       AppcatKind,
+	}
+	remoteApb.ObjectMeta = metav1.ObjectMeta{
+		Name:      apb.Name,
+		Namespace: ns,
+	}
+	if remoteApb.Spec.ClientConfig.Service == nil {
+		remoteApb.Spec.ClientConfig.Service = &appApi.ServiceReference{}
+	}
+	remoteApb.Spec.ClientConfig.Service.Name = dns
+	remoteApb.Spec.ClientConfig.Service.Port = port
+	if remoteApb.Spec.Secret == nil {
+		remoteApb.Spec.Secret = &appApi.TypedLocalObjectReference{}
+	}
+	remoteApb.Spec.Secret.Name = authSecretName
+	if tlsSecretName != "" {
+		if remoteApb.Spec.TLSSecret == nil {
+			remoteApb.Spec.TLSSecret = &appApi.TypedLocalObjectReference{}
+		}
+		remoteApb.Spec.TLSSecret.Name = tlsSecretName
+	}
+	return remoteApb
+}
+
+// requireRemoteReplicaField refuses to emit a replica manifest for a kind whose
+// CRD has no spec.remoteReplica. The apiserver would prune the unknown field and
+// the manifest would create an independent, writable database instead of a
+// replica. The CRD is read on the source cluster; the remote cluster is
+// expected to run the same operator release.
+func requireRemoteReplicaField(f cmdutil.Factory, resource string) error {
+	dc, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	crdGVR := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	crd, err := dc.Resource(crdGVR).Get(context.TODO(), resource+"."+kubedb.GroupName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read the %s CRD to check remote replica support: %v", resource, err)
+	}
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		vm, ok := v.(map[string]any)
+		if !ok || vm["name"] != "v1" {
+			continue
+		}
+		if _, found, _ := unstructured.NestedMap(vm, "schema", "openAPIV3Schema", "properties", "spec", "properties", "remoteReplica"); found {
+			return nil
+		}
+	}
+	return fmt.Errorf("the installed %s CRD has no spec.remoteReplica, so --replica-name cannot emit a replica manifest: "+
+		"the field would be dropped and the manifest would create an independent database; upgrade the KubeDB operator, or drop --replica-name and configure replication by hand", resource)
+}
+
+// remoteReplicaManifest renders a replica manifest of the given kind. spec
+// carries the capacity fields copied from the source; the source reference,
+// auth secret and a Halt deletion policy are added here.
+func remoteReplicaManifest(kind, name, ns, sourceRefName, authSecretName string, spec map[string]any) ([]byte, error) {
+	spec["authSecret"] = map[string]any{"name": authSecretName}
+	spec["remoteReplica"] = map[string]any{
+		"sourceRef": map[string]any{"name": sourceRefName, "namespace": ns},
+	}
+	spec["deletionPolicy"] = "Halt"
+	return yaml.Marshal(map[string]any{
+		"apiVersion": kubedb.GroupName + "/v1",
+		"kind":       kind,
+		"metadata":   map[string]any{"name": name, "namespace": ns},
+		"spec":       spec,
+	})
+}
+
+// containerResources returns the resources of the named container as a
+// podTemplate stanza, or nil when the source does not set them.
+func containerResources(containers []core.Container, name string) map[string]any {
+	for _, c := range containers {
+		if c.Name != name || (len(c.Resources.Requests) == 0 && len(c.Resources.Limits) == 0) {
+			continue
+		}
+		return map[string]any{
+			"spec": map[string]any{
+				"containers": []any{map[string]any{"name": c.Name, "resources": c.Resources}},
+			},
+		}
+	}
+	return nil
+}
+
+// x509Subject converts the subject of a certificate spec to cert-manager's.
+func x509Subject(s *kmapi.X509Subject) *cm_api.X509Subject {
+	if s == nil {
+		return nil
+	}
+	return &cm_api.X509Subject{
+		Organizations:       s.Organizations,
+		Countries:           s.Countries,
+		OrganizationalUnits: s.OrganizationalUnits,
+		Localities:          s.Localities,
+		Provinces:           s.Provinces,
+		StreetAddresses:     s.StreetAddresses,
+		PostalCodes:         s.PostalCodes,
+		SerialNumber:        s.SerialNumber,
+	}
+}
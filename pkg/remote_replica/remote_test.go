@@ -0,0 +1,134 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"testing"
+	"time"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateMongoDBReplicaSpec(t *testing.T) {
+	replicas := int32(3)
+	src := &dbapi.MongoDB{}
+	src.Spec.Version = "7.0.5"
+	src.Spec.Replicas = &replicas
+	src.Spec.ReplicaSet = &dbapi.MongoDBReplicaSet{Name: "rs0"}
+	src.Spec.StorageType = dbapi.StorageTypeDurable
+	src.Spec.Storage = &core.PersistentVolumeClaimSpec{
+		Resources: core.VolumeResourceRequirements{
+			Requests: core.ResourceList{core.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+
+	out, err := generateMongoDBReplicaSpec(src, "mg-dr", "demo", "mg-src", "mg-src-remote-replica-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Version        string `json:"version"`
+			Replicas       int32  `json:"replicas"`
+			DeletionPolicy string `json:"deletionPolicy"`
+			AuthSecret     struct {
+				Name string `json:"name"`
+			} `json:"authSecret"`
+			ReplicaSet struct {
+				Name string `json:"name"`
+			} `json:"replicaSet"`
+			RemoteReplica struct {
+				SourceRef struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"sourceRef"`
+			} `json:"remoteReplica"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("unexpected manifest shape: %v\n%s", err, out)
+	}
+	if m.Kind != dbapi.ResourceKindMongoDB || m.Metadata.Name != "mg-dr" || m.Metadata.Namespace != "demo" {
+		t.Errorf("got %s %s/%s, want MongoDB demo/mg-dr", m.Kind, m.Metadata.Namespace, m.Metadata.Name)
+	}
+	if m.Spec.Version != "7.0.5" || m.Spec.Replicas != 3 || m.Spec.ReplicaSet.Name != "rs0" {
+		t.Errorf("capacity not copied from the source: %+v", m.Spec)
+	}
+	if m.Spec.RemoteReplica.SourceRef.Name != "mg-src" || m.Spec.RemoteReplica.SourceRef.Namespace != "demo" {
+		t.Errorf("sourceRef = %+v, want demo/mg-src", m.Spec.RemoteReplica.SourceRef)
+	}
+	if m.Spec.AuthSecret.Name != "mg-src-remote-replica-auth" {
+		t.Errorf("authSecret = %q", m.Spec.AuthSecret.Name)
+	}
+	if m.Spec.DeletionPolicy != "Halt" {
+		t.Errorf("deletionPolicy = %q, want Halt", m.Spec.DeletionPolicy)
+	}
+}
+
+func TestParseVerticalStatus(t *testing.T) {
+	out := `*************************** 1. row ***************************
+             Replica_IO_State: Waiting for source to send event
+                  Source_Host: my-src.demo.example.com
+                  Source_Port: 3306
+           Replica_IO_Running: Yes
+          Replica_SQL_Running: No
+                Last_SQL_Error: Error 'x': check 'y'
+        Seconds_Behind_Source: NULL
+*************************** 2. row ***************************
+                  Source_Host: other
+`
+	m := parseVerticalStatus(out)
+	if got := statusField(m, "Source_Host", "Master_Host"); got != "my-src.demo.example.com" {
+		t.Errorf("Source_Host = %q", got)
+	}
+	if got := statusField(m, "Replica_SQL_Running", "Slave_SQL_Running"); got != "No" {
+		t.Errorf("Replica_SQL_Running = %q", got)
+	}
+	if got := statusField(m, "Last_SQL_Error"); got != "Error 'x': check 'y'" {
+		t.Errorf("Last_SQL_Error = %q", got)
+	}
+	if got := statusField(m, "Slave_IO_Running"); got != "" {
+		t.Errorf("Slave_IO_Running = %q, want absent", got)
+	}
+}
+
+func TestReplicaHealthCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		h       replicaHealth
+		wantErr bool
+	}{
+		{"caught up", replicaHealth{connected: true, lagBytes: 0, lagSeconds: 0}, false},
+		{"unknown lag", replicaHealth{connected: true, lagBytes: -1, lagSeconds: -1}, false},
+		{"disconnected", replicaHealth{lagBytes: 0, lagSeconds: 0}, true},
+		{"bytes", replicaHealth{connected: true, lagBytes: 2048, lagSeconds: 0}, true},
+		{"time", replicaHealth{connected: true, lagBytes: 0, lagSeconds: 90}, true},
+	} {
+		if err := tc.h.check(1024, time.Minute); (err != nil) != tc.wantErr {
+			t.Errorf("%s: check() = %v, wantErr %t", tc.name, err, tc.wantErr)
+		}
+	}
+}
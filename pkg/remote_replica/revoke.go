@@ -0,0 +1,370 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	as "kmodules.xyz/custom-resources/client/clientset/versioned"
+)
+
+// remoteObjects are the objects remote-config applied on the remote cluster
+// for one source: the AppBinding the replica connects through and its
+// Secrets. replica is nil when the replica itself is already gone.
+type remoteObjects struct {
+	client     kubernetes.Interface
+	appcat     as.Interface
+	ns         string
+	apb        string
+	authSecret string
+	tlsSecret  string
+	user       string
+	replica    *unstructured.Unstructured
+	// slot is the replication slot a postgres replica streams through.
+	slot string
+}
+
+// findRemoteObjects looks the remote-config objects of a source up on the
+// remote cluster. It returns nil when there is no AppBinding for the source.
+func (o *replicaTargetFlags) findRemoteObjects(ctx context.Context, kind, dbName string) (*remoteObjects, error) {
+	cfg, err := o.remote.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	r := &remoteObjects{ns: o.replicaNS, apb: dbName}
+	if r.ns == "" {
+		r.ns = o.ns
+	}
+	if r.client, err = kubernetes.NewForConfig(cfg); err != nil {
+		return nil, err
+	}
+	if r.appcat, err = as.NewForConfig(cfg); err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	gvr := schema.GroupVersionResource{Group: kubedb.GroupName, Version: "v1", Resource: resourcePlural(kind)}
+	if replica, err := findRemoteReplica(ctx, dc, gvr, r.ns, o.replicaName, dbName); err == nil {
+		r.replica = replica
+		if ref, _, _ := unstructured.NestedString(replica.Object, "spec", "remoteReplica", "sourceRef", "name"); ref != "" {
+			r.apb = ref
+		}
+		if kind == dbapi.ResourceSingularPostgres {
+			if pod, err := primaryPod(r.client, r.ns, offshootSelectors(kind, replica.GetName(), r.ns)); err == nil {
+				if rows, err := psqlRows(cfg, pod, "SELECT coalesce(slot_name, '') FROM pg_stat_wal_receiver"); err == nil && len(rows) > 0 {
+					r.slot = rows[0][0]
+				}
+			}
+		}
+	} else if o.replicaName != "" {
+		return nil, err
+	}
+	apb, err := r.appcat.AppcatalogV1alpha1().AppBindings(r.ns).Get(ctx, r.apb, metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get appbinding %s/%s on the remote cluster %v", r.ns, r.apb, err)
+	}
+	if apb.Spec.Secret != nil {
+		r.authSecret = apb.Spec.Secret.Name
+		if secret, err := r.client.CoreV1().Secrets(r.ns).Get(ctx, r.authSecret, metav1.GetOptions{}); err == nil {
+			r.user = string(secret.Data[core.BasicAuthUsernameKey])
+		}
+	}
+	if apb.Spec.TLSSecret != nil {
+		r.tlsSecret = apb.Spec.TLSSecret.Name
+	}
+	return r, nil
+}
+
+func (r *remoteObjects) delete(ctx context.Context) error {
+	if err := r.appcat.AppcatalogV1alpha1().AppBindings(r.ns).Delete(ctx, r.apb, metav1.DeleteOptions{}); err != nil && !kerr.IsNotFound(err) {
+		return fmt.Errorf("failed to delete appbinding %s/%s on the remote cluster %v", r.ns, r.apb, err)
+	}
+	fmt.Printf("appbinding %s/%s deleted on the remote cluster\n", r.ns, r.apb)
+	for _, name := range []string{r.authSecret, r.tlsSecret} {
+		if name == "" {
+			continue
+		}
+		if err := r.client.CoreV1().Secrets(r.ns).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !kerr.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %s/%s on the remote cluster %v", r.ns, name, err)
+		}
+		fmt.Printf("secret %s/%s deleted on the remote cluster\n", r.ns, name)
+	}
+	return nil
+}
+
+func NewCmdRevoke(f cmdutil.Factory) *cobra.Command {
+	var o replicaTargetFlags
+	var user string
+	var slots []string
+	var disable, yes bool
+	cmd := &cobra.Command{
+		Use:   "revoke <kind> <db_name>",
+		Short: "Revoke the replication user and client certificate remote-config created for a remote replica",
+		Long: "Decommissions what remote-config created on the source: drops the replication user (or, with --disable, locks it and keeps it), " +
+			"terminates its sessions, drops the replication slots it streamed through (postgres), and deletes the client Certificate and its Secret. " +
+			"With --remote-kubeconfig/--remote-context it also deletes the AppBinding and Secrets remote-config applied on the remote cluster; " +
+			"the replica database itself is left for you to delete. Supported kinds: " + strings.Join(remoteReplicaKinds, ", ") + ".",
+		Example: "kubectl dba remote-replica revoke postgres -n demo --user replicator pg-src\n" +
+			"kubectl dba remote-replica revoke mysql -n demo --user replicator --remote-kubeconfig remote.yaml my-src\n" +
+			"kubectl dba remote-replica revoke postgres -n demo --user replicator --disable --slot dr_slot pg-src",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			src, err := openSourceDB(f, args[0], args[1], o.ns)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if user == src.adminUser {
+				log.Fatalf("%s is the admin user of %s %s/%s; refusing to revoke it", user, src.kind, src.ns, src.name)
+			}
+			var remote *remoteObjects
+			if o.remote.Remote() {
+				if remote, err = o.findRemoteObjects(ctx, src.kind, src.name); err != nil {
+					log.Fatal(err)
+				}
+				if remote != nil && remote.user != "" && remote.user != user {
+					log.Fatalf("appbinding %s/%s on the remote cluster connects as %s, not %s; refusing to delete it", remote.ns, remote.apb, remote.user, user)
+				}
+			}
+			if src.kind == dbapi.ResourceSingularPostgres {
+				found, err := postgresUserSlots(src, user)
+				if err != nil {
+					log.Fatal(err)
+				}
+				all := sets.NewString(slots...).Insert(found...)
+				if remote != nil && remote.slot != "" {
+					all.Insert(remote.slot)
+				}
+				slots = all.List()
+			}
+
+			verb := "drop"
+			if disable {
+				verb = "lock"
+			}
+			fmt.Printf("This will, on %s %s/%s:\n", src.kind, src.ns, src.name)
+			fmt.Printf("  - %s user %s and terminate its sessions\n", verb, user)
+			for _, s := range slots {
+				fmt.Printf("  - drop replication slot %s\n", s)
+			}
+			fmt.Printf("  - delete certificate %s and secret %s, if present\n", src.certName(user), src.certSecretName(user))
+			if remote != nil {
+				fmt.Printf("and on the remote cluster, delete appbinding %s/%s and secrets %s\n", remote.ns, remote.apb, strings.Join(sets.NewString(remote.authSecret, remote.tlsSecret).Delete("").List(), ", "))
+			} else if o.remote.Remote() {
+				fmt.Printf("No appbinding for %s found on the remote cluster; nothing to delete there.\n", src.name)
+			}
+			if err := userPrompt("The remote replica will stop replicating. Do you want to continue/Y/N?", yes); err != nil {
+				log.Fatal(err)
+			}
+
+			if err := src.revokeUser(user, slots, disable); err != nil {
+				log.Fatal(err)
+			}
+			deleted, err := src.deleteClientCert(ctx, user)
+			for _, d := range deleted {
+				fmt.Printf("%s deleted on the source cluster\n", d)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			if remote != nil {
+				if err := remote.delete(ctx); err != nil {
+					log.Fatal(err)
+				}
+				if remote.replica != nil {
+					fmt.Printf("%s %s/%s on the remote cluster was left in place; delete it once it is no longer needed.\n", remote.replica.GetKind(), remote.ns, remote.replica.GetName())
+				}
+			}
+		},
+	}
+	addReplicaTargetFlags(cmd, &o)
+	cmd.Flags().StringVarP(&user, "user", "u", "", "replication user to revoke")
+	if err := cmd.MarkFlagRequired("user"); err != nil {
+		log.Fatal(err)
+	}
+	cmd.Flags().BoolVar(&disable, "disable", false, "lock the user instead of dropping it (postgres: NOLOGIN, mysql/mariadb: ACCOUNT LOCK, mongodb: revoke its roles)")
+	cmd.Flags().StringSliceVar(&slots, "slot", nil, "postgres replication slot to drop in addition to those found through the user's connections and the remote replica")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+// postgresUserSlots returns the slots user's walsenders stream through.
+func postgresUserSlots(s *sourceDB, user string) ([]string, error) {
+	rows, err := s.psql("SELECT s.slot_name FROM pg_replication_slots s JOIN pg_stat_replication r ON r.pid = s.active_pid WHERE r.usename = " + sqlLiteral(user))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replication slots %v", err)
+	}
+	var slots []string
+	for _, r := range rows {
+		slots = append(slots, r[0])
+	}
+	return slots, nil
+}
+
+// revokeUser drops or locks the replication user and ends its sessions, so
+// that the remote replica cannot go on with the access it already has.
+func (s *sourceDB) revokeUser(user string, slots []string, disable bool) error {
+	switch s.kind {
+	case dbapi.ResourceSingularPostgres:
+		return s.revokePostgresUser(user, slots, disable)
+	case dbapi.ResourceSingularMongoDB:
+		return s.revokeMongoDBUser(user, disable)
+	}
+	return s.revokeMySQLUser(user, disable)
+}
+
+func (s *sourceDB) revokePostgresUser(user string, slots []string, disable bool) error {
+	rows, err := s.psql("SELECT 1 FROM pg_roles WHERE rolname = " + sqlLiteral(user))
+	if err != nil {
+		return fmt.Errorf("failed to look up role %s %v", user, err)
+	}
+	exists := len(rows) > 0
+	if exists {
+		// NOLOGIN first, or the replica reconnects the moment its walsender is
+		// terminated and re-acquires the slot.
+		if _, err := s.psql("ALTER ROLE " + quoteIdent(user) + " NOLOGIN"); err != nil {
+			return fmt.Errorf("failed to disable role %s %v", user, err)
+		}
+		if _, err := s.psql("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE usename = " + sqlLiteral(user)); err != nil {
+			return fmt.Errorf("failed to terminate the sessions of %s %v", user, err)
+		}
+	} else {
+		fmt.Printf("role %s does not exist on %s/%s\n", user, s.ns, s.name)
+	}
+	for _, slot := range slots {
+		if err := s.dropPostgresSlot(slot); err != nil {
+			return err
+		}
+	}
+	if !exists {
+		return nil
+	}
+	if disable {
+		fmt.Printf("role %s locked (NOLOGIN) on %s/%s\n", user, s.ns, s.name)
+		return nil
+	}
+	// generateUser granted pg_read_binary_file, which DROP ROLE refuses to
+	// drop implicitly.
+	_, err = s.psql("REVOKE EXECUTE ON FUNCTION pg_read_binary_file(text) FROM " + quoteIdent(user) + "; DROP ROLE " + quoteIdent(user))
+	if err != nil {
+		return fmt.Errorf("failed to drop role %s (it is locked; rerun with --disable to keep it that way) %v", user, err)
+	}
+	fmt.Printf("role %s dropped on %s/%s\n", user, s.ns, s.name)
+	return nil
+}
+
+// dropPostgresSlot drops a slot once its walsender has exited; the
+// terminated walsender can hold it for a moment.
+func (s *sourceDB) dropPostgresSlot(slot string) error {
+	err := wait.PollUntilContextTimeout(context.Background(), time.Second, 30*time.Second, true, func(ctx context.Context) (bool, error) {
+		rows, err := s.psql("SELECT active FROM pg_replication_slots WHERE slot_name = " + sqlLiteral(slot))
+		if err != nil {
+			return false, err
+		}
+		if len(rows) == 0 {
+			return true, nil
+		}
+		if rows[0][0] == "t" {
+			return false, nil
+		}
+		_, err = s.psql("SELECT pg_drop_replication_slot(" + sqlLiteral(slot) + ")")
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop replication slot %s %v", slot, err)
+	}
+	fmt.Printf("replication slot %s dropped on %s/%s\n", slot, s.ns, s.name)
+	return nil
+}
+
+func (s *sourceDB) revokeMySQLUser(user string, disable bool) error {
+	account := sqlLiteral(user) + "@'%'"
+	out, err := s.mysql("SELECT COUNT(*) FROM mysql.user WHERE user = " + sqlLiteral(user) + " AND host = '%'")
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s %v", user, err)
+	}
+	if strings.TrimSpace(out) == "0" {
+		fmt.Printf("user %s does not exist on %s/%s\n", user, s.ns, s.name)
+		return nil
+	}
+	stmt, done := "DROP USER "+account, "dropped"
+	if disable {
+		stmt, done = "ALTER USER "+account+" ACCOUNT LOCK", "locked"
+	}
+	if _, err := s.mysql(stmt); err != nil {
+		return fmt.Errorf("failed to revoke user %s %v", user, err)
+	}
+	// Neither statement affects the sessions that are already open, such as
+	// the replica's binlog dump thread.
+	out, err = s.mysql("SELECT id FROM information_schema.processlist WHERE user = " + sqlLiteral(user))
+	if err != nil {
+		return fmt.Errorf("failed to list the sessions of %s %v", user, err)
+	}
+	for _, id := range strings.Fields(out) {
+		// A session may end on its own in between.
+		_, _ = s.mysql("KILL " + id)
+	}
+	fmt.Printf("user %s %s on %s/%s\n", user, done, s.ns, s.name)
+	return nil
+}
+
+func (s *sourceDB) revokeMongoDBUser(user string, disable bool) error {
+	name, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	eval := fmt.Sprintf("var n = %s; var u = db.getUser(n); if (!u) { print('absent'); } else { db.dropUser(n); print('dropped'); }", name)
+	if disable {
+		eval = fmt.Sprintf("var n = %s; var u = db.getUser(n); if (!u) { print('absent'); } else { if (u.roles.length) { db.revokeRolesFromUser(n, u.roles); } print('locked'); }", name)
+	}
+	out, err := s.mongo(eval)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user %s %v", user, err)
+	}
+	if strings.TrimSpace(out) == "absent" {
+		fmt.Printf("user %s does not exist on %s/%s\n", user, s.ns, s.name)
+		return nil
+	}
+	if _, err := s.mongo(fmt.Sprintf(`db.adminCommand({killAllSessionsByPattern: [{users: [{user: %s, db: "admin"}]}]})`, name)); err != nil {
+		return fmt.Errorf("failed to kill the sessions of %s %v", user, err)
+	}
+	fmt.Printf("user %s %s on %s/%s\n", user, strings.TrimSpace(out), s.ns, s.name)
+	return nil
+}
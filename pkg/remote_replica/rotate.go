@@ -0,0 +1,392 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+
+	"github.com/spf13/cobra"
+	password "gomodules.xyz/password-generator"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	core_util "kmodules.xyz/client-go/core/v1"
+)
+
+func NewCmdRotate(f cmdutil.Factory) *cobra.Command {
+	var o replicaTargetFlags
+	var rotatePassword, rotateCert, yes bool
+	var caCertPath, caKeyPath string
+	var clientSANs []string
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "rotate <kind> <db_name>",
+		Short: "Rotate the password and client certificate a remote replica connects with",
+		Long: "Generates a new password for the replication user and/or reissues its client certificate, through cert-manager or locally from " +
+			"--ca-cert/--ca-key, updates the source and pushes the new Secrets to the remote cluster, then restarts the replica's pods one at a time, standbys first, so they pick up " +
+			"the new Secrets and waits until it replicates again. Without --password or --cert both are rotated (the certificate only when the replica uses one). " +
+			"Supported kinds: " + strings.Join(remoteReplicaKinds, ", ") + ".",
+		Example: "kubectl dba remote-replica rotate postgres -n demo --remote-kubeconfig remote.yaml pg-src\n" +
+			"kubectl dba remote-replica rotate mysql -n demo --remote-context dr --cert --ca-cert ca.crt --ca-key ca.key my-src",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			if (caCertPath == "") != (caKeyPath == "") {
+				log.Fatal("--ca-cert and --ca-key must be provided together")
+			}
+			t, err := o.resolve(ctx, f, args[0], args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !rotatePassword && !rotateCert {
+				rotatePassword, rotateCert = true, t.tlsSecret != ""
+			}
+			if rotateCert && t.tlsSecret == "" {
+				log.Fatalf("appbinding of %s/%s on the remote cluster has no client certificate to rotate", t.replica.GetNamespace(), t.replica.GetName())
+			}
+
+			fmt.Printf("This will, for replication user %s of %s %s/%s:\n", t.user, t.src.kind, t.src.ns, t.src.name)
+			if rotateCert {
+				how := "through cert-manager (certificate " + t.src.certName(t.user) + ")"
+				if caCertPath != "" {
+					how = "from " + caCertPath
+				}
+				fmt.Printf("  - issue a new client certificate %s and replace secret %s/%s on the remote cluster\n", how, t.secretNS, t.tlsSecret)
+			}
+			if rotatePassword {
+				fmt.Printf("  - set a new password on the source and replace secret %s/%s on the remote cluster\n", t.secretNS, t.authSecret)
+			}
+			fmt.Printf("  - restart the pods of %s/%s on the remote cluster one at a time, standbys first, so they pick up the new secrets, and wait up to %s for it to replicate\n", t.replica.GetNamespace(), t.replica.GetName(), timeout)
+			if err := userPrompt("Do you want to continue/Y/N?", yes); err != nil {
+				log.Fatal(err)
+			}
+
+			// The certificate goes first: the old and the new one are signed by
+			// the same CA, so the replica can connect with either and there is
+			// nothing to coordinate. A password has no such overlap; the replica's
+			// established connection is already authenticated and survives the
+			// change, so the only window is a reconnect between the two writes
+			// below, which are done back to back.
+			if rotateCert {
+				data, err := t.reissueClientCert(ctx, caCertPath, caKeyPath, clientSANs, timeout)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if err := t.updateRemoteSecret(ctx, t.tlsSecret, func(s *core.Secret) { s.Data = data }); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("secret %s/%s updated on the remote cluster\n", t.secretNS, t.tlsSecret)
+			}
+			if rotatePassword {
+				pwd := password.GenerateForCharset(32, password.AlphaNum)
+				if err := t.src.setPassword(t.user, pwd); err != nil {
+					log.Fatal(err)
+				}
+				err := t.updateRemoteSecret(ctx, t.authSecret, func(s *core.Secret) {
+					if s.Data == nil {
+						s.Data = map[string][]byte{}
+					}
+					s.Data[core.BasicAuthPasswordKey] = []byte(pwd)
+				})
+				if err != nil {
+					log.Fatalf("the password of %s was changed on the source but the remote secret was not updated, so the replica cannot reconnect: %v", t.user, err)
+				}
+				fmt.Printf("password of %s changed; secret %s/%s updated on the remote cluster\n", t.user, t.secretNS, t.authSecret)
+			}
+
+			if err := t.verifyReconnect(ctx, timeout); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	addReplicaTargetFlags(cmd, &o)
+	cmd.Flags().BoolVar(&rotatePassword, "password", false, "rotate the replication user's password")
+	cmd.Flags().BoolVar(&rotateCert, "cert", false, "reissue the replication user's client certificate")
+	cmd.Flags().StringVar(&caCertPath, "ca-cert", "", "path to a CA certificate PEM; when set (together with --ca-key) the client certificate is issued locally from this CA instead of through cert-manager")
+	cmd.Flags().StringVar(&caKeyPath, "ca-key", "", "path to the CA private key PEM matching --ca-cert")
+	cmd.Flags().StringSliceVar(&clientSANs, "client-sans", nil, "comma separated DNS names to add to the SANs of the current client certificate")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "how long to wait for a new certificate and for the replica to reconnect")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+// reissueClientCert returns the data of the new client certificate Secret.
+// Without a CA pair it deletes cert-manager's Secret, which makes cert-manager
+// issue the Certificate again, and waits for the new one.
+func (t *replicaTarget) reissueClientCert(ctx context.Context, caCertPath, caKeyPath string, extraSANs []string, timeout time.Duration) (map[string][]byte, error) {
+	current, err := t.remoteClient.CoreV1().Secrets(t.secretNS).Get(ctx, t.tlsSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s on the remote cluster %v", t.secretNS, t.tlsSecret, err)
+	}
+	if caCertPath != "" {
+		var sans []string
+		if cert, err := leafCert(current); err == nil {
+			sans = cert.DNSNames
+		}
+		caCertPEM, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --ca-cert: %v", err)
+		}
+		caKeyPEM, err := os.ReadFile(caKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --ca-key: %v", err)
+		}
+		certPEM, keyPEM, err := issueClientCertFromCA(t.user, sets.NewString(append(sans, extraSANs...)...).List(), caCertPEM, caKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{"ca.crt": caCertPEM, core.TLSCertKey: certPEM, core.TLSPrivateKeyKey: keyPEM}, nil
+	}
+
+	s := t.src
+	if len(extraSANs) > 0 {
+		return nil, fmt.Errorf("--client-sans needs --ca-cert/--ca-key; with cert-manager, edit the SANs on certificate %s/%s instead", s.ns, s.certName(t.user))
+	}
+	if _, err := s.certManager.CertmanagerV1().Certificates(s.ns).Get(ctx, s.certName(t.user), metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get certificate %s/%s; if the client certificate was signed with --ca-cert/--ca-key, pass them again %v", s.ns, s.certName(t.user), err)
+	}
+	if err := s.client.CoreV1().Secrets(s.ns).Delete(ctx, s.certSecretName(t.user), metav1.DeleteOptions{}); err != nil && !kerr.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete secret %s/%s %v", s.ns, s.certSecretName(t.user), err)
+	}
+	fmt.Printf("waiting for cert-manager to reissue certificate %s/%s...\n", s.ns, s.certName(t.user))
+	var data map[string][]byte
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		secret, err := s.client.CoreV1().Secrets(s.ns).Get(ctx, s.certSecretName(t.user), metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if len(secret.Data[core.TLSCertKey]) == 0 || bytes.Equal(secret.Data[core.TLSCertKey], current.Data[core.TLSCertKey]) {
+			return false, nil
+		}
+		data = secret.Data
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("certificate %s/%s was not reissued within %s %v", s.ns, s.certName(t.user), timeout, err)
+	}
+	return data, nil
+}
+
+func (t *replicaTarget) updateRemoteSecret(ctx context.Context, name string, mutate func(*core.Secret)) error {
+	secret, err := t.remoteClient.CoreV1().Secrets(t.secretNS).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s on the remote cluster %v", t.secretNS, name, err)
+	}
+	mutate(secret)
+	if _, err := t.remoteClient.CoreV1().Secrets(t.secretNS).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update secret %s/%s on the remote cluster %v", t.secretNS, name, err)
+	}
+	return nil
+}
+
+// setPassword changes the password of an existing replication user.
+func (s *sourceDB) setPassword(user, pwd string) error {
+	var err error
+	switch s.kind {
+	case dbapi.ResourceSingularPostgres:
+		var rows [][]string
+		if rows, err = s.psql("SELECT 1 FROM pg_roles WHERE rolname = " + sqlLiteral(user)); err == nil && len(rows) == 0 {
+			return fmt.Errorf("role %s does not exist on %s/%s", user, s.ns, s.name)
+		}
+		if err == nil {
+			_, err = s.psql("ALTER ROLE " + quoteIdent(user) + " WITH PASSWORD " + sqlLiteral(pwd))
+		}
+	case dbapi.ResourceSingularMongoDB:
+		var name, p []byte
+		if name, err = json.Marshal(user); err != nil {
+			return err
+		}
+		if p, err = json.Marshal(pwd); err != nil {
+			return err
+		}
+		var out string
+		if out, err = s.mongo(fmt.Sprintf("if (db.getUser(%s)) { db.changeUserPassword(%s, %s); print('ok'); } else { print('absent'); }", name, name, p)); err == nil && strings.TrimSpace(out) == "absent" {
+			return fmt.Errorf("user %s does not exist on %s/%s", user, s.ns, s.name)
+		}
+	default:
+		var out string
+		if out, err = s.mysql("SELECT COUNT(*) FROM mysql.user WHERE user = " + sqlLiteral(user) + " AND host = '%'"); err == nil && strings.TrimSpace(out) == "0" {
+			return fmt.Errorf("user %s does not exist on %s/%s", user, s.ns, s.name)
+		}
+		if err == nil {
+			_, err = s.mysql("ALTER USER " + sqlLiteral(user) + "@'%' IDENTIFIED BY " + sqlLiteral(pwd))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to change the password of %s %v", user, err)
+	}
+	return nil
+}
+
+// verifyReconnect restarts the replica's pods, so that they start with the
+// rotated Secrets, ends whatever replication session of the user survived from
+// before, and waits for a new one, which proves the rotated credentials work.
+// A MongoDB replica has no single replication session to end; for it, the
+// password of the remote Secret must authenticate on the source.
+func (t *replicaTarget) verifyReconnect(ctx context.Context, timeout time.Duration) error {
+	s := t.src
+	// A new session is one that started after this mark: on postgres by the
+	// server's clock, on mysql by its ever-growing thread id.
+	var since string
+	var err error
+	switch s.kind {
+	case dbapi.ResourceSingularPostgres:
+		var rows [][]string
+		if rows, err = s.psql("SELECT now()"); err == nil && len(rows) > 0 {
+			since = rows[0][0]
+		}
+	case dbapi.ResourceSingularMongoDB:
+	default:
+		var out string
+		if out, err = s.mysql("SELECT CONNECTION_ID()"); err == nil {
+			since = strings.TrimSpace(out)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the source's replication sessions %v", err)
+	}
+	if err := t.restartReplicaPods(ctx, timeout); err != nil {
+		return err
+	}
+	if s.kind == dbapi.ResourceSingularMongoDB {
+		return t.verifyMongoDBPassword(ctx)
+	}
+
+	if s.kind == dbapi.ResourceSingularPostgres {
+		_, err = s.psql(fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_replication WHERE usename = %s AND backend_start <= %s::timestamptz",
+			sqlLiteral(t.user), sqlLiteral(since)))
+	} else {
+		var out string
+		if out, err = s.mysql(mysqlDumpThreads(t.user, "id", "id <= "+since)); err == nil {
+			for _, id := range strings.Fields(out) {
+				_, _ = s.mysql("KILL " + id)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to end the old replication session of %s %v", t.user, err)
+	}
+	fmt.Printf("waiting up to %s for %s/%s to reconnect...\n", timeout, t.replica.GetNamespace(), t.replica.GetName())
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		var out string
+		var err error
+		if s.kind == dbapi.ResourceSingularPostgres {
+			var rows [][]string
+			rows, err = s.psql(fmt.Sprintf("SELECT count(*) FROM pg_stat_replication WHERE usename = %s AND state = 'streaming' AND backend_start > %s::timestamptz",
+				sqlLiteral(t.user), sqlLiteral(since)))
+			if err == nil && len(rows) > 0 {
+				out = rows[0][0]
+			}
+		} else {
+			out, err = s.mysql(mysqlDumpThreads(t.user, "COUNT(*)", "id > "+since))
+		}
+		return err == nil && strings.TrimSpace(out) != "0" && strings.TrimSpace(out) != "", nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s/%s did not reconnect as %s within %s %v", t.replica.GetNamespace(), t.replica.GetName(), t.user, timeout, err)
+	}
+	fmt.Printf("%s/%s reconnected with the rotated credentials.\n", t.replica.GetNamespace(), t.replica.GetName())
+	return nil
+}
+
+// restartReplicaPods restarts the replica's pods on the remote cluster one at a
+// time, standbys first and the primary last, waiting for each replacement to be
+// Ready before deleting the next, so the replica never loses all of its pods. A
+// Secret is read into environment variables only when a container starts, so a
+// running replica keeps using the old credentials until then.
+func (t *replicaTarget) restartReplicaPods(ctx context.Context, timeout time.Duration) error {
+	ns := t.replica.GetNamespace()
+	selector := labels.Set(offshootSelectors(t.src.kind, t.replica.GetName(), ns)).String()
+	pods, err := t.remoteClient.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of %s/%s on the remote cluster %v", ns, t.replica.GetName(), err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pods found with labels %s on the remote cluster", selector)
+	}
+	isPrimary := func(p *core.Pod) bool {
+		return strings.EqualFold(p.Labels[kubedb.LabelRole], kubedb.DatabasePodPrimary)
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return !isPrimary(&pods.Items[i]) && isPrimary(&pods.Items[j])
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	fmt.Printf("restarting %d pod(s) of %s/%s on the remote cluster one at a time to pick up the new secrets...\n", len(pods.Items), ns, t.replica.GetName())
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if err := t.remoteClient.CoreV1().Pods(ns).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !kerr.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s/%s on the remote cluster %v", ns, pod.Name, err)
+		}
+		// The PetSet recreates the pod under the same name.
+		err := wait.PollUntilContextCancel(ctx, 5*time.Second, false, func(ctx context.Context) (bool, error) {
+			cur, err := t.remoteClient.CoreV1().Pods(ns).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, nil
+			}
+			return cur.UID != pod.UID && core_util.IsPodReady(cur), nil
+		})
+		if err != nil {
+			return fmt.Errorf("pod %s/%s was not Ready again within %s %v", ns, pod.Name, timeout, err)
+		}
+		fmt.Printf("  %s restarted\n", pod.Name)
+	}
+	return nil
+}
+
+// verifyMongoDBPassword authenticates on the source as the replication user
+// with the password of the remote Secret, the one the restarted replica
+// connects with.
+func (t *replicaTarget) verifyMongoDBPassword(ctx context.Context) error {
+	secret, err := t.remoteClient.CoreV1().Secrets(t.secretNS).Get(ctx, t.authSecret, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s on the remote cluster %v", t.secretNS, t.authSecret, err)
+	}
+	s := t.src
+	out, err := mongoEval(s.config, s.pod, t.user, string(secret.Data[core.BasicAuthPasswordKey]), s.tls, "print(db.runCommand({ping: 1}).ok)")
+	if err != nil || strings.TrimSpace(out) != "1" {
+		return fmt.Errorf("%s could not authenticate on %s/%s with the password of secret %s/%s on the remote cluster: %v %s", t.user, s.ns, s.name, t.secretNS, t.authSecret, err, out)
+	}
+	fmt.Printf("%s authenticates on %s/%s with the rotated password.\n", t.user, s.ns, s.name)
+	return nil
+}
+
+// mysqlDumpThreads selects from the binlog dump threads of user whose id
+// matches idCond; MySQL calls them "Binlog Dump" or, with GTIDs, "Binlog Dump
+// GTID".
+func mysqlDumpThreads(user, what, idCond string) string {
+	return "SELECT " + what + " FROM information_schema.processlist WHERE user = " + sqlLiteral(user) +
+		" AND command LIKE 'Binlog Dump%' AND " + idCond
+}
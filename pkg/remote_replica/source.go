@@ -0,0 +1,194 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"kubedb.dev/cli/pkg/common"
+
+	cm "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	core "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// sourceDB is a source database on the current cluster, reduced to what the
+// remote-replica commands need: its primary pod, admin credentials, and the
+// names remote-config gives the per-user client certificate.
+type sourceDB struct {
+	kind, name, ns string
+	config         *rest.Config
+	client         kubernetes.Interface
+	certManager    cm.Interface
+	pod            *core.Pod
+	container      string
+	adminUser      string
+	adminPass      string
+	tls            bool
+	certPrefix     string
+	secretPrefix   string
+}
+
+// offshootSelectors returns the pod selector of a KubeDB database by kind.
+func offshootSelectors(kind, name, ns string) map[string]string {
+	meta := metav1.ObjectMeta{Name: name, Namespace: ns}
+	switch kind {
+	case dbapi.ResourceSingularMySQL:
+		return (&dbapi.MySQL{ObjectMeta: meta}).OffshootSelectors()
+	case dbapi.ResourceSingularMariaDB:
+		return (&dbapi.MariaDB{ObjectMeta: meta}).OffshootSelectors()
+	case dbapi.ResourceSingularMongoDB:
+		return (&dbapi.MongoDB{ObjectMeta: meta}).OffshootSelectors()
+	}
+	return (&dbapi.Postgres{ObjectMeta: meta}).OffshootSelectors()
+}
+
+// resourcePlural returns the plural resource name of a kind.
+func resourcePlural(kind string) string {
+	switch kind {
+	case dbapi.ResourceSingularMySQL:
+		return dbapi.ResourcePluralMySQL
+	case dbapi.ResourceSingularMariaDB:
+		return dbapi.ResourcePluralMariaDB
+	case dbapi.ResourceSingularMongoDB:
+		return dbapi.ResourcePluralMongoDB
+	}
+	return dbapi.ResourcePluralPostgres
+}
+
+// openSourceDB loads a source database of one of the kinds remote-config
+// supports and finds its primary pod.
+func openSourceDB(f cmdutil.Factory, kind, name, ns string) (*sourceDB, error) {
+	s := &sourceDB{kind: kind, name: name, ns: ns}
+	switch kind {
+	case dbapi.ResourceSingularPostgres:
+		opts, err := common.NewPostgresOpts(f, name, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get db %s, err:%v", name, err)
+		}
+		s.config, s.client, s.certManager = opts.Config, opts.Client, opts.CertManagerClient
+		s.container, s.adminUser, s.adminPass = "postgres", opts.Username, opts.Pass
+		s.tls = opts.DB.Spec.TLS != nil
+		s.certPrefix = opts.DB.CertificateName(dbapi.PostgresClientCert)
+		s.secretPrefix = opts.DB.GetCertSecretName(dbapi.PostgresClientCert)
+	case dbapi.ResourceSingularMySQL:
+		opts, err := common.NewMySQLOpts(f, name, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get db %s, err:%v", name, err)
+		}
+		s.config, s.client, s.certManager = opts.Config, opts.Client, opts.CertManagerClient
+		// generateMySQLUser connects as root, whatever the auth secret names.
+		s.container, s.adminUser, s.adminPass = "mysql", "root", opts.Pass
+		s.tls = opts.DB.Spec.TLS != nil
+		s.certPrefix = opts.DB.CertificateName(dbapi.MySQLClientCert)
+		s.secretPrefix = opts.DB.GetCertSecretName(dbapi.MySQLClientCert)
+	case dbapi.ResourceSingularMariaDB:
+		opts, err := common.NewMariaDBOpts(f, name, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get db %s, err:%v", name, err)
+		}
+		s.config, s.client, s.certManager = opts.Config, opts.Client, opts.CertManagerClient
+		s.container, s.adminUser, s.adminPass = "mariadb", opts.Username, opts.Pass
+		s.tls = opts.DB.Spec.TLS != nil
+		s.certPrefix = opts.DB.CertificateName(dbapi.MariaDBClientCert)
+		s.secretPrefix = opts.DB.GetCertSecretName(dbapi.MariaDBClientCert)
+	case dbapi.ResourceSingularMongoDB:
+		opts, err := common.NewMongoDBOpts(f, name, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get db %s, err:%v", name, err)
+		}
+		s.config, s.client, s.certManager = opts.Config, opts.Client, opts.CertManagerClient
+		s.container, s.adminUser, s.adminPass = "mongodb", opts.Username, opts.Pass
+		s.tls = opts.DB.Spec.TLS != nil
+		s.certPrefix = opts.DB.CertificateName(dbapi.MongoDBClientCert, "")
+		s.secretPrefix = opts.DB.GetCertSecretName(dbapi.MongoDBClientCert, "")
+	default:
+		return nil, fmt.Errorf("unsupported database kind %q; supported: %s", kind, strings.Join(remoteReplicaKinds, ", "))
+	}
+	pod, err := primaryPod(s.client, ns, offshootSelectors(kind, name, ns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the primary of %s %s/%s %v", kind, ns, name, err)
+	}
+	s.pod = pod
+	return s, nil
+}
+
+// remoteReplicaKinds are the kinds remote-config generates a replica for.
+var remoteReplicaKinds = []string{
+	dbapi.ResourceSingularPostgres,
+	dbapi.ResourceSingularMySQL,
+	dbapi.ResourceSingularMariaDB,
+	dbapi.ResourceSingularMongoDB,
+}
+
+// certName and certSecretName are the cert-manager Certificate and Secret
+// remote-config issues for user.
+func (s *sourceDB) certName(user string) string {
+	return s.certPrefix + "-" + user
+}
+
+func (s *sourceDB) certSecretName(user string) string {
+	return s.secretPrefix + "-" + user
+}
+
+// psql runs a query on the source primary, see psqlRows.
+func (s *sourceDB) psql(query string) ([][]string, error) {
+	return psqlRows(s.config, s.pod, query)
+}
+
+// mysql runs a statement on the source primary with tab-separated output.
+func (s *sourceDB) mysql(query string) (string, error) {
+	return mysqlQuery(s.config, s.pod, s.container, s.adminUser, s.adminPass, false, query)
+}
+
+// mongo evaluates a script in the admin database of the source primary.
+func (s *sourceDB) mongo(eval string) (string, error) {
+	return mongoEval(s.config, s.pod, s.adminUser, s.adminPass, s.tls, eval)
+}
+
+// deleteClientCert deletes the Certificate and Secret remote-config issued
+// for user on the source cluster, and returns what it deleted. A client
+// certificate signed with --ca-cert/--ca-key has neither.
+func (s *sourceDB) deleteClientCert(ctx context.Context, user string) ([]string, error) {
+	var deleted []string
+	err := s.certManager.CertmanagerV1().Certificates(s.ns).Delete(ctx, s.certName(user), metav1.DeleteOptions{})
+	if err == nil {
+		deleted = append(deleted, "certificate/"+s.certName(user))
+	} else if !kerr.IsNotFound(err) {
+		return deleted, fmt.Errorf("failed to delete certificate %s/%s %v", s.ns, s.certName(user), err)
+	}
+	// cert-manager leaves the Secret behind when its Certificate goes.
+	err = s.client.CoreV1().Secrets(s.ns).Delete(ctx, s.certSecretName(user), metav1.DeleteOptions{})
+	if err == nil {
+		deleted = append(deleted, "secret/"+s.certSecretName(user))
+	} else if !kerr.IsNotFound(err) {
+		return deleted, fmt.Errorf("failed to delete secret %s/%s %v", s.ns, s.certSecretName(user), err)
+	}
+	return deleted, nil
+}
+
+// quoteIdent quotes s as a postgres identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
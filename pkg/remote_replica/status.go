@@ -0,0 +1,559 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote_replica
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubedb.dev/apimachinery/apis/kubedb"
+	dbapi "kubedb.dev/apimachinery/apis/kubedb/v1"
+	"kubedb.dev/cli/pkg/common"
+
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	exec_util "kmodules.xyz/client-go/tools/exec"
+	as "kmodules.xyz/custom-resources/client/clientset/versioned"
+)
+
+// replicaTargetFlags locate a source database on the current cluster and its
+// remote replica on the remote cluster.
+type replicaTargetFlags struct {
+	ns, replicaNS, replicaName string
+	remote                     common.RemoteClusterFlags
+}
+
+func addReplicaTargetFlags(cmd *cobra.Command, o *replicaTargetFlags) {
+	cmd.Flags().StringVarP(&o.ns, "namespace", "n", "default", "namespace of the source database")
+	cmd.Flags().StringVar(&o.replicaNS, "replica-namespace", "", "namespace of the replica on the remote cluster (default: same as --namespace)")
+	cmd.Flags().StringVar(&o.replicaName, "replica-name", "", "name of the replica on the remote cluster (default: the one whose spec.remoteReplica.sourceRef names the source)")
+	common.AddRemoteKubeconfigFlags(cmd.Flags(), &o.remote)
+}
+
+// replicaTarget is a resolved source/replica pair.
+type replicaTarget struct {
+	src *sourceDB
+
+	remoteConfig *rest.Config
+	remoteClient kubernetes.Interface
+	replica      *unstructured.Unstructured
+	// replicaPod is nil when the replica has no running pod yet.
+	replicaPod *core.Pod
+	// user, authSecret and tlsSecret come from the AppBinding the replica
+	// connects through, i.e. the objects remote-config generated.
+	user       string
+	secretNS   string
+	authSecret string
+	tlsSecret  string
+}
+
+func (o *replicaTargetFlags) resolve(ctx context.Context, f cmdutil.Factory, kind, dbName string) (*replicaTarget, error) {
+	if !o.remote.Remote() {
+		return nil, fmt.Errorf("give --remote-kubeconfig and/or --remote-context to reach the cluster running the replica")
+	}
+	src, err := openSourceDB(f, kind, dbName, o.ns)
+	if err != nil {
+		return nil, err
+	}
+	t := &replicaTarget{src: src}
+
+	if t.remoteConfig, err = o.remote.RESTConfig(); err != nil {
+		return nil, err
+	}
+	if t.remoteClient, err = kubernetes.NewForConfig(t.remoteConfig); err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(t.remoteConfig)
+	if err != nil {
+		return nil, err
+	}
+	replicaNS := o.replicaNS
+	if replicaNS == "" {
+		replicaNS = o.ns
+	}
+	gvr := schema.GroupVersionResource{Group: kubedb.GroupName, Version: "v1", Resource: resourcePlural(kind)}
+	if t.replica, err = findRemoteReplica(ctx, dc, gvr, replicaNS, o.replicaName, dbName); err != nil {
+		return nil, err
+	}
+	if pod, err := primaryPod(t.remoteClient, replicaNS, offshootSelectors(kind, t.replica.GetName(), replicaNS)); err == nil {
+		t.replicaPod = pod
+	}
+
+	apbName, _, _ := unstructured.NestedString(t.replica.Object, "spec", "remoteReplica", "sourceRef", "name")
+	apbNS, _, _ := unstructured.NestedString(t.replica.Object, "spec", "remoteReplica", "sourceRef", "namespace")
+	if apbNS == "" {
+		apbNS = replicaNS
+	}
+	t.secretNS = apbNS
+	ac, err := as.NewForConfig(t.remoteConfig)
+	if err != nil {
+		return nil, err
+	}
+	apb, err := ac.AppcatalogV1alpha1().AppBindings(apbNS).Get(ctx, apbName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appbinding %s/%s on the remote cluster %v", apbNS, apbName, err)
+	}
+	if apb.Spec.Secret != nil {
+		t.authSecret = apb.Spec.Secret.Name
+		secret, err := t.remoteClient.CoreV1().Secrets(apbNS).Get(ctx, t.authSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get auth secret %s/%s on the remote cluster %v", apbNS, t.authSecret, err)
+		}
+		t.user = string(secret.Data[core.BasicAuthUsernameKey])
+	}
+	if apb.Spec.TLSSecret != nil {
+		t.tlsSecret = apb.Spec.TLSSecret.Name
+	}
+	if t.user == "" {
+		return nil, fmt.Errorf("appbinding %s/%s on the remote cluster has no replication user", apbNS, apbName)
+	}
+	return t, nil
+}
+
+// findRemoteReplica returns the named replica or, without a name, the only
+// replica whose spec.remoteReplica.sourceRef names the source AppBinding.
+func findRemoteReplica(ctx context.Context, dc dynamic.Interface, gvr schema.GroupVersionResource, ns, name, source string) (*unstructured.Unstructured, error) {
+	if name != "" {
+		obj, err := dc.Resource(gvr).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %s/%s on the remote cluster %v", gvr.Resource, ns, name, err)
+		}
+		return obj, nil
+	}
+	list, err := dc.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s in %s on the remote cluster %v", gvr.Resource, ns, err)
+	}
+	var found []*unstructured.Unstructured
+	for i := range list.Items {
+		if ref, _, _ := unstructured.NestedString(list.Items[i].Object, "spec", "remoteReplica", "sourceRef", "name"); ref == source {
+			found = append(found, &list.Items[i])
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no %s in %s on the remote cluster replicates from %s; pass --replica-name or --replica-namespace", gvr.Resource, ns, source)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%d %s in %s on the remote cluster replicate from %s; pass --replica-name", len(found), gvr.Resource, ns, source)
+}
+
+// replicaHealth is what status reports. Negative lags and retention are
+// unknown.
+type replicaHealth struct {
+	connected        bool
+	state            string
+	sourceHost       string
+	tls              string
+	lagBytes         int64
+	lagSeconds       float64
+	slot             string
+	slotRetention    int64
+	inactiveSlots    []string
+	credentialExpiry string
+	certExpiry       string
+	errors           []string
+}
+
+func NewCmdStatus(f cmdutil.Factory) *cobra.Command {
+	var o replicaTargetFlags
+	var maxLagBytes int64
+	var maxLag time.Duration
+	cmd := &cobra.Command{
+		Use:   "status <kind> <db_name>",
+		Short: "Show whether a remote replica is replicating, and how far behind it is",
+		Long: "Connects to the source primary on the current cluster and to the remote replica through --remote-kubeconfig/--remote-context, " +
+			"and reports the connection state, whether TLS is in use, byte and time lag, the WAL retained on the source for the replica's slot (postgres), " +
+			"and when the replication user's password and client certificate expire. Supported kinds: postgres (pg_stat_replication, pg_stat_wal_receiver) " +
+			"and mysql (SHOW REPLICA STATUS). Exits non-zero when the replica is not connected or its lag exceeds --max-lag-bytes or --max-lag.",
+		Example: "kubectl dba remote-replica status postgres -n demo --remote-kubeconfig remote.yaml pg-src\n" +
+			"kubectl dba remote-replica status mysql -n demo --remote-context dr --replica-name my-dr --max-lag 30s my-src",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			if args[0] != dbapi.ResourceSingularPostgres && args[0] != dbapi.ResourceSingularMySQL {
+				log.Fatalf("unsupported database kind %q; status supports %s and %s", args[0], dbapi.ResourceSingularPostgres, dbapi.ResourceSingularMySQL)
+			}
+			t, err := o.resolve(ctx, f, args[0], args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+			var h *replicaHealth
+			if t.src.kind == dbapi.ResourceSingularPostgres {
+				h = postgresHealth(t)
+			} else {
+				h = mysqlHealth(t)
+			}
+			h.certExpiry = certExpiry(ctx, t)
+			printHealth(t, h)
+			if err := h.check(maxLagBytes, maxLag); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	addReplicaTargetFlags(cmd, &o)
+	cmd.Flags().Int64Var(&maxLagBytes, "max-lag-bytes", 64<<20, "fail when the replica is more than this many bytes behind the source (0 disables)")
+	cmd.Flags().DurationVar(&maxLag, "max-lag", time.Minute, "fail when the replica is more than this far behind the source (0 disables)")
+	return cmd
+}
+
+// check fails when the replica is disconnected or behind by more than a
+// threshold. An unknown lag does not fail on its own.
+func (h *replicaHealth) check(maxLagBytes int64, maxLag time.Duration) error {
+	if !h.connected {
+		return fmt.Errorf("replica is not replicating (state %s)", orDash(h.state))
+	}
+	if maxLagBytes > 0 && h.lagBytes > maxLagBytes {
+		return fmt.Errorf("replica is %d bytes behind the source, more than --max-lag-bytes=%d", h.lagBytes, maxLagBytes)
+	}
+	if maxLag > 0 && h.lagSeconds > maxLag.Seconds() {
+		return fmt.Errorf("replica is %s behind the source, more than --max-lag=%s", secondsDuration(h.lagSeconds), maxLag)
+	}
+	return nil
+}
+
+func printHealth(t *replicaTarget, h *replicaHealth) {
+	replicaPod := "-"
+	if t.replicaPod != nil {
+		replicaPod = t.replicaPod.Name
+	}
+	fmt.Printf("Source:            %s %s/%s (primary pod %s)\n", t.src.kind, t.src.ns, t.src.name, t.src.pod.Name)
+	fmt.Printf("Replica:           %s/%s on the remote cluster (pod %s)\n", t.replica.GetNamespace(), t.replica.GetName(), replicaPod)
+	fmt.Printf("Replication user:  %s\n", t.user)
+	fmt.Printf("Connected:         %t (state %s)\n", h.connected, orDash(h.state))
+	if h.sourceHost != "" {
+		fmt.Printf("Replicating from:  %s\n", h.sourceHost)
+	}
+	fmt.Printf("TLS:               %s\n", orDash(h.tls))
+	fmt.Printf("Lag:               %s, %s\n", bytesOrDash(h.lagBytes), secondsDuration(h.lagSeconds))
+	if t.src.kind == dbapi.ResourceSingularPostgres {
+		fmt.Printf("Slot:              %s, retaining %s on the source\n", orDash(h.slot), bytesOrDash(h.slotRetention))
+		for _, s := range h.inactiveSlots {
+			fmt.Printf("WARNING: inactive replication slot %s on the source is retaining WAL\n", s)
+		}
+	}
+	fmt.Printf("Password expires:  %s\n", orDash(h.credentialExpiry))
+	fmt.Printf("Client cert:       %s\n", orDash(h.certExpiry))
+	for _, e := range h.errors {
+		fmt.Printf("WARNING: %s\n", e)
+	}
+}
+
+func bytesOrDash(n int64) string {
+	if n < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(n, 10) + " bytes"
+}
+
+func secondsDuration(s float64) string {
+	if s < 0 {
+		return "time lag unknown"
+	}
+	return (time.Duration(s * float64(time.Second))).Round(time.Millisecond).String()
+}
+
+// certExpiry reads the client certificate the replica connects with from the
+// generated TLS Secret on the remote cluster.
+func certExpiry(ctx context.Context, t *replicaTarget) string {
+	if t.tlsSecret == "" {
+		return "none (the replica connects without a client certificate)"
+	}
+	secret, err := t.remoteClient.CoreV1().Secrets(t.secretNS).Get(ctx, t.tlsSecret, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Sprintf("failed to get secret %s: %v", t.tlsSecret, err)
+	}
+	cert, err := leafCert(secret)
+	if err != nil {
+		return err.Error()
+	}
+	return expiryString(cert.NotAfter, t.tlsSecret)
+}
+
+// leafCert parses the certificate in a TLS Secret.
+func leafCert(secret *core.Secret) (*x509.Certificate, error) {
+	block, _ := pem.Decode(secret.Data[core.TLSCertKey])
+	if block == nil {
+		return nil, fmt.Errorf("secret %s has no PEM %s", secret.Name, core.TLSCertKey)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s in secret %s: %v", core.TLSCertKey, secret.Name, err)
+	}
+	return cert, nil
+}
+
+func expiryString(at time.Time, what string) string {
+	left := time.Until(at)
+	if left <= 0 {
+		return fmt.Sprintf("%s EXPIRED at %s", what, at.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s expires %s (in %s)", what, at.UTC().Format(time.RFC3339), left.Round(time.Hour))
+}
+
+// execSQL runs a client command in a database pod.
+func execSQL(config *rest.Config, pod *core.Pod, container string, command ...string) (string, error) {
+	return exec_util.ExecIntoPod(config, pod, exec_util.Command(command...), exec_util.Container(container))
+}
+
+// sqlLiteral quotes s as a SQL string literal.
+func sqlLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psqlRows runs a query with psql and splits the unaligned output into rows of
+// '|'-separated fields.
+func psqlRows(config *rest.Config, pod *core.Pod, query string) ([][]string, error) {
+	out, err := execSQL(config, pod, "postgres", "psql", "-tAX", "-F", "|", "-c", query)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, strings.Split(line, "|"))
+		}
+	}
+	return rows, nil
+}
+
+func parseInt(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func parseSeconds(s string) float64 {
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func postgresHealth(t *replicaTarget) *replicaHealth {
+	h := &replicaHealth{lagBytes: -1, lagSeconds: -1, slotRetention: -1}
+
+	// The replica's side: is the WAL receiver streaming, and through which slot.
+	activePID := ""
+	if t.replicaPod == nil {
+		h.errors = append(h.errors, "the replica has no running pod")
+	} else if rows, err := psqlRows(t.remoteConfig, t.replicaPod,
+		"SELECT status, coalesce(slot_name, ''), coalesce(sender_host, ''), coalesce(sender_port::text, ''), "+
+			"coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp())::text, '') FROM pg_stat_wal_receiver"); err != nil {
+		h.errors = append(h.errors, fmt.Sprintf("failed to read pg_stat_wal_receiver on the replica: %v", err))
+	} else if len(rows) == 0 || len(rows[0]) < 5 {
+		h.state = "no WAL receiver"
+	} else {
+		r := rows[0]
+		h.state, h.slot = r[0], r[1]
+		h.connected = r[0] == "streaming"
+		if r[2] != "" {
+			h.sourceHost = r[2] + ":" + r[3]
+		}
+		h.lagSeconds = parseSeconds(r[4])
+	}
+
+	// The source's side: slots first, so the pg_stat_replication row can be
+	// matched on the slot's walsender.
+	if rows, err := t.src.psql("SELECT slot_name, active, coalesce(active_pid::text, ''), " +
+		"coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), restart_lsn)::bigint::text, '') FROM pg_replication_slots"); err != nil {
+		h.errors = append(h.errors, fmt.Sprintf("failed to read pg_replication_slots on the source: %v", err))
+	} else {
+		for _, r := range rows {
+			if len(r) < 4 {
+				continue
+			}
+			if r[0] == h.slot {
+				h.slotRetention = parseInt(r[3])
+				activePID = r[2]
+			} else if r[1] != "t" {
+				h.inactiveSlots = append(h.inactiveSlots, fmt.Sprintf("%s (%s)", r[0], bytesOrDash(parseInt(r[3]))))
+			}
+		}
+	}
+	if rows, err := t.src.psql("SELECT r.pid, coalesce(s.ssl::text, 'false'), coalesce(s.version, ''), " +
+		"coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), r.replay_lsn)::bigint::text, ''), coalesce(extract(epoch FROM r.replay_lag)::text, '') " +
+		"FROM pg_stat_replication r LEFT JOIN pg_stat_ssl s USING (pid) WHERE r.usename = " + sqlLiteral(t.user)); err != nil {
+		h.errors = append(h.errors, fmt.Sprintf("failed to read pg_stat_replication on the source: %v", err))
+	} else {
+		var row []string
+		for _, r := range rows {
+			if len(r) >= 5 && (row == nil || r[0] == activePID) {
+				row = r
+			}
+		}
+		if row == nil {
+			h.connected = false
+			h.errors = append(h.errors, fmt.Sprintf("the source has no walsender for user %s", t.user))
+		} else {
+			h.tls = "no"
+			if row[1] == "true" || row[1] == "t" {
+				h.tls = "yes (" + row[2] + ")"
+			}
+			h.lagBytes = parseInt(row[3])
+			// replay_lag is NULL once the replica has caught up on an idle
+			// source, which the replica's replay timestamp would report as an
+			// ever-growing lag.
+			if row[4] != "" {
+				h.lagSeconds = parseSeconds(row[4])
+			} else if h.lagBytes == 0 {
+				h.lagSeconds = 0
+			}
+		}
+	}
+
+	if rows, err := t.src.psql("SELECT coalesce(rolvaliduntil::text, '') FROM pg_roles WHERE rolname = " + sqlLiteral(t.user)); err != nil {
+		h.errors = append(h.errors, fmt.Sprintf("failed to read the replication role on the source: %v", err))
+	} else if len(rows) == 0 {
+		h.credentialExpiry = fmt.Sprintf("role %s does not exist on the source", t.user)
+	} else if rows[0][0] == "" || rows[0][0] == "infinity" {
+		h.credentialExpiry = "never"
+	} else {
+		h.credentialExpiry = rows[0][0]
+	}
+	return h
+}
+
+// mysqlQuery runs a statement with the mysql client. password is exported
+// as MYSQL_PWD; an empty password uses the container's MYSQL_ROOT_PASSWORD.
+func mysqlQuery(config *rest.Config, pod *core.Pod, container, user, password string, vertical bool, query string) (string, error) {
+	pwd := `"${MYSQL_ROOT_PASSWORD}"`
+	if password != "" {
+		pwd = shellQuote(password)
+	}
+	flags := "-N -B"
+	if vertical {
+		flags = "-E"
+	}
+	script := fmt.Sprintf("export MYSQL_PWD=%s && mysql -u%s %s -e %s", pwd, shellQuote(user), flags, shellQuote(query))
+	return execSQL(config, pod, container, "bash", "-c", script)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseVerticalStatus parses the first record of mysql -E (\G) output into
+// field/value pairs.
+func parseVerticalStatus(out string) map[string]string {
+	m := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "***") {
+			if len(m) > 0 {
+				break
+			}
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+// statusField returns the first present field of a replica status, given its
+// 8.0.22+ name first and the pre-8.0.22 one after.
+func statusField(m map[string]string, names ...string) string {
+	for _, n := range names {
+		if v, ok := m[n]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func mysqlHealth(t *replicaTarget) *replicaHealth {
+	h := &replicaHealth{lagBytes: -1, lagSeconds: -1, slotRetention: -1}
+	var st map[string]string
+	if t.replicaPod == nil {
+		h.errors = append(h.errors, "the replica has no running pod")
+	} else {
+		out, err := mysqlQuery(t.remoteConfig, t.replicaPod, "mysql", "root", "", true, "SHOW REPLICA STATUS")
+		if err != nil {
+			// Before 8.0.22 there is only the old spelling.
+			out, err = mysqlQuery(t.remoteConfig, t.replicaPod, "mysql", "root", "", true, "SHOW SLAVE STATUS")
+		}
+		if err != nil {
+			h.errors = append(h.errors, fmt.Sprintf("failed to read the replica status: %v", err))
+		} else if st = parseVerticalStatus(out); len(st) == 0 {
+			h.state = "not configured as a replica"
+		}
+	}
+	if len(st) > 0 {
+		io := statusField(st, "Replica_IO_Running", "Slave_IO_Running")
+		sql := statusField(st, "Replica_SQL_Running", "Slave_SQL_Running")
+		h.state = fmt.Sprintf("IO %s, SQL %s", io, sql)
+		h.connected = io == "Yes" && sql == "Yes"
+		h.sourceHost = statusField(st, "Source_Host", "Master_Host") + ":" + statusField(st, "Source_Port", "Master_Port")
+		h.tls = "no"
+		if statusField(st, "Source_SSL_Allowed", "Master_SSL_Allowed") == "Yes" {
+			h.tls = "yes"
+		}
+		if s := statusField(st, "Seconds_Behind_Source", "Seconds_Behind_Master"); s != "NULL" {
+			h.lagSeconds = parseSeconds(s)
+		}
+		for _, e := range []string{statusField(st, "Last_IO_Error"), statusField(st, "Last_SQL_Error")} {
+			if e != "" {
+				h.errors = append(h.errors, e)
+			}
+		}
+		// Byte lag is only meaningful while both sides are in the same binlog.
+		out, err := t.src.mysql("SHOW BINARY LOG STATUS")
+		if err != nil {
+			out, err = t.src.mysql("SHOW MASTER STATUS")
+		}
+		if err != nil {
+			h.errors = append(h.errors, fmt.Sprintf("failed to read the source binlog position: %v", err))
+		} else if f := strings.Fields(out); len(f) >= 2 && f[0] == statusField(st, "Relay_Source_Log_File", "Relay_Master_Log_File") {
+			if exec := parseInt(statusField(st, "Exec_Source_Log_Pos", "Exec_Master_Log_Pos")); exec >= 0 {
+				h.lagBytes = parseInt(f[1]) - exec
+			}
+		}
+	}
+
+	out, err := t.src.mysql("SELECT password_expired, IFNULL(password_lifetime, @@default_password_lifetime), password_last_changed FROM mysql.user WHERE user = " + sqlLiteral(t.user) + " LIMIT 1")
+	if err != nil {
+		h.errors = append(h.errors, fmt.Sprintf("failed to read the replication user on the source: %v", err))
+	} else if f := strings.Split(strings.TrimSpace(out), "\t"); len(f) < 3 {
+		h.credentialExpiry = fmt.Sprintf("user %s does not exist on the source", t.user)
+	} else if f[0] == "Y" {
+		h.credentialExpiry = "EXPIRED"
+	} else if days := parseInt(f[1]); days <= 0 {
+		h.credentialExpiry = "never"
+	} else if changed, err := time.Parse(time.DateTime, f[2]); err == nil {
+		h.credentialExpiry = expiryString(changed.AddDate(0, 0, int(days)), "password")
+	}
+	return h
+}